@@ -6,6 +6,7 @@ import (
 	"syscall"
 
 	"github.com/yhlooo/stackcrisp/pkg/commands"
+	cmdutil "github.com/yhlooo/stackcrisp/pkg/utils/cmd"
 	ctxutil "github.com/yhlooo/stackcrisp/pkg/utils/context"
 )
 
@@ -21,7 +22,9 @@ func main() {
 	cmd := commands.NewStackCrispCommand()
 	cmd.Version = Version
 	// 执行命令
-	if err := cmd.ExecuteContext(ctx); err != nil {
+	if c, err := cmd.ExecuteContextC(ctx); err != nil {
+		// 出错时不会执行 PersistentPostRunE ，在退出前释放锁
+		_ = cmdutil.CloseManagerIfNecessary(c)
 		log.Fatal(err)
 	}
 }
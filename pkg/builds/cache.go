@@ -0,0 +1,88 @@
+package builds
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/yhlooo/stackcrisp/pkg/spaces/trees"
+	"github.com/yhlooo/stackcrisp/pkg/utils/uid"
+	"github.com/yhlooo/stackcrisp/pkg/workspaces"
+)
+
+const nodeAnnoBuildCacheKey = "build-cache-key"
+
+// CacheKeyInput 决定构建步骤结果的输入
+type CacheKeyInput struct {
+	// 父节点 ID
+	Parent uid.UID
+	// 步骤的命令
+	Command string
+	// 额外的环境变量
+	Env []string
+	// 工作目录
+	Dir string
+	// 是否 chroot 运行
+	Chroot bool
+	// 运行步骤的用户，格式为 <uid>:<gid> ，以 root 运行时为空
+	User string
+}
+
+// CacheKey 计算构建步骤的缓存键
+func CacheKey(in CacheKeyInput) string {
+	raw, _ := json.Marshal(struct {
+		Parent  string   `json:"parent"`
+		Command string   `json:"command"`
+		Env     []string `json:"env,omitempty"`
+		Dir     string   `json:"dir,omitempty"`
+		Chroot  bool     `json:"chroot,omitempty"`
+		User    string   `json:"user,omitempty"`
+	}{
+		Parent:  in.Parent.Hex(),
+		Command: in.Command,
+		Env:     in.Env,
+		Dir:     in.Dir,
+		Chroot:  in.Chroot,
+		User:    in.User,
+	})
+	sum := sha256.Sum256(raw)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// SetCacheKey 将缓存键记录到节点
+func SetCacheKey(node trees.Node, key string) {
+	node.AddAnnotation(nodeAnnoBuildCacheKey, key)
+}
+
+// CacheKeyOf 返回节点的缓存键，不是构建步骤的提交返回空字符串
+func CacheKeyOf(node trees.Node) string {
+	return node.Annotations()[nodeAnnoBuildCacheKey]
+}
+
+// FindCached 在 parent 的子节点中查找缓存键为 key 的提交，有多个时返回最新的，没有时返回 nil
+func FindCached(parent trees.Node, key string) trees.Node {
+	var found trees.Node
+	for _, child := range parent.Children() {
+		if CacheKeyOf(child) != key || !workspaces.IsCommitted(child) {
+			continue
+		}
+		if found == nil || newerThan(child, found) {
+			found = child
+		}
+	}
+	return found
+}
+
+// newerThan 返回节点 a 是否比 b 更新，提交日期相同时按 ID 排序以保证结果稳定
+func newerThan(a, b trees.Node) bool {
+	dateA, dateB := workspaces.CommitDate(a), workspaces.CommitDate(b)
+	switch {
+	case dateA == nil && dateB != nil:
+		return false
+	case dateA != nil && dateB == nil:
+		return true
+	case dateA != nil && !dateA.Equal(*dateB):
+		return dateA.After(*dateB)
+	}
+	return a.ID().Hex() > b.ID().Hex()
+}
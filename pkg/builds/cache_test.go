@@ -0,0 +1,60 @@
+package builds
+
+import (
+	"testing"
+	"time"
+
+	"github.com/yhlooo/stackcrisp/pkg/spaces/trees"
+	"github.com/yhlooo/stackcrisp/pkg/utils/uid"
+	"github.com/yhlooo/stackcrisp/pkg/workspaces"
+)
+
+// TestCacheKey 测试 CacheKey 方法
+func TestCacheKey(t *testing.T) {
+	parent := uid.NewUID128()
+	in := CacheKeyInput{Parent: parent, Command: "echo a"}
+	key := CacheKey(in)
+	if CacheKey(in) != key {
+		t.Errorf("expected the same key for the same input")
+	}
+	for _, other := range []CacheKeyInput{
+		{Parent: uid.NewUID128(), Command: "echo a"},
+		{Parent: parent, Command: "echo b"},
+		{Parent: parent, Command: "echo a", Env: []string{"A=1"}},
+		{Parent: parent, Command: "echo a", Dir: "/src"},
+		{Parent: parent, Command: "echo a", Chroot: true},
+	} {
+		if CacheKey(other) == key {
+			t.Errorf("expected a different key for %#v", other)
+		}
+	}
+}
+
+// TestFindCached 测试 FindCached 方法
+func TestFindCached(t *testing.T) {
+	parent := trees.NewNode(uid.NewUID128())
+	addChild := func(key string, date time.Time) trees.Node {
+		child := trees.NewNode(uid.NewUID128())
+		workspaces.NewCommitInfoWithDate("step", date).SetToNode(child)
+		SetCacheKey(child, key)
+		child.SetParent(parent)
+		parent.AddChild(child)
+		return child
+	}
+	now := time.Now()
+	addChild("a", now.Add(-time.Hour))
+	newer := addChild("a", now)
+	addChild("b", now.Add(time.Hour))
+	// 未提交的节点不作为缓存
+	uncommitted := trees.NewNode(uid.NewUID128())
+	SetCacheKey(uncommitted, "c")
+	uncommitted.SetParent(parent)
+	parent.AddChild(uncommitted)
+
+	if got := FindCached(parent, "a"); got == nil || got.ID().Hex() != newer.ID().Hex() {
+		t.Errorf("expected %s for key a, got %v", newer.ID().Hex(), got)
+	}
+	if got := FindCached(parent, "c"); got != nil {
+		t.Errorf("expected nil for key c, got %s", got.ID().Hex())
+	}
+}
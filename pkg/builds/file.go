@@ -0,0 +1,52 @@
+package builds
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// DefaultFileName 默认的构建文件名
+const DefaultFileName = "Crispfile"
+
+// Step 构建步骤
+type Step struct {
+	// 步骤在构建文件中开始的行号，从 1 开始
+	Line int
+	// 通过 sh -c 运行的 shell 命令
+	Command string
+}
+
+// Parse 解析构建文件
+//
+// 每个非空行是一个步骤，首个非空白字符是 # 的行是注释。行尾是 \ 时下一行是同一个步骤的延续，
+// 延续的换行原样保留由 shell 处理，其中的空行和注释行被忽略
+func Parse(r io.Reader) ([]Step, error) {
+	var steps []Step
+	var cur *Step
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimRight(scanner.Text(), " \t\r")
+		trimmed := strings.TrimSpace(text)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if cur == nil {
+			cur = &Step{Line: line, Command: trimmed}
+		} else {
+			cur.Command += "\n" + text
+		}
+		if !strings.HasSuffix(text, "\\") {
+			steps = append(steps, *cur)
+			cur = nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read build file error: %w", err)
+	}
+	if cur != nil {
+		return nil, fmt.Errorf("line %d: unexpected end of file after line continuation", cur.Line)
+	}
+	return steps, nil
+}
@@ -0,0 +1,37 @@
+package builds
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestParse 测试 Parse 方法
+func TestParse(t *testing.T) {
+	content := `# 安装依赖
+apt-get update
+
+apt-get install -y \
+    # 编译工具
+    gcc \
+
+    make
+  echo done  
+`
+	steps, err := Parse(strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	expected := []Step{
+		{Line: 2, Command: "apt-get update"},
+		{Line: 4, Command: "apt-get install -y \\\n    gcc \\\n    make"},
+		{Line: 9, Command: "echo done"},
+	}
+	if !reflect.DeepEqual(steps, expected) {
+		t.Errorf("expected %#v, got %#v", expected, steps)
+	}
+
+	if _, err := Parse(strings.NewReader("echo a \\\n")); err == nil {
+		t.Errorf("expected an error for unterminated line continuation, got nil")
+	}
+}
@@ -0,0 +1,313 @@
+//go:build linux
+
+package changes
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+
+	fsutil "github.com/yhlooo/stackcrisp/pkg/utils/fs"
+)
+
+// WriteTar 将视图中的内容以 tar 格式写入 w
+//
+// 保留文件类型、权限、所有者（仅数字 ID ）、扩展属性、修改时间、软链和硬链接关系，
+// 不包含 whiteout 文件和 overlay 内部使用的扩展属性
+func WriteTar(view View, w io.Writer) error {
+	tw := tar.NewWriter(w)
+	// 已写入的 inode 与归档中的路径，用于还原硬链接
+	links := map[[2]uint64]string{}
+	err := view.Walk(func(entry *Entry) error {
+		return writeTarEntry(tw, entry, links)
+	})
+	if err != nil {
+		return err
+	}
+	return tw.Close()
+}
+
+// writeTarEntry 将视图中的一个文件写入 tar
+func writeTarEntry(tw *tar.Writer, entry *Entry, links map[[2]uint64]string) error {
+	info := entry.Info
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fmt.Errorf("unsupported file info of %q", entry.RealPath)
+	}
+
+	link := ""
+	if info.Mode()&os.ModeSymlink != 0 {
+		var err error
+		if link, err = os.Readlink(entry.RealPath); err != nil {
+			return fmt.Errorf("read link %q error: %w", entry.RealPath, err)
+		}
+	}
+	hdr, err := tar.FileInfoHeader(info, link)
+	if err != nil {
+		return fmt.Errorf("make tar header of %q error: %w", entry.Path, err)
+	}
+	hdr.Name = filepath.ToSlash(entry.Path)
+	if info.IsDir() {
+		hdr.Name += "/"
+	}
+	// 用户名和组名来自当前系统，与归档中的内容无关
+	hdr.Uname, hdr.Gname = "", ""
+	hdr.Format = tar.FormatPAX
+
+	// 硬链接
+	if info.Mode().IsRegular() && stat.Nlink > 1 {
+		key := [2]uint64{uint64(stat.Dev), stat.Ino}
+		if linked, ok := links[key]; ok {
+			hdr.Typeflag = tar.TypeLink
+			hdr.Linkname = linked
+			hdr.Size = 0
+			if err := tw.WriteHeader(hdr); err != nil {
+				return fmt.Errorf("write tar header of %q error: %w", entry.Path, err)
+			}
+			return nil
+		}
+		links[key] = hdr.Name
+	}
+
+	// 扩展属性
+	if info.Mode()&os.ModeSymlink == 0 {
+		names, err := fsutil.ListXattrs(entry.RealPath)
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			if isOverlayXattr(name) {
+				continue
+			}
+			value, err := fsutil.GetXattr(entry.RealPath, name)
+			if err != nil {
+				return err
+			}
+			if hdr.PAXRecords == nil {
+				hdr.PAXRecords = map[string]string{}
+			}
+			hdr.PAXRecords["SCHILY.xattr."+name] = string(value)
+		}
+	}
+
+	if err := tw.WriteHeader(hdr); err != nil {
+		return fmt.Errorf("write tar header of %q error: %w", entry.Path, err)
+	}
+	if !info.Mode().IsRegular() {
+		return nil
+	}
+	f, err := os.Open(entry.RealPath)
+	if err != nil {
+		return fmt.Errorf("open %q error: %w", entry.RealPath, err)
+	}
+	defer func() { _ = f.Close() }()
+	if _, err := io.Copy(tw, f); err != nil {
+		return fmt.Errorf("write content of %q to tar error: %w", entry.Path, err)
+	}
+	return nil
+}
+
+// ExtractTar 将 tar 格式的内容解压到 dst 目录
+//
+// 保留文件类型、权限、所有者（仅数字 ID ）、扩展属性、修改时间、软链和硬链接关系。
+// 归档中的路径都被限制在 dst 中，不会穿过软链写到 dst 外面。同名文件以后出现的为准。 dst 目录需要已经存在。
+func ExtractTar(r io.Reader, dst string) error {
+	return extractTar(r, dst, false)
+}
+
+// extractTar 将 tar 格式的内容解压到 dst 目录， layer 为 true 时将 OCI whiteout 文件转换为 overlay whiteout
+func extractTar(r io.Reader, dst string, layer bool) error {
+	tr := tar.NewReader(r)
+	// 目录的时间需要在其中内容解压完后再设置
+	var dirs []*tar.Header
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("read tar error: %w", err)
+		}
+		hdr.Name = cleanName(hdr.Name)
+		if layer {
+			if ok, err := extractOCIWhiteout(hdr, dst); err != nil || ok {
+				if err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		if err := extractTarEntry(tr, hdr, dst); err != nil {
+			return err
+		}
+		if hdr.Typeflag == tar.TypeDir {
+			dirs = append(dirs, hdr)
+		}
+	}
+
+	// 从深到浅设置目录时间
+	for i := len(dirs) - 1; i >= 0; i-- {
+		// 目录或其父目录可能已经被之后的同名文件替换（比如软链），不能穿过它们设置时间
+		if err := checkParents(dst, dirs[i].Name); err != nil {
+			continue
+		}
+		target := filepath.Join(dst, dirs[i].Name)
+		if info, err := os.Lstat(target); err != nil || !info.IsDir() {
+			continue
+		}
+		if err := setTarTimes(target, dirs[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// extractTarEntry 将 tar 中的一个文件解压到 dst 目录
+func extractTarEntry(tr *tar.Reader, hdr *tar.Header, dst string) error {
+	target := filepath.Join(dst, hdr.Name)
+	if hdr.Name != "." {
+		if err := mkdirParents(dst, hdr.Name); err != nil {
+			return err
+		}
+		// 替换已经存在的同名文件，目录则合并
+		if info, err := os.Lstat(target); err == nil && !(info.IsDir() && hdr.Typeflag == tar.TypeDir) {
+			if err := os.RemoveAll(target); err != nil {
+				return fmt.Errorf("remove %q error: %w", target, err)
+			}
+		}
+	}
+
+	switch hdr.Typeflag {
+	case tar.TypeDir:
+		if err := os.Mkdir(target, 0700); err != nil && !errors.Is(err, os.ErrExist) {
+			return fmt.Errorf("mkdir %q error: %w", target, err)
+		}
+	case tar.TypeReg:
+		f, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err != nil {
+			return fmt.Errorf("create %q error: %w", target, err)
+		}
+		if _, err := io.Copy(f, tr); err != nil {
+			_ = f.Close()
+			return fmt.Errorf("write content of %q error: %w", target, err)
+		}
+		if err := f.Close(); err != nil {
+			return fmt.Errorf("close %q error: %w", target, err)
+		}
+	case tar.TypeSymlink:
+		if err := os.Symlink(hdr.Linkname, target); err != nil {
+			return fmt.Errorf("create symlink %q error: %w", target, err)
+		}
+	case tar.TypeLink:
+		// 被链接的文件的父目录中可能有归档中之前创建的软链，不能穿过它们链接到 dst 外面的文件
+		linkname := cleanName(hdr.Linkname)
+		if err := checkParents(dst, linkname); err != nil {
+			return fmt.Errorf("invalid hardlink %q to %q: %w", hdr.Name, hdr.Linkname, err)
+		}
+		linked := filepath.Join(dst, linkname)
+		if info, err := os.Lstat(linked); err != nil || !info.Mode().IsRegular() {
+			return fmt.Errorf("invalid hardlink %q to %q", hdr.Name, hdr.Linkname)
+		}
+		if err := os.Link(linked, target); err != nil {
+			return fmt.Errorf("link %q to %q error: %w", target, linked, err)
+		}
+		// 硬链接与被链接的文件共享 inode ，不需要再设置元信息
+		return nil
+	case tar.TypeChar, tar.TypeBlock, tar.TypeFifo:
+		mode := uint32(syscall.S_IFIFO)
+		switch hdr.Typeflag {
+		case tar.TypeChar:
+			mode = syscall.S_IFCHR
+		case tar.TypeBlock:
+			mode = syscall.S_IFBLK
+		}
+		dev := unix.Mkdev(uint32(hdr.Devmajor), uint32(hdr.Devminor))
+		if err := syscall.Mknod(target, mode, int(dev)); err != nil {
+			return fmt.Errorf("mknod %q error: %w", target, err)
+		}
+	default:
+		return fmt.Errorf("unsupported type %q of %q in tar", hdr.Typeflag, hdr.Name)
+	}
+
+	// 所有者、权限和扩展属性
+	if err := os.Lchown(target, hdr.Uid, hdr.Gid); err != nil {
+		return fmt.Errorf("chown %q error: %w", target, err)
+	}
+	if hdr.Typeflag == tar.TypeSymlink {
+		return nil
+	}
+	// chown 会清除 setuid 等位，所以最后设置权限
+	if err := syscall.Chmod(target, uint32(hdr.Mode&07777)); err != nil {
+		return fmt.Errorf("chmod %q error: %w", target, err)
+	}
+	for key, value := range hdr.PAXRecords {
+		name, ok := strings.CutPrefix(key, "SCHILY.xattr.")
+		if !ok || isOverlayXattr(name) {
+			// overlay 使用的扩展属性会改变层的合并方式，不能来自归档
+			continue
+		}
+		if err := syscall.Setxattr(target, name, []byte(value), 0); err != nil {
+			return fmt.Errorf("set xattr %q of %q error: %w", name, target, err)
+		}
+	}
+	if hdr.Typeflag == tar.TypeDir {
+		return nil
+	}
+	return setTarTimes(target, hdr)
+}
+
+// mkdirParents 创建 dst 中 name 的所有父目录，父目录已经存在但不是目录（包括软链）时返回错误
+func mkdirParents(dst, name string) error {
+	return walkParents(dst, name, true)
+}
+
+// checkParents 检查 dst 中 name 的所有父目录都存在且是目录（不是软链）
+func checkParents(dst, name string) error {
+	return walkParents(dst, name, false)
+}
+
+// walkParents 逐级检查 dst 中 name 的父目录， create 为 true 时创建不存在的父目录
+func walkParents(dst, name string, create bool) error {
+	cur := dst
+	components := strings.Split(filepath.Dir(name), string(filepath.Separator))
+	for _, c := range components {
+		if c == "." {
+			continue
+		}
+		cur = filepath.Join(cur, c)
+		info, err := os.Lstat(cur)
+		switch {
+		case err == nil:
+			if !info.IsDir() {
+				return fmt.Errorf("parent %q of %q is not a directory", cur, name)
+			}
+		case errors.Is(err, os.ErrNotExist) && create:
+			if err := os.Mkdir(cur, 0755); err != nil {
+				return fmt.Errorf("mkdir %q error: %w", cur, err)
+			}
+		default:
+			return fmt.Errorf("lstat %q error: %w", cur, err)
+		}
+	}
+	return nil
+}
+
+// setTarTimes 按 tar 头设置文件访问和修改时间（不穿透软链）
+func setTarTimes(path string, hdr *tar.Header) error {
+	atime := hdr.AccessTime
+	if atime.IsZero() {
+		atime = hdr.ModTime
+	}
+	ts := []unix.Timespec{unix.NsecToTimespec(atime.UnixNano()), unix.NsecToTimespec(hdr.ModTime.UnixNano())}
+	if err := unix.UtimesNanoAt(unix.AT_FDCWD, path, ts, unix.AT_SYMLINK_NOFOLLOW); err != nil {
+		return fmt.Errorf("set times of %q error: %w", path, err)
+	}
+	return nil
+}
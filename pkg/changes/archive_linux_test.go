@@ -0,0 +1,188 @@
+//go:build linux
+
+package changes
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	fsutil "github.com/yhlooo/stackcrisp/pkg/utils/fs"
+)
+
+// TestWriteTar 测试 WriteTar 方法
+func TestWriteTar(t *testing.T) {
+	lower := t.TempDir()
+	upper := t.TempDir()
+	writeFiles(t, lower, "a/1", "b")
+	writeFiles(t, upper, "a/1", "c")
+	if err := os.Link(filepath.Join(upper, "c"), filepath.Join(upper, "d")); err != nil {
+		t.Fatalf("link error: %v", err)
+	}
+	if err := os.Symlink("a/1", filepath.Join(upper, "e")); err != nil {
+		t.Fatalf("symlink error: %v", err)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := WriteTar(NewView([]string{lower, upper}), buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	type item struct {
+		name     string
+		typeflag byte
+		linkname string
+		content  string
+	}
+	var ret []item
+	tr := tar.NewReader(buf)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("read tar error: %v", err)
+		}
+		content, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatalf("read content of %q error: %v", hdr.Name, err)
+		}
+		ret = append(ret, item{hdr.Name, hdr.Typeflag, hdr.Linkname, string(content)})
+	}
+	expected := []item{
+		{"a/", tar.TypeDir, "", ""},
+		{"a/1", tar.TypeReg, "", "a/1"},
+		{"b", tar.TypeReg, "", "b"},
+		{"c", tar.TypeReg, "", "c"},
+		{"d", tar.TypeLink, "c", ""},
+		{"e", tar.TypeSymlink, "a/1", ""},
+	}
+	if !reflect.DeepEqual(ret, expected) {
+		t.Errorf("unexpected result: %v (expected %v)", ret, expected)
+	}
+}
+
+// TestExtractTar 测试 ExtractTar 方法
+func TestExtractTar(t *testing.T) {
+	src := t.TempDir()
+	writeFiles(t, src, "a/1", "b/", "c")
+	if err := os.Link(filepath.Join(src, "c"), filepath.Join(src, "d")); err != nil {
+		t.Fatalf("link error: %v", err)
+	}
+	if err := os.Symlink("a/1", filepath.Join(src, "e")); err != nil {
+		t.Fatalf("symlink error: %v", err)
+	}
+	buf := &bytes.Buffer{}
+	if err := WriteTar(NewView([]string{src}), buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	dst := t.TempDir()
+	if err := ExtractTar(buf, dst); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	changes, err := Compare(NewView([]string{src}), NewView([]string{dst}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(changes) != 0 {
+		t.Errorf("unexpected changes: %v", changes)
+	}
+	cInfo, err := os.Stat(filepath.Join(dst, "c"))
+	if err != nil {
+		t.Fatalf("stat c error: %v", err)
+	}
+	if dInfo, err := os.Stat(filepath.Join(dst, "d")); err != nil || !os.SameFile(cInfo, dInfo) {
+		t.Errorf("expected d to be a hardlink of c, error: %v", err)
+	}
+
+	// 不能写到 dst 外面
+	buf.Reset()
+	tw := tar.NewWriter(buf)
+	for _, hdr := range []*tar.Header{
+		{Name: "link", Typeflag: tar.TypeSymlink, Linkname: "/tmp"},
+		{Name: "link/x", Typeflag: tar.TypeReg, Mode: 0644},
+	} {
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatalf("write tar header error: %v", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("close tar writer error: %v", err)
+	}
+	if err := ExtractTar(buf, t.TempDir()); err == nil {
+		t.Errorf("expected an error when extracting through a symlink")
+	}
+
+	// 硬链接不能穿过软链指向 dst 外面的文件
+	// /etc 可能与 dst 不在同一个文件系统，链接本来就会失败，所以也检查同一个文件系统中的目录
+	outside := t.TempDir()
+	writeFiles(t, outside, "passwd")
+	for _, linkTarget := range []string{"/etc", outside} {
+		buf.Reset()
+		tw = tar.NewWriter(buf)
+		for _, hdr := range []*tar.Header{
+			{Name: "l", Typeflag: tar.TypeSymlink, Linkname: linkTarget},
+			{Name: "x", Typeflag: tar.TypeLink, Linkname: "l/passwd"},
+		} {
+			if err := tw.WriteHeader(hdr); err != nil {
+				t.Fatalf("write tar header error: %v", err)
+			}
+		}
+		if err := tw.Close(); err != nil {
+			t.Fatalf("close tar writer error: %v", err)
+		}
+		dst = t.TempDir()
+		if err := ExtractTar(buf, dst); err == nil {
+			t.Errorf("expected an error when hardlinking through a symlink to %q", linkTarget)
+		}
+		if _, err := os.Lstat(filepath.Join(dst, "x")); !os.IsNotExist(err) {
+			t.Errorf("expected x not to be created through a symlink to %q, got error: %v", linkTarget, err)
+		}
+	}
+}
+
+// TestExtractTarOverlayXattrs 测试解压时忽略 overlay 使用的扩展属性
+func TestExtractTarOverlayXattrs(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("setting trusted xattrs requires root")
+	}
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+	hdr := &tar.Header{
+		Name:     "a/",
+		Typeflag: tar.TypeDir,
+		Mode:     0755,
+		Format:   tar.FormatPAX,
+		PAXRecords: map[string]string{
+			"SCHILY.xattr.trusted.overlay.opaque":   "y",
+			"SCHILY.xattr.trusted.overlay.redirect": "/b",
+			"SCHILY.xattr.trusted.overlay.metacopy": "",
+			"SCHILY.xattr.user.overlay.opaque":      "y",
+			"SCHILY.xattr.trusted.test":             "1",
+		},
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		t.Fatalf("write tar header error: %v", err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("close tar writer error: %v", err)
+	}
+
+	dst := t.TempDir()
+	if err := ExtractTar(buf, dst); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	names, err := fsutil.ListXattrs(filepath.Join(dst, "a"))
+	if err != nil {
+		t.Fatalf("list xattrs error: %v", err)
+	}
+	if !reflect.DeepEqual(names, []string{"trusted.test"}) {
+		t.Errorf("unexpected xattrs: %v (expected [trusted.test])", names)
+	}
+}
@@ -0,0 +1,19 @@
+//go:build !linux
+
+package changes
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+)
+
+// WriteTar 将视图中的内容以 tar 格式写入 w
+func WriteTar(View, io.Writer) error {
+	return fmt.Errorf("write tar is not supported on %s", runtime.GOOS)
+}
+
+// ExtractTar 将 tar 格式的内容解压到 dst 目录
+func ExtractTar(io.Reader, string) error {
+	return fmt.Errorf("extract tar is not supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,148 @@
+package changes
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Kind 变更类型
+type Kind string
+
+// Kind 的合法值
+const (
+	Added    Kind = "Added"
+	Modified Kind = "Modified"
+	Deleted  Kind = "Deleted"
+)
+
+// Change 一个文件的变更
+type Change struct {
+	// 文件相对路径
+	Path string `json:"path"`
+	// 变更类型
+	Kind Kind `json:"kind"`
+	// 是否目录
+	IsDir bool `json:"isDir,omitempty"`
+}
+
+// Diff 计算 upper 层相对于 lower 视图的变更
+//
+// upperDir 是 overlay 挂载的 upper 目录，其中 whiteout 文件表示删除，不透明目录表示替换整个目录。
+// 与 Compare 一致，删除的目录（包括被文件替换的目录）及其中所有文件都报告为删除。
+func Diff(lower View, upperDir string) ([]Change, error) {
+	var ret []Change
+	// 上层中的不透明目录
+	var opaqueDirs []string
+	err := filepath.WalkDir(upperDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		name, err := filepath.Rel(upperDir, path)
+		if err != nil {
+			return err
+		}
+		if name == "." {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return fmt.Errorf("get info of %q error: %w", path, err)
+		}
+
+		// 下层对应文件
+		lowerEntry, err := lower.Lstat(name)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+
+		switch {
+		case IsWhiteout(info):
+			// 删除
+			if lowerEntry != nil {
+				if err := addAll(lower, lowerEntry, Deleted, &ret); err != nil {
+					return err
+				}
+			}
+		case info.IsDir():
+			switch {
+			case lowerEntry == nil:
+				ret = append(ret, Change{Path: name, Kind: Added, IsDir: true})
+			case !lowerEntry.Info.IsDir():
+				// 文件变为目录，视为先删除再添加
+				ret = append(ret,
+					Change{Path: name, Kind: Deleted},
+					Change{Path: name, Kind: Added, IsDir: true},
+				)
+			case IsOpaque(path) || underAny(name, opaqueDirs):
+				// 不透明目录（或其中的目录），下层中有但是上层中没有的都被删除了
+				opaqueDirs = append(opaqueDirs, name)
+				deleted, err := opaqueDeleted(lower, name, path)
+				if err != nil {
+					return err
+				}
+				ret = append(ret, deleted...)
+			}
+		default:
+			switch {
+			case lowerEntry == nil:
+				ret = append(ret, Change{Path: name, Kind: Added})
+			case lowerEntry.Info.IsDir():
+				// 目录变为文件，视为先删除目录及其中所有文件再添加
+				if err := addAll(lower, lowerEntry, Deleted, &ret); err != nil {
+					return err
+				}
+				ret = append(ret, Change{Path: name, Kind: Added})
+			default:
+				ret = append(ret, Change{Path: name, Kind: Modified})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("walk upper dir %q error: %w", upperDir, err)
+	}
+
+	// 同一路径的删除在添加之前
+	sort.SliceStable(ret, func(i, j int) bool {
+		return ret[i].Path < ret[j].Path
+	})
+	return ret, nil
+}
+
+// underAny 返回 name 是否在 dirs 中任意一个目录下
+func underAny(name string, dirs []string) bool {
+	for _, dir := range dirs {
+		if strings.HasPrefix(name, dir+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// opaqueDeleted 返回不透明目录遮盖掉的下层文件
+func opaqueDeleted(lower View, name, upperPath string) ([]Change, error) {
+	entries, err := lower.ReadDir(name)
+	if err != nil {
+		return nil, err
+	}
+	var ret []Change
+	for _, entry := range entries {
+		_, err := os.Lstat(filepath.Join(upperPath, filepath.Base(entry.Path)))
+		switch {
+		case err == nil:
+			// 上层中有同名文件，由遍历上层时处理
+		case errors.Is(err, os.ErrNotExist):
+			if err := addAll(lower, entry, Deleted, &ret); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, err
+		}
+	}
+	return ret, nil
+}
@@ -0,0 +1,48 @@
+//go:build linux
+
+package changes
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"syscall"
+	"testing"
+)
+
+// TestDiffDeletedDirs 测试 Diff 方法对删除的目录一致地报告其中所有文件
+func TestDiffDeletedDirs(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("creating whiteout requires root")
+	}
+	lower := t.TempDir()
+	upper := t.TempDir()
+	writeFiles(t, lower, "a/1", "a/2/", "b/1", "c/1/x", "c/2")
+	// 删除目录 a ，目录 b 变为文件，目录 c 不透明（删除 c/1 ）
+	writeFiles(t, upper, "b", "c/2")
+	if err := syscall.Mknod(filepath.Join(upper, "a"), syscall.S_IFCHR, 0); err != nil {
+		t.Fatalf("mknod error: %v", err)
+	}
+	if err := syscall.Setxattr(filepath.Join(upper, "c"), overlayOpaqueXattrs[0], []byte("y"), 0); err != nil {
+		t.Fatalf("set opaque xattr error: %v", err)
+	}
+
+	ret, err := Diff(NewView([]string{lower}), upper)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []Change{
+		{Path: "a", Kind: Deleted, IsDir: true},
+		{Path: "a/1", Kind: Deleted},
+		{Path: "a/2", Kind: Deleted, IsDir: true},
+		{Path: "b", Kind: Deleted, IsDir: true},
+		{Path: "b", Kind: Added},
+		{Path: "b/1", Kind: Deleted},
+		{Path: "c/1", Kind: Deleted, IsDir: true},
+		{Path: "c/1/x", Kind: Deleted},
+		{Path: "c/2", Kind: Modified},
+	}
+	if !reflect.DeepEqual(ret, expected) {
+		t.Errorf("unexpected result: %v (expected %v)", ret, expected)
+	}
+}
@@ -0,0 +1,217 @@
+package changes
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeFiles 在 dir 中创建文件，以 / 结尾的是目录
+func writeFiles(t *testing.T, dir string, files ...string) {
+	t.Helper()
+	for _, name := range files {
+		p := filepath.Join(dir, name)
+		if name[len(name)-1] == '/' {
+			if err := os.MkdirAll(p, 0755); err != nil {
+				t.Fatalf("mkdir %q error: %v", p, err)
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatalf("mkdir %q error: %v", filepath.Dir(p), err)
+		}
+		if err := os.WriteFile(p, []byte(name), 0644); err != nil {
+			t.Fatalf("write file %q error: %v", p, err)
+		}
+	}
+}
+
+// TestView_Walk 测试 defaultView.Walk 方法
+func TestView_Walk(t *testing.T) {
+	lower := t.TempDir()
+	upper := t.TempDir()
+	writeFiles(t, lower, "a/1", "a/2", "b")
+	writeFiles(t, upper, "a/3", "c/")
+
+	var ret []string
+	err := NewView([]string{lower, upper}).Walk(func(entry *Entry) error {
+		ret = append(ret, entry.Path)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{"a", "a/1", "a/2", "a/3", "b", "c"}
+	if !reflect.DeepEqual(ret, expected) {
+		t.Errorf("unexpected result: %v (expected %v)", ret, expected)
+	}
+}
+
+// TestView_Lstat 测试 defaultView.Lstat 方法
+func TestView_Lstat(t *testing.T) {
+	lower := t.TempDir()
+	upper := t.TempDir()
+	writeFiles(t, lower, "a/1", "b")
+	writeFiles(t, upper, "b")
+
+	view := NewView([]string{lower, upper})
+	entry, err := view.Lstat("/b")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if entry.Layer != 1 || entry.RealPath != filepath.Join(upper, "b") {
+		t.Errorf("unexpected entry: %#v (expected in layer 1)", entry)
+	}
+	entry, err = view.Lstat("a/1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if entry.Layer != 0 {
+		t.Errorf("unexpected entry: %#v (expected in layer 0)", entry)
+	}
+	if _, err := view.Lstat("a/2"); !os.IsNotExist(err) {
+		t.Errorf("expected not exist error, but: %v", err)
+	}
+}
+
+// TestExportToDir 测试使用记录的不透明目录导出视图，不需要 root 权限
+func TestExportToDir(t *testing.T) {
+	lower := t.TempDir()
+	upper := t.TempDir()
+	writeFiles(t, lower, "a/1", "a/b/1", "c")
+	writeFiles(t, upper, "a/2", "a/b/2")
+
+	// a/b 是不透明目录
+	view := NewViewWithOpaqueDirs([]string{lower, upper}, [][]string{nil, {"a/b"}})
+	dst := t.TempDir()
+	if err := ExportToDir(view, dst); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var ret []string
+	err := NewView([]string{dst}).Walk(func(entry *Entry) error {
+		ret = append(ret, entry.Path)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{"a", "a/1", "a/2", "a/b", "a/b/2", "c"}
+	if !reflect.DeepEqual(ret, expected) {
+		t.Errorf("unexpected result: %v (expected %v)", ret, expected)
+	}
+}
+
+// TestDiff 测试 Diff 方法
+func TestDiff(t *testing.T) {
+	lower := t.TempDir()
+	upper := t.TempDir()
+	writeFiles(t, lower, "a/1", "b", "d/", "e/1", "e/2/")
+	// 目录 e 变为文件，文件 b 变为目录
+	writeFiles(t, upper, "a/1", "a/2", "b/x", "c/x", "e")
+
+	ret, err := Diff(NewView([]string{lower}), upper)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []Change{
+		{Path: "a/1", Kind: Modified},
+		{Path: "a/2", Kind: Added},
+		{Path: "b", Kind: Deleted},
+		{Path: "b", Kind: Added, IsDir: true},
+		{Path: "b/x", Kind: Added},
+		{Path: "c", Kind: Added, IsDir: true},
+		{Path: "c/x", Kind: Added},
+		{Path: "e", Kind: Deleted, IsDir: true},
+		{Path: "e", Kind: Added},
+		{Path: "e/1", Kind: Deleted},
+		{Path: "e/2", Kind: Deleted, IsDir: true},
+	}
+	if !reflect.DeepEqual(ret, expected) {
+		t.Errorf("unexpected result: %v (expected %v)", ret, expected)
+	}
+}
+
+// TestCompare 测试 Compare 方法
+func TestCompare(t *testing.T) {
+	from := t.TempDir()
+	to := t.TempDir()
+	writeFiles(t, from, "a/1", "a/2", "b/1", "c", "d", "e/")
+	// 删除 a/2 ，目录 b 变为文件，修改 d ，目录 e 权限变化，新增目录 f
+	writeFiles(t, to, "a/1", "b", "c", "f/1")
+	if err := os.WriteFile(filepath.Join(to, "d"), []byte("changed"), 0644); err != nil {
+		t.Fatalf("write file error: %v", err)
+	}
+	if err := os.Mkdir(filepath.Join(to, "e"), 0700); err != nil {
+		t.Fatalf("mkdir error: %v", err)
+	}
+
+	ret, err := Compare(NewView([]string{from}), NewView([]string{to}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []Change{
+		{Path: "a/2", Kind: Deleted},
+		{Path: "b", Kind: Deleted, IsDir: true},
+		{Path: "b/1", Kind: Deleted},
+		{Path: "b", Kind: Added},
+		{Path: "d", Kind: Modified},
+		{Path: "e", Kind: Modified, IsDir: true},
+		{Path: "f", Kind: Added, IsDir: true},
+		{Path: "f/1", Kind: Added},
+	}
+	if !reflect.DeepEqual(ret, expected) {
+		t.Errorf("unexpected result: %v (expected %v)", ret, expected)
+	}
+}
+
+// TestSameContent 测试 SameContent 方法
+func TestSameContent(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{"a": "content", "b": "content", "c": "changed", "d": "content"}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("write file error: %v", err)
+		}
+	}
+	if err := os.Chmod(filepath.Join(dir, "d"), 0755); err != nil {
+		t.Fatalf("chmod error: %v", err)
+	}
+	for name, target := range map[string]string{"la": "a", "lb": "a", "lc": "c"} {
+		if err := os.Symlink(target, filepath.Join(dir, name)); err != nil {
+			t.Fatalf("symlink error: %v", err)
+		}
+	}
+
+	view := NewView([]string{dir})
+	cases := []struct {
+		a, b     string
+		expected bool
+	}{
+		{"a", "a", true},
+		{"a", "b", true},
+		{"a", "c", false},
+		{"a", "d", false},
+		{"la", "lb", true},
+		{"la", "lc", false},
+		{"a", "la", false},
+	}
+	for i, c := range cases {
+		a, err := view.Lstat(c.a)
+		if err != nil {
+			t.Fatalf("lstat %q error: %v", c.a, err)
+		}
+		b, err := view.Lstat(c.b)
+		if err != nil {
+			t.Fatalf("lstat %q error: %v", c.b, err)
+		}
+		ret, err := SameContent(a, b)
+		if err != nil {
+			t.Fatalf("unexpected error of the case %d: %v", i, err)
+		}
+		if ret != c.expected {
+			t.Errorf("unexpected result of the case %d: %t (expected: %t)", i, ret, c.expected)
+		}
+	}
+}
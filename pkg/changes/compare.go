@@ -0,0 +1,168 @@
+package changes
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Compare 比较两个视图，返回从 from 到 to 的变更
+//
+// 新增或删除的目录会同时返回目录本身及其中所有文件的变更
+func Compare(from, to View) ([]Change, error) {
+	var ret []Change
+	if err := compareDir(from, to, ".", &ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// compareDir 比较两个视图中的同一个目录
+func compareDir(from, to View, name string, ret *[]Change) error {
+	fromEntries, err := from.ReadDir(name)
+	if err != nil {
+		return err
+	}
+	toEntries, err := to.ReadDir(name)
+	if err != nil {
+		return err
+	}
+
+	// 两个列表都是按路径排序的，归并比较
+	i, j := 0, 0
+	for i < len(fromEntries) || j < len(toEntries) {
+		switch {
+		case j >= len(toEntries) || (i < len(fromEntries) && fromEntries[i].Path < toEntries[j].Path):
+			// 仅在 from 中
+			if err := addAll(from, fromEntries[i], Deleted, ret); err != nil {
+				return err
+			}
+			i++
+		case i >= len(fromEntries) || fromEntries[i].Path > toEntries[j].Path:
+			// 仅在 to 中
+			if err := addAll(to, toEntries[j], Added, ret); err != nil {
+				return err
+			}
+			j++
+		default:
+			// 都有
+			a, b := fromEntries[i], toEntries[j]
+			i++
+			j++
+			if a.Info.IsDir() && b.Info.IsDir() {
+				if a.Info.Mode() != b.Info.Mode() {
+					*ret = append(*ret, Change{Path: b.Path, Kind: Modified, IsDir: true})
+				}
+				if err := compareDir(from, to, a.Path, ret); err != nil {
+					return err
+				}
+				continue
+			}
+			if a.Info.IsDir() != b.Info.IsDir() {
+				// 类型变了，视为先删除再添加
+				if err := addAll(from, a, Deleted, ret); err != nil {
+					return err
+				}
+				if err := addAll(to, b, Added, ret); err != nil {
+					return err
+				}
+				continue
+			}
+			same, err := SameContent(a, b)
+			if err != nil {
+				return err
+			}
+			if !same {
+				*ret = append(*ret, Change{Path: b.Path, Kind: Modified})
+			}
+		}
+	}
+	return nil
+}
+
+// addAll 将文件及其中所有文件添加为指定类型的变更
+func addAll(view View, entry *Entry, kind Kind, ret *[]Change) error {
+	*ret = append(*ret, Change{Path: entry.Path, Kind: kind, IsDir: entry.Info.IsDir()})
+	if !entry.Info.IsDir() {
+		return nil
+	}
+	entries, err := view.ReadDir(entry.Path)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if err := addAll(view, e, kind, ret); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SameContent 返回两个非目录文件的类型、权限和内容是否一致
+func SameContent(a, b *Entry) (bool, error) {
+	if a.RealPath == b.RealPath {
+		// 同一层中的同一个文件
+		return true, nil
+	}
+	if a.Info.Mode() != b.Info.Mode() {
+		return false, nil
+	}
+
+	switch {
+	case a.Info.Mode()&os.ModeSymlink != 0:
+		aTarget, err := os.Readlink(a.RealPath)
+		if err != nil {
+			return false, fmt.Errorf("read link %q error: %w", a.RealPath, err)
+		}
+		bTarget, err := os.Readlink(b.RealPath)
+		if err != nil {
+			return false, fmt.Errorf("read link %q error: %w", b.RealPath, err)
+		}
+		return aTarget == bTarget, nil
+	case a.Info.Mode().IsRegular():
+		if a.Info.Size() != b.Info.Size() {
+			return false, nil
+		}
+		return sameFileContent(a.RealPath, b.RealPath)
+	default:
+		// 设备、管道等特殊文件仅比较类型和权限
+		return true, nil
+	}
+}
+
+// sameFileContent 返回两个普通文件内容是否一致
+func sameFileContent(aPath, bPath string) (bool, error) {
+	aFile, err := os.Open(aPath)
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = aFile.Close() }()
+	bFile, err := os.Open(bPath)
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = bFile.Close() }()
+
+	aBuf := make([]byte, 32*1024)
+	bBuf := make([]byte, 32*1024)
+	for {
+		aN, aErr := io.ReadFull(aFile, aBuf)
+		bN, bErr := io.ReadFull(bFile, bBuf)
+		if !bytes.Equal(aBuf[:aN], bBuf[:bN]) {
+			return false, nil
+		}
+		aDone := aErr == io.EOF || aErr == io.ErrUnexpectedEOF
+		bDone := bErr == io.EOF || bErr == io.ErrUnexpectedEOF
+		switch {
+		case aErr != nil && !aDone:
+			return false, aErr
+		case bErr != nil && !bDone:
+			return false, bErr
+		case aDone && bDone:
+			return true, nil
+		case aDone != bDone:
+			return false, nil
+		}
+	}
+}
@@ -0,0 +1,169 @@
+//go:build linux
+
+package changes
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	fsutil "github.com/yhlooo/stackcrisp/pkg/utils/fs"
+)
+
+// MakeDiffLayer 将 dir 中的完整内容转换为叠加在 base 视图上的差异层
+//
+// dir 中与 base 视图中相同的文件被删除， base 视图中有但是 dir 中没有的文件以 whiteout 文件表示删除，
+// 使得以 dir 作为 base 视图上层叠加后看到的内容与转换前 dir 中的内容一致。
+// 文件类型、权限、所有者、扩展属性和内容都相同的文件视为相同，不比较修改时间。
+func MakeDiffLayer(base View, dir string) error {
+	_, err := reduceDir(base, dir, ".")
+	return err
+}
+
+// reduceDir 删除 dir 中指定目录下与 base 视图中相同的文件，并为被删除的文件创建 whiteout
+//
+// base 视图中该路径需要是目录。返回处理后目录中是否已经没有内容
+func reduceDir(base View, dir, name string) (bool, error) {
+	realDir := filepath.Join(dir, name)
+	dirInfo, err := os.Lstat(realDir)
+	if err != nil {
+		return false, fmt.Errorf("lstat %q error: %w", realDir, err)
+	}
+
+	baseEntries, err := base.ReadDir(name)
+	if err != nil {
+		return false, err
+	}
+	items, err := os.ReadDir(realDir)
+	if err != nil {
+		return false, fmt.Errorf("read dir %q error: %w", realDir, err)
+	}
+	empty := true
+
+	// 被删除的文件
+	exists := make(map[string]bool, len(items))
+	for _, item := range items {
+		exists[item.Name()] = true
+	}
+	for _, entry := range baseEntries {
+		if exists[filepath.Base(entry.Path)] {
+			continue
+		}
+		if err := createWhiteout(filepath.Join(dir, entry.Path)); err != nil {
+			return false, err
+		}
+		empty = false
+	}
+
+	// 新增或者修改的文件
+	for _, item := range items {
+		entry := &Entry{
+			Path:     filepath.Join(name, item.Name()),
+			RealPath: filepath.Join(realDir, item.Name()),
+		}
+		if entry.Info, err = os.Lstat(entry.RealPath); err != nil {
+			return false, fmt.Errorf("lstat %q error: %w", entry.RealPath, err)
+		}
+		baseEntry, err := base.Lstat(entry.Path)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				empty = false
+				continue
+			}
+			return false, err
+		}
+		if entry.Info.IsDir() != baseEntry.Info.IsDir() {
+			// 类型变了，上层的文件会遮盖下层的
+			empty = false
+			continue
+		}
+
+		same, err := sameMetadata(baseEntry, entry)
+		if err != nil {
+			return false, err
+		}
+		if entry.Info.IsDir() {
+			childrenEmpty, err := reduceDir(base, dir, entry.Path)
+			if err != nil {
+				return false, err
+			}
+			same = same && childrenEmpty
+		} else if same {
+			if same, err = SameContent(baseEntry, entry); err != nil {
+				return false, err
+			}
+		}
+		if !same {
+			empty = false
+			continue
+		}
+		if err := os.Remove(entry.RealPath); err != nil {
+			return false, fmt.Errorf("remove %q error: %w", entry.RealPath, err)
+		}
+	}
+
+	// 删除文件会改变目录的修改时间，恢复原来的时间
+	if stat, ok := dirInfo.Sys().(*syscall.Stat_t); ok {
+		if err := syscall.UtimesNano(realDir, []syscall.Timespec{stat.Atim, stat.Mtim}); err != nil {
+			return false, fmt.Errorf("set times of %q error: %w", realDir, err)
+		}
+	}
+	return empty, nil
+}
+
+// sameMetadata 返回两个文件的类型、权限、所有者和扩展属性是否一致
+func sameMetadata(a, b *Entry) (bool, error) {
+	if a.Info.Mode() != b.Info.Mode() {
+		return false, nil
+	}
+	aStat, aOK := a.Info.Sys().(*syscall.Stat_t)
+	bStat, bOK := b.Info.Sys().(*syscall.Stat_t)
+	if !aOK || !bOK {
+		return false, nil
+	}
+	if aStat.Uid != bStat.Uid || aStat.Gid != bStat.Gid {
+		return false, nil
+	}
+	if a.Info.Mode()&os.ModeSymlink != 0 {
+		// 读取扩展属性会穿透软链
+		return true, nil
+	}
+	aXattrs, err := userXattrs(a.RealPath)
+	if err != nil {
+		return false, err
+	}
+	bXattrs, err := userXattrs(b.RealPath)
+	if err != nil {
+		return false, err
+	}
+	if len(aXattrs) != len(bXattrs) {
+		return false, nil
+	}
+	for name, value := range aXattrs {
+		if other, ok := bXattrs[name]; !ok || !bytes.Equal(value, other) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// userXattrs 返回文件除 overlay 内部使用的以外的扩展属性
+func userXattrs(path string) (map[string][]byte, error) {
+	names, err := fsutil.ListXattrs(path)
+	if err != nil {
+		return nil, err
+	}
+	ret := make(map[string][]byte, len(names))
+	for _, name := range names {
+		if isOverlayXattr(name) {
+			continue
+		}
+		if ret[name], err = fsutil.GetXattr(path, name); err != nil {
+			return nil, err
+		}
+	}
+	return ret, nil
+}
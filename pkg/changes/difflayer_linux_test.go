@@ -0,0 +1,61 @@
+//go:build linux
+
+package changes
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// TestMakeDiffLayer 测试 MakeDiffLayer 方法
+func TestMakeDiffLayer(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("creating whiteout requires root")
+	}
+	lower := t.TempDir()
+	writeFiles(t, lower, "a/1", "a/2", "b/1", "c", "d")
+	dir := t.TempDir()
+	// 删除 a/2 和 c ，修改 d ，新增 e
+	writeFiles(t, dir, "a/1", "b/1", "d", "e")
+	if err := os.WriteFile(filepath.Join(dir, "d"), []byte("changed"), 0644); err != nil {
+		t.Fatalf("write file error: %v", err)
+	}
+	expected, err := Compare(NewView([]string{lower}), NewView([]string{dir}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	base := NewView([]string{lower})
+	if err := MakeDiffLayer(base, dir); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// 相同的文件和目录被删除
+	var ret []string
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if path != dir {
+			ret = append(ret, path[len(dir)+1:])
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("walk error: %v", err)
+	}
+	if layerFiles := []string{"a", "a/2", "c", "d", "e"}; !reflect.DeepEqual(ret, layerFiles) {
+		t.Errorf("unexpected files in layer: %v (expected %v)", ret, layerFiles)
+	}
+
+	// 叠加后的内容与原来一致
+	changes, err := Compare(base, NewView([]string{lower, dir}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("unexpected changes: %v (expected %v)", changes, expected)
+	}
+}
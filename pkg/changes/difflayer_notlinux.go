@@ -0,0 +1,13 @@
+//go:build !linux
+
+package changes
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// MakeDiffLayer 将 dir 中的完整内容转换为叠加在 base 视图上的差异层
+func MakeDiffLayer(View, string) error {
+	return fmt.Errorf("make diff layer is not supported on %s", runtime.GOOS)
+}
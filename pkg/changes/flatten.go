@@ -0,0 +1,48 @@
+package changes
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	fsutil "github.com/yhlooo/stackcrisp/pkg/utils/fs"
+)
+
+// Flatten 将视图中的内容复制到 dst 目录，得到一个与视图内容一致的单层目录
+//
+// 结果中不包含 whiteout 文件，也没有 overlay 使用的扩展属性。 dst 目录需要已经存在。
+func Flatten(view View, dst string) error {
+	c := fsutil.NewCopier()
+	c.SkipXattr = isOverlayXattr
+	return copyView(view, dst, c)
+}
+
+// ExportToDir 将视图中的内容复制到 dst 目录，与 Flatten 相同，但是可以由非 root 用户执行
+//
+// 非 root 用户执行时不保留所有者，也不保留没有权限设置的扩展属性。 dst 目录需要已经存在。
+func ExportToDir(view View, dst string) error {
+	c := fsutil.NewCopier()
+	c.SkipXattr = isOverlayXattr
+	c.Unprivileged = os.Geteuid() != 0
+	return copyView(view, dst, c)
+}
+
+// copyView 使用 c 将视图中的内容复制到 dst 目录
+func copyView(view View, dst string, c *fsutil.Copier) error {
+	// 根目录的所有者和权限
+	root, err := view.Lstat(".")
+	if err != nil {
+		return fmt.Errorf("lstat root of view error: %w", err)
+	}
+	if err := c.Copy(root.RealPath, dst, root.Info); err != nil {
+		return err
+	}
+
+	err = view.Walk(func(entry *Entry) error {
+		return c.Copy(entry.RealPath, filepath.Join(dst, entry.Path), entry.Info)
+	})
+	if err != nil {
+		return err
+	}
+	return c.Finish()
+}
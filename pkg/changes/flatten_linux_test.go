@@ -0,0 +1,76 @@
+//go:build linux
+
+package changes
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"syscall"
+	"testing"
+
+	fsutil "github.com/yhlooo/stackcrisp/pkg/utils/fs"
+)
+
+// TestFlatten 测试 Flatten 方法
+func TestFlatten(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("creating whiteout requires root")
+	}
+	lower := t.TempDir()
+	middle := t.TempDir()
+	upper := t.TempDir()
+	writeFiles(t, lower, "a/1", "a/2", "b", "d/x")
+	writeFiles(t, middle, "a/3", "c", "d/")
+	writeFiles(t, upper, "a/1")
+	// 删除 b 和 a/2 ，并且 d 在 middle 中是不透明目录
+	for _, p := range []string{filepath.Join(middle, "b"), filepath.Join(upper, "a/2")} {
+		if err := syscall.Mknod(p, syscall.S_IFCHR, 0); err != nil {
+			t.Fatalf("mknod %q error: %v", p, err)
+		}
+	}
+	if err := syscall.Setxattr(filepath.Join(middle, "d"), overlayOpaqueXattrs[0], []byte("y"), 0); err != nil {
+		t.Fatalf("set opaque xattr error: %v", err)
+	}
+	// c 上有 overlay 的其它标记和普通扩展属性
+	for name, value := range map[string]string{
+		"trusted.overlay.redirect": "/x",
+		"trusted.overlay.metacopy": "",
+		"trusted.test":             "kept",
+	} {
+		if err := syscall.Setxattr(filepath.Join(middle, "c"), name, []byte(value), 0); err != nil {
+			t.Fatalf("set xattr %q error: %v", name, err)
+		}
+	}
+
+	dst := t.TempDir()
+	if err := Flatten(NewView([]string{lower, middle, upper}), dst); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var ret []string
+	err := NewView([]string{dst}).Walk(func(entry *Entry) error {
+		ret = append(ret, entry.Path)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{"a", "a/1", "a/3", "c", "d"}
+	if !reflect.DeepEqual(ret, expected) {
+		t.Errorf("unexpected result: %v (expected %v)", ret, expected)
+	}
+	if content, err := os.ReadFile(filepath.Join(dst, "a/1")); err != nil || string(content) != "a/1" {
+		t.Errorf("unexpected content of a/1: %q, %v", content, err)
+	}
+	if IsOpaque(filepath.Join(dst, "d")) {
+		t.Errorf("expected d not opaque")
+	}
+	names, err := fsutil.ListXattrs(filepath.Join(dst, "c"))
+	if err != nil {
+		t.Fatalf("list xattrs of c error: %v", err)
+	}
+	if !reflect.DeepEqual(names, []string{"trusted.test"}) {
+		t.Errorf("unexpected xattrs of c: %v (expected [trusted.test])", names)
+	}
+}
@@ -0,0 +1,115 @@
+//go:build linux
+
+package changes
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+const (
+	// ociWhiteoutPrefix OCI 镜像层中表示删除下层同名文件的 whiteout 文件名前缀
+	ociWhiteoutPrefix = ".wh."
+	// ociOpaqueWhiteout OCI 镜像层中表示所在目录为不透明目录的 whiteout 文件名
+	ociOpaqueWhiteout = ".wh..wh..opq"
+)
+
+// WriteLayerTar 将层目录中的内容以 OCI 镜像层的 tar 格式写入 w
+//
+// overlay whiteout 文件转换为 .wh.<name> 文件，不透明目录在其中添加 .wh..wh..opq 文件
+func WriteLayerTar(dir string, w io.Writer) error {
+	tw := tar.NewWriter(w)
+	// 已写入的 inode 与归档中的路径，用于还原硬链接
+	links := map[[2]uint64]string{}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		name, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		if name == "." {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return fmt.Errorf("get info of %q error: %w", path, err)
+		}
+
+		if IsWhiteout(info) {
+			whName := filepath.Join(filepath.Dir(name), ociWhiteoutPrefix+filepath.Base(name))
+			return writeOCIWhiteout(tw, whName, info)
+		}
+		if err := writeTarEntry(tw, &Entry{Path: name, RealPath: path, Info: info}, links); err != nil {
+			return err
+		}
+		if info.IsDir() && IsOpaque(path) {
+			return writeOCIWhiteout(tw, filepath.Join(name, ociOpaqueWhiteout), info)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("walk layer dir %q error: %w", dir, err)
+	}
+	return tw.Close()
+}
+
+// ExtractLayerTar 将 OCI 镜像层的 tar 格式内容解压到层目录 dst
+//
+// 与 ExtractTar 相同，但是 .wh.<name> 文件转换为 overlay whiteout 文件，
+// .wh..wh..opq 文件转换为所在目录的不透明标记
+func ExtractLayerTar(r io.Reader, dst string) error {
+	return extractTar(r, dst, true)
+}
+
+// writeOCIWhiteout 写入一个 OCI whiteout 文件
+func writeOCIWhiteout(tw *tar.Writer, name string, info os.FileInfo) error {
+	hdr := &tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     filepath.ToSlash(name),
+		Mode:     0600,
+		ModTime:  info.ModTime(),
+		Format:   tar.FormatPAX,
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return fmt.Errorf("write tar header of %q error: %w", name, err)
+	}
+	return nil
+}
+
+// extractOCIWhiteout 如果 tar 中的文件是 OCI whiteout 文件，将其转换为 overlay 的表示，并返回 true
+func extractOCIWhiteout(hdr *tar.Header, dst string) (bool, error) {
+	base := filepath.Base(hdr.Name)
+	if !strings.HasPrefix(base, ociWhiteoutPrefix) {
+		return false, nil
+	}
+	if err := mkdirParents(dst, hdr.Name); err != nil {
+		return false, err
+	}
+	parent := filepath.Join(dst, filepath.Dir(hdr.Name))
+
+	// 不透明目录
+	if base == ociOpaqueWhiteout {
+		if err := syscall.Setxattr(parent, overlayOpaqueXattrs[0], []byte("y"), 0); err != nil {
+			return false, fmt.Errorf("set opaque xattr of %q error: %w", parent, err)
+		}
+		return true, nil
+	}
+
+	// 删除下层同名文件
+	target := filepath.Join(parent, strings.TrimPrefix(base, ociWhiteoutPrefix))
+	if err := os.RemoveAll(target); err != nil {
+		return false, fmt.Errorf("remove %q error: %w", target, err)
+	}
+	if err := createWhiteout(target); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,65 @@
+//go:build linux
+
+package changes
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"syscall"
+	"testing"
+)
+
+// TestLayerTar 测试 WriteLayerTar 和 ExtractLayerTar 方法
+func TestLayerTar(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("creating whiteout requires root")
+	}
+	src := t.TempDir()
+	writeFiles(t, src, "a/1", "d/x")
+	if err := syscall.Mknod(filepath.Join(src, "a/2"), syscall.S_IFCHR, 0); err != nil {
+		t.Fatalf("mknod error: %v", err)
+	}
+	if err := syscall.Setxattr(filepath.Join(src, "d"), overlayOpaqueXattrs[0], []byte("y"), 0); err != nil {
+		t.Fatalf("set opaque xattr error: %v", err)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := WriteLayerTar(src, buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var names []string
+	tr := tar.NewReader(bytes.NewReader(buf.Bytes()))
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("read tar error: %v", err)
+		}
+		names = append(names, hdr.Name)
+	}
+	expected := []string{"a/", "a/1", "a/.wh.2", "d/", "d/.wh..wh..opq", "d/x"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("unexpected entries: %v (expected %v)", names, expected)
+	}
+
+	dst := t.TempDir()
+	if err := ExtractLayerTar(buf, dst); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	info, err := os.Lstat(filepath.Join(dst, "a/2"))
+	if err != nil || !IsWhiteout(info) {
+		t.Errorf("expected a/2 to be a whiteout, error: %v", err)
+	}
+	if !IsOpaque(filepath.Join(dst, "d")) {
+		t.Errorf("expected d to be opaque")
+	}
+	if _, err := os.Lstat(filepath.Join(dst, "d/x")); err != nil {
+		t.Errorf("expected d/x to exist, error: %v", err)
+	}
+}
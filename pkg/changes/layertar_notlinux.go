@@ -0,0 +1,19 @@
+//go:build !linux
+
+package changes
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+)
+
+// WriteLayerTar 将层目录中的内容以 OCI 镜像层的 tar 格式写入 w
+func WriteLayerTar(string, io.Writer) error {
+	return fmt.Errorf("write layer tar is not supported on %s", runtime.GOOS)
+}
+
+// ExtractLayerTar 将 OCI 镜像层的 tar 格式内容解压到层目录 dst
+func ExtractLayerTar(io.Reader, string) error {
+	return fmt.Errorf("extract layer tar is not supported on %s", runtime.GOOS)
+}
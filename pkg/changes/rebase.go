@@ -0,0 +1,63 @@
+package changes
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Conflicts 返回将 upper 层从 oldBase 移到 newBase 上时会产生冲突的路径
+//
+// upper 层中涉及的每个路径（包括 whiteout 和为了存放变更而复制上来的目录）在 oldBase 和 newBase 中的状态一致时，
+// upper 层中的变更才能原样作用于 newBase 。
+func Conflicts(upperDir string, oldBase, newBase View) ([]string, error) {
+	var ret []string
+	err := filepath.WalkDir(upperDir, func(path string, _ fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		name, err := filepath.Rel(upperDir, path)
+		if err != nil {
+			return err
+		}
+		if name == "." {
+			return nil
+		}
+		same, err := sameInViews(name, oldBase, newBase)
+		if err != nil {
+			return err
+		}
+		if !same {
+			ret = append(ret, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("walk upper dir %q error: %w", upperDir, err)
+	}
+	return ret, nil
+}
+
+// sameInViews 返回指定路径在两个视图中的状态是否一致
+//
+// 目录只比较是否都是目录，不比较其中的内容
+func sameInViews(name string, a, b View) (bool, error) {
+	aEntry, err := a.Lstat(name)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return false, err
+	}
+	bEntry, err := b.Lstat(name)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return false, err
+	}
+	switch {
+	case aEntry == nil || bEntry == nil:
+		return aEntry == nil && bEntry == nil, nil
+	case aEntry.Info.IsDir() || bEntry.Info.IsDir():
+		return aEntry.Info.IsDir() && bEntry.Info.IsDir(), nil
+	default:
+		return SameContent(aEntry, bEntry)
+	}
+}
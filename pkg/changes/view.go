@@ -0,0 +1,298 @@
+package changes
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/yhlooo/stackcrisp/pkg/layers"
+)
+
+// Entry 视图中的一个文件
+type Entry struct {
+	// 在视图中的相对路径
+	Path string
+	// 文件实际所在路径
+	RealPath string
+	// 文件所在层在视图中的序号
+	Layer int
+	// 文件信息（不穿透软链）
+	Info os.FileInfo
+}
+
+// WalkFunc 遍历视图时对每个文件调用的方法
+//
+// 对于目录，如果返回 filepath.SkipDir 则不再遍历其中的内容
+type WalkFunc func(entry *Entry) error
+
+// View 多个层叠加后的只读视图
+//
+// 与 overlay 挂载看到的内容一致，但是不需要挂载
+type View interface {
+	// Dirs 返回视图包含的层目录，第 0 个元素是最底层
+	Dirs() []string
+	// Lstat 获取视图中指定路径文件（不穿透软链）
+	//
+	// 文件不存在时返回的错误满足 errors.Is(err, os.ErrNotExist)
+	Lstat(name string) (*Entry, error)
+	// ReadDir 列出视图中指定目录下的文件，按文件名排序
+	ReadDir(name string) ([]*Entry, error)
+	// Walk 按字典序深度优先遍历视图中的所有文件（不包括根目录）
+	Walk(fn WalkFunc) error
+}
+
+// NewView 创建一个视图
+//
+// dirs 中第 0 个元素是最底层，第 n-1 个元素是最顶层。
+func NewView(dirs []string) View {
+	return &defaultView{dirs: dirs}
+}
+
+// NewViewWithOpaqueDirs 创建一个视图，使用记录的不透明目录代替读取扩展属性
+//
+// 非 root 用户不能读取 trusted.overlay.opaque 扩展属性，需要通过记录的不透明目录识别。
+// opaqueDirs[i] 是 dirs[i] 中所有不透明目录的相对路径，为 nil 表示没有记录，仍然读取扩展属性
+func NewViewWithOpaqueDirs(dirs []string, opaqueDirs [][]string) View {
+	opaque := make([]map[string]bool, len(dirs))
+	for i := 0; i < len(dirs) && i < len(opaqueDirs); i++ {
+		if opaqueDirs[i] == nil {
+			continue
+		}
+		opaque[i] = map[string]bool{}
+		for _, name := range opaqueDirs[i] {
+			opaque[i][cleanName(name)] = true
+		}
+	}
+	return &defaultView{dirs: dirs, opaque: opaque}
+}
+
+// NewLayersView 创建由 layerSet 中各层叠加而成的视图，使用各层记录的不透明目录
+//
+// layerSet 中第 0 个元素是最底层
+func NewLayersView(layerSet []layers.Layer) View {
+	dirs := make([]string, len(layerSet))
+	opaqueDirs := make([][]string, len(layerSet))
+	for i, l := range layerSet {
+		dirs[i] = l.DiffDir()
+		opaqueDirs[i], _ = l.OpaqueDirs()
+	}
+	return NewViewWithOpaqueDirs(dirs, opaqueDirs)
+}
+
+// defaultView 是 View 的一个默认实现
+type defaultView struct {
+	dirs []string
+	// 各层中记录的不透明目录，为 nil 时读取扩展属性
+	opaque []map[string]bool
+}
+
+var _ View = &defaultView{}
+
+// Dirs 返回视图包含的层目录，第 0 个元素是最底层
+func (v *defaultView) Dirs() []string {
+	return v.dirs
+}
+
+// Lstat 获取视图中指定路径文件（不穿透软链）
+func (v *defaultView) Lstat(name string) (*Entry, error) {
+	name = cleanName(name)
+	hits, err := v.resolve(name)
+	if err != nil {
+		return nil, err
+	}
+	if len(hits) == 0 {
+		return nil, &os.PathError{Op: "lstat", Path: name, Err: os.ErrNotExist}
+	}
+	return hits[0], nil
+}
+
+// ReadDir 列出视图中指定目录下的文件，按文件名排序
+func (v *defaultView) ReadDir(name string) ([]*Entry, error) {
+	name = cleanName(name)
+	hits, err := v.resolve(name)
+	if err != nil {
+		return nil, err
+	}
+	if len(hits) == 0 {
+		return nil, &os.PathError{Op: "readdir", Path: name, Err: os.ErrNotExist}
+	}
+	if !hits[0].Info.IsDir() {
+		return nil, &os.PathError{Op: "readdir", Path: name, Err: fmt.Errorf("not a directory")}
+	}
+
+	// 从上往下合并各层的内容，上层的同名文件（包括 whiteout ）遮盖下层
+	seen := map[string]bool{}
+	var ret []*Entry
+	for _, hit := range hits {
+		if !hit.Info.IsDir() {
+			break
+		}
+		items, err := os.ReadDir(hit.RealPath)
+		if err != nil {
+			return nil, fmt.Errorf("read dir %q error: %w", hit.RealPath, err)
+		}
+		for _, item := range items {
+			if seen[item.Name()] {
+				continue
+			}
+			seen[item.Name()] = true
+			info, err := item.Info()
+			if err != nil {
+				return nil, fmt.Errorf("get info of %q error: %w", filepath.Join(hit.RealPath, item.Name()), err)
+			}
+			if IsWhiteout(info) {
+				continue
+			}
+			ret = append(ret, &Entry{
+				Path:     filepath.Join(name, item.Name()),
+				RealPath: filepath.Join(hit.RealPath, item.Name()),
+				Layer:    hit.Layer,
+				Info:     info,
+			})
+		}
+	}
+
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Path < ret[j].Path
+	})
+	return ret, nil
+}
+
+// Walk 按字典序深度优先遍历视图中的所有文件（不包括根目录）
+func (v *defaultView) Walk(fn WalkFunc) error {
+	return v.walk(".", fn)
+}
+
+// walk 遍历视图中指定目录下的所有文件
+func (v *defaultView) walk(name string, fn WalkFunc) error {
+	entries, err := v.ReadDir(name)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if err := fn(entry); err != nil {
+			if errors.Is(err, filepath.SkipDir) && entry.Info.IsDir() {
+				continue
+			}
+			return err
+		}
+		if entry.Info.IsDir() {
+			if err := v.walk(entry.Path, fn); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// resolve 找到视图中指定路径在各层中可见的文件
+//
+// 返回结果中第 0 个元素是最顶层的文件，即视图中实际看到的文件。
+// 只有目录会与下层合并，所以除第 0 个元素外其余元素都应该是目录。
+func (v *defaultView) resolve(name string) ([]*Entry, error) {
+	var components []string
+	if name != "." {
+		components = strings.Split(name, string(filepath.Separator))
+	}
+
+	var hits []*Entry
+	for i := len(v.dirs) - 1; i >= 0; i-- {
+		cur := v.dirs[i]
+		// 祖先目录中有不透明目录，下层的内容不可见
+		hidden := false
+		found := true
+		for k, c := range components {
+			cur = filepath.Join(cur, c)
+			curName := filepath.Join(components[:k+1]...)
+			info, err := os.Lstat(cur)
+			if err != nil {
+				if errors.Is(err, os.ErrNotExist) {
+					found = false
+					break
+				}
+				return nil, fmt.Errorf("lstat %q error: %w", cur, err)
+			}
+			if IsWhiteout(info) {
+				// 被删除了
+				return hits, nil
+			}
+			if k == len(components)-1 {
+				break
+			}
+			if !info.IsDir() {
+				// 祖先不是目录，下层的内容都不可见
+				return hits, nil
+			}
+			if v.isOpaque(i, curName, cur) {
+				hidden = true
+			}
+		}
+		if found {
+			info, err := os.Lstat(cur)
+			if err != nil {
+				return nil, fmt.Errorf("lstat %q error: %w", cur, err)
+			}
+			hits = append(hits, &Entry{
+				Path:     name,
+				RealPath: cur,
+				Layer:    i,
+				Info:     info,
+			})
+			if !info.IsDir() || v.isOpaque(i, name, cur) {
+				return hits, nil
+			}
+		}
+		if hidden {
+			return hits, nil
+		}
+	}
+
+	return hits, nil
+}
+
+// isOpaque 返回第 layer 层中相对路径为 name 、实际路径为 realPath 的目录是否不透明目录
+func (v *defaultView) isOpaque(layer int, name, realPath string) bool {
+	if layer < len(v.opaque) && v.opaque[layer] != nil {
+		return v.opaque[layer][name]
+	}
+	return IsOpaque(realPath)
+}
+
+// FindOpaqueDirs 返回层目录 dir 中所有不透明目录的相对路径
+func FindOpaqueDirs(dir string) ([]string, error) {
+	ret := []string{}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if IsOpaque(path) {
+			name, err := filepath.Rel(dir, path)
+			if err != nil {
+				return err
+			}
+			ret = append(ret, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("walk %q error: %w", dir, err)
+	}
+	return ret, nil
+}
+
+// cleanName 规范化视图中的相对路径
+func cleanName(name string) string {
+	name = filepath.Clean(string(filepath.Separator) + name)
+	name = strings.TrimPrefix(name, string(filepath.Separator))
+	if name == "" {
+		return "."
+	}
+	return name
+}
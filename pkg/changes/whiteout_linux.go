@@ -0,0 +1,62 @@
+//go:build linux
+
+package changes
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+)
+
+// overlayOpaqueXattrs 标记 overlay 不透明目录的扩展属性
+var overlayOpaqueXattrs = []string{"trusted.overlay.opaque", "user.overlay.opaque"}
+
+// overlayXattrPrefixes overlay 内部使用的扩展属性前缀，包括不透明目录、重定向、元数据复制、来源等标记
+var overlayXattrPrefixes = []string{"trusted.overlay.", "user.overlay."}
+
+// IsWhiteout 返回文件是否 overlay whiteout 文件
+//
+// whiteout 文件是设备号为 0/0 的字符设备
+func IsWhiteout(info os.FileInfo) bool {
+	if info == nil || info.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return false
+	}
+	return stat.Rdev == 0
+}
+
+// IsOpaque 返回目录是否 overlay 不透明目录
+//
+// 不透明目录会遮盖下层中的同名目录的所有内容
+func IsOpaque(path string) bool {
+	buf := make([]byte, 1)
+	for _, attr := range overlayOpaqueXattrs {
+		n, err := syscall.Getxattr(path, attr, buf)
+		if err == nil && n == 1 && buf[0] == 'y' {
+			return true
+		}
+	}
+	return false
+}
+
+// isOverlayXattr 返回扩展属性是否 overlay 内部使用的
+func isOverlayXattr(name string) bool {
+	for _, prefix := range overlayXattrPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// createWhiteout 在 path 创建 whiteout 文件，表示删除下层中的同名文件
+func createWhiteout(path string) error {
+	if err := syscall.Mknod(path, syscall.S_IFCHR, 0); err != nil {
+		return fmt.Errorf("create whiteout %q error: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+//go:build !linux
+
+package changes
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+)
+
+// IsWhiteout 返回文件是否 overlay whiteout 文件
+func IsWhiteout(os.FileInfo) bool {
+	return false
+}
+
+// IsOpaque 返回目录是否 overlay 不透明目录
+func IsOpaque(string) bool {
+	return false
+}
+
+// isOverlayXattr 返回扩展属性是否 overlay 内部使用的
+func isOverlayXattr(string) bool {
+	return false
+}
+
+// createWhiteout 在 path 创建 whiteout 文件
+func createWhiteout(string) error {
+	return fmt.Errorf("create whiteout is not supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,140 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/go-logr/logr"
+	"github.com/spf13/cobra"
+
+	"github.com/yhlooo/stackcrisp/pkg/changes"
+	"github.com/yhlooo/stackcrisp/pkg/commands/options"
+	cmdutil "github.com/yhlooo/stackcrisp/pkg/utils/cmd"
+	"github.com/yhlooo/stackcrisp/pkg/utils/compress"
+	fsutil "github.com/yhlooo/stackcrisp/pkg/utils/fs"
+)
+
+// NewArchiveCommandWithOptions 创建一个基于选项的 archive 命令
+func NewArchiveCommandWithOptions(opts *options.ArchiveOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "archive <revision> (-o <file> | --to-dir <dir>)",
+		Short: "Export the files of a revision as an archive or a directory",
+		Long: "Export the files of a revision as an archive or a directory.\n\n" +
+			"The files are computed from the layers of the revision without mounting, " +
+			"keeping ownership, modes, xattrs, symlinks and hardlinks. " +
+			"Opaque directories are recognized from the record made when the layers are committed, " +
+			"so root privileges are not required. " +
+			"Without root privileges, trusted xattrs can not be read, " +
+			"and ownership is not kept when exporting to a directory.",
+		GroupID: groupState,
+		Annotations: map[string]string{
+			cmdutil.AnnotationRequireManager: cmdutil.AnnotationValueTrue,
+		},
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			logger := logr.FromContextOrDiscard(ctx).WithName(loggerName)
+
+			if (opts.Output == "") == (opts.ToDir == "") {
+				return fmt.Errorf("exactly one of --output and --to-dir is required")
+			}
+			if os.Geteuid() != 0 {
+				logger.Info("WARN not running as root, trusted xattrs can not be read")
+			}
+
+			// 获取管理器
+			mgr := cmdutil.ManagerFromContext(ctx)
+
+			// 获取视图
+			var view changes.View
+			if opts.Space != "" {
+				var err error
+				if view, err = mgr.SpaceView(ctx, opts.Space, args[0]); err != nil {
+					return err
+				}
+			} else {
+				ws, err := mgr.GetWorkspaceFromPath(ctx, ".")
+				if err != nil {
+					return fmt.Errorf("get workspace from path \".\" error: %w", err)
+				}
+				if view, err = ws.GetView(ctx, args[0]); err != nil {
+					return err
+				}
+			}
+
+			// 导出到目录
+			if opts.ToDir != "" {
+				if fsutil.IsExists(opts.ToDir) && !fsutil.IsEmptyDir(opts.ToDir) {
+					return fmt.Errorf("path %q is not an empty dir", opts.ToDir)
+				}
+				if err := os.MkdirAll(opts.ToDir, 0755); err != nil {
+					return fmt.Errorf("make directory %q error: %w", opts.ToDir, err)
+				}
+				if err := changes.ExportToDir(view, opts.ToDir); err != nil {
+					return fmt.Errorf("export to %q error: %w", opts.ToDir, err)
+				}
+				return nil
+			}
+
+			// 导出为归档
+			w, err := createArchiveWriter(opts.Output)
+			if err != nil {
+				return err
+			}
+			if err := changes.WriteTar(view, w); err != nil {
+				_ = w.Close()
+				if opts.Output != "-" {
+					_ = os.Remove(opts.Output)
+				}
+				return fmt.Errorf("write archive error: %w", err)
+			}
+			if err := w.Close(); err != nil {
+				return fmt.Errorf("write archive error: %w", err)
+			}
+			return nil
+		},
+	}
+
+	// 绑定选项到命令行参数
+	opts.AddPFlags(cmd.Flags())
+
+	return cmd
+}
+
+// createArchiveWriter 根据文件后缀创建写入归档的 io.WriteCloser ， - 表示以 tar 格式写到标准输出
+func createArchiveWriter(path string) (io.WriteCloser, error) {
+	if path == "-" {
+		return compress.NewWriter(os.Stdout, compress.None)
+	}
+	format, err := compress.FormatFromTarName(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("create %q error: %w", path, err)
+	}
+	w, err := compress.NewWriter(f, format)
+	if err != nil {
+		_ = f.Close()
+		_ = os.Remove(path)
+		return nil, err
+	}
+	return &fileWriteCloser{WriteCloser: w, file: f}, nil
+}
+
+// fileWriteCloser 将内容压缩后写入文件的 io.WriteCloser
+type fileWriteCloser struct {
+	io.WriteCloser
+	file *os.File
+}
+
+// Close 完成压缩并关闭文件
+func (w *fileWriteCloser) Close() error {
+	if err := w.WriteCloser.Close(); err != nil {
+		_ = w.file.Close()
+		return err
+	}
+	return w.file.Close()
+}
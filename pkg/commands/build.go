@@ -0,0 +1,110 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/yhlooo/stackcrisp/pkg/builds"
+	"github.com/yhlooo/stackcrisp/pkg/commands/options"
+	"github.com/yhlooo/stackcrisp/pkg/manager"
+	cmdutil "github.com/yhlooo/stackcrisp/pkg/utils/cmd"
+	"github.com/yhlooo/stackcrisp/pkg/workspaces"
+)
+
+// NewBuildCommandWithOptions 创建一个基于选项的 build 命令
+func NewBuildCommandWithOptions(opts *options.BuildOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "build",
+		Short: "Build commits from the steps in a Crispfile, reusing cached steps",
+		Long: "Build commits from the steps in a Crispfile, reusing cached steps.\n\n" +
+			"Each non-empty line of the file is a step run by /bin/sh -c, lines starting with # are comments, " +
+			"and a line ending with \\ continues on the next line. Each step runs like the run command " +
+			"in a temporary mount of the commit of the previous step, not in the workspace mount, and its changes are committed with a cache key derived from " +
+			"the parent commit and the step. A step is skipped if the parent already has a commit with the same key.",
+		GroupID: groupWork,
+		Annotations: map[string]string{
+			cmdutil.AnnotationRunAsRoot:      cmdutil.AnnotationValueTrue,
+			cmdutil.AnnotationRequireManager: cmdutil.AnnotationValueTrue,
+		},
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			ctx := cmd.Context()
+
+			// 获取管理器
+			mgr := cmdutil.ManagerFromContext(ctx)
+
+			// 找到当前目录对应 workspace
+			var ws workspaces.Workspace
+			if opts.Space == "" {
+				var err error
+				ws, err = mgr.GetWorkspaceFromPath(ctx, ".")
+				if err != nil {
+					return fmt.Errorf("get workspace from path \".\" error: %w", err)
+				}
+			}
+
+			// 读取构建文件
+			steps, err := readBuildFile(opts.File)
+			if err != nil {
+				return err
+			}
+
+			// 构建
+			result, err := mgr.Build(ctx, ws, manager.BuildOptions{
+				Space:    opts.Space,
+				Revision: opts.Revision,
+				Steps:    steps,
+				Env:      opts.Env,
+				Dir:      opts.Workdir,
+				Chroot:   opts.Chroot,
+				AsRoot:   opts.AsRoot,
+				NoCache:  opts.NoCache,
+				Stdout:   os.Stdout,
+				Stderr:   os.Stderr,
+				Branch:   opts.Branch,
+			})
+			if err != nil {
+				return fmt.Errorf("build error: %w", err)
+			}
+			for i, step := range result.Steps {
+				cached := ""
+				if step.Cached {
+					cached = " (cached)"
+				}
+				fmt.Printf("Step %d/%d: %s%s\n", i+1, len(result.Steps), step.Commit, cached)
+			}
+			if result.Branch != "" {
+				fmt.Printf("Built %s (branch %s)\n", result.Commit, result.Branch)
+			} else {
+				fmt.Printf("Built %s\n", result.Commit)
+			}
+			return nil
+		},
+	}
+
+	// 绑定选项到命令行参数
+	opts.AddPFlags(cmd.Flags())
+
+	return cmd
+}
+
+// readBuildFile 读取并解析构建文件， - 表示从标准输入读取
+func readBuildFile(path string) ([]builds.Step, error) {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("open %q error: %w", path, err)
+		}
+		defer func() { _ = f.Close() }()
+		r = f
+	}
+	steps, err := builds.Parse(r)
+	if err != nil {
+		return nil, fmt.Errorf("parse %q error: %w", path, err)
+	}
+	return steps, nil
+}
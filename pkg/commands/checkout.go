@@ -7,55 +7,90 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/yhlooo/stackcrisp/pkg/commands/options"
+	"github.com/yhlooo/stackcrisp/pkg/manager"
 	cmdutil "github.com/yhlooo/stackcrisp/pkg/utils/cmd"
 )
 
 // NewCheckoutCommandWithOptions 创建一个基于选项的 checkout 命令
-func NewCheckoutCommandWithOptions(_ *options.CheckoutOptions) *cobra.Command {
+func NewCheckoutCommandWithOptions(opts *options.CheckoutOptions) *cobra.Command {
 	cmd := &cobra.Command{
-		Use:     "checkout <commit>",
+		Use:     "checkout [-b | -B <new-branch>] [<commit>]",
 		Short:   "Switch branches and restore working tree files",
 		GroupID: groupWork,
 		Annotations: map[string]string{
 			cmdutil.AnnotationRunAsRoot:      cmdutil.AnnotationValueTrue,
 			cmdutil.AnnotationRequireManager: cmdutil.AnnotationValueTrue,
 		},
-		Args: cobra.ExactArgs(1),
+		Args: func(cmd *cobra.Command, args []string) error {
+			if opts.NewBranch != "" || opts.ForceNewBranch != "" {
+				return cobra.MaximumNArgs(1)(cmd, args)
+			}
+			return cobra.ExactArgs(1)(cmd, args)
+		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			ctx := cmd.Context()
-			logger := logr.FromContextOrDiscard(ctx).WithName(loggerName)
+			targetCommit := "HEAD"
+			if len(args) > 0 {
+				targetCommit = args[0]
+			}
+			checkoutOpts := manager.CheckoutOptions{
+				NewBranch: opts.NewBranch,
+				Detach:    opts.Detach,
+			}
+			if opts.ForceNewBranch != "" {
+				checkoutOpts.NewBranch = opts.ForceNewBranch
+				checkoutOpts.ForceNewBranch = true
+			}
+			return runCheckout(cmd, targetCommit, checkoutOpts)
+		},
+	}
 
-			targetCommit := args[0]
-			logger.V(1).Info(fmt.Sprintf("target commit: %q", targetCommit))
+	// 绑定选项到命令行参数
+	opts.AddPFlags(cmd.Flags())
+	cmd.MarkFlagsMutuallyExclusive("new-branch", "force-new-branch", "detach")
 
-			// 获取管理器
-			mgr := cmdutil.ManagerFromContext(ctx)
+	return cmd
+}
 
-			// 找到当前目录对应 workspace
-			ws, err := mgr.GetWorkspaceFromPath(ctx, ".")
-			if err != nil {
-				return fmt.Errorf("get workspace from path \".\" error: %w", err)
-			}
+// runCheckout 切换当前目录对应工作空间到指定位置
+func runCheckout(cmd *cobra.Command, targetCommit string, opts manager.CheckoutOptions) error {
+	ctx := cmd.Context()
+	logger := logr.FromContextOrDiscard(ctx).WithName(loggerName)
+	logger.V(1).Info(fmt.Sprintf("target commit: %q, options: %#v", targetCommit, opts))
 
-			// checkout
-			newWS, err := mgr.Checkout(ctx, ws, targetCommit)
-			if err != nil {
-				return fmt.Errorf("commit error: %w", err)
-			}
+	// 获取管理器
+	mgr := cmdutil.ManagerFromContext(ctx)
 
-			// 展开 workspace
-			logger.Info("expanding workspace ...")
-			if err := newWS.Expand(ctx); err != nil {
-				return fmt.Errorf("expand workspace error: %w", err)
-			}
+	// 找到当前目录对应 workspace
+	ws, err := mgr.GetWorkspaceFromPath(ctx, ".")
+	if err != nil {
+		return fmt.Errorf("get workspace from path \".\" error: %w", err)
+	}
 
-			// 回收旧的 workspace
-			logger.Info("removing old workspace mount ...")
-			if err := mgr.RemoveWorkspaceMount(ctx, ws); err != nil {
-				return fmt.Errorf("remove old workspace mount error: %w", err)
-			}
-			return nil
-		},
+	// checkout
+	newWS, err := mgr.Checkout(ctx, ws, targetCommit, opts)
+	if err != nil {
+		return fmt.Errorf("checkout error: %w", err)
 	}
-	return cmd
+
+	// 展开新的 workspace 并回收旧的 workspace
+	if err := mgr.Apply(ctx, newWS, ws); err != nil {
+		return err
+	}
+
+	// 提示分离头指针状态
+	if branch := newWS.Branch(); branch.Name() == "" {
+		cmd.PrintErrf(
+			"Note: switching to %q.\n\n"+
+				"You are in 'detached HEAD' state. Changes committed in this state do not belong to any branch.\n"+
+				"If you want to keep them, create a branch with:\n\n"+
+				"  stackcrisp switch -c <new-branch-name>\n\n"+
+				"HEAD is now at %s\n",
+			targetCommit, newWS.Head().Parent().ID().Hex(),
+		)
+	} else if opts.NewBranch != "" {
+		cmd.PrintErrf("Switched to a new branch %q\n", branch.LocalName())
+	} else {
+		cmd.PrintErrf("Switched to branch %q\n", branch.LocalName())
+	}
+	return nil
 }
@@ -8,14 +8,20 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/yhlooo/stackcrisp/pkg/commands/options"
+	"github.com/yhlooo/stackcrisp/pkg/manager"
 	cmdutil "github.com/yhlooo/stackcrisp/pkg/utils/cmd"
+	fsutil "github.com/yhlooo/stackcrisp/pkg/utils/fs"
+	"github.com/yhlooo/stackcrisp/pkg/workspaces"
 )
 
 // NewCloneCommandWithOptions 创建一个基于选项的 clone 命令
-func NewCloneCommandWithOptions(_ *options.CloneOptions) *cobra.Command {
+func NewCloneCommandWithOptions(opts *options.CloneOptions) *cobra.Command {
 	cmd := &cobra.Command{
-		Use:     "clone <workspace> [<directory>]",
-		Short:   "Clone a workspace into a new directory",
+		Use:   "clone <workspace | space> [<directory>]",
+		Short: "Clone a workspace or a space into a new directory",
+		Long: "Clone a workspace or a space into a new directory.\n\n" +
+			"The source is a path in a mounted workspace, or the ID of a space, " +
+			"in which case no workspace of the space needs to be mounted.",
 		GroupID: groupStart,
 		Annotations: map[string]string{
 			cmdutil.AnnotationRunAsRoot:      cmdutil.AnnotationValueTrue,
@@ -40,29 +46,40 @@ func NewCloneCommandWithOptions(_ *options.CloneOptions) *cobra.Command {
 			// 获取管理器
 			mgr := cmdutil.ManagerFromContext(ctx)
 
-			// 获取源工作空间
-			// TODO: 应该还要支持从 space id / 名获取
-			sourceWS, err := mgr.GetWorkspaceFromPath(ctx, source)
-			if err != nil {
-				return fmt.Errorf("get workspace from path %q error: %w", source, err)
+			cloneOpts := manager.CloneOptions{
+				Branch:   opts.Branch,
+				Revision: opts.Revision,
 			}
-
-			// 克隆
-			logger.Info("cloning workspace ...")
-			targetWS, err := mgr.Clone(ctx, sourceWS, targetAbsPath)
-			if err != nil {
-				return fmt.Errorf("clone workspace error: %w", err)
+			var targetWS workspaces.Workspace
+			if fsutil.IsExists(source) {
+				// 从工作空间克隆
+				sourceWS, err := mgr.GetWorkspaceFromPath(ctx, source)
+				if err != nil {
+					return fmt.Errorf("get workspace from path %q error: %w", source, err)
+				}
+				logger.Info("cloning workspace ...")
+				if targetWS, err = mgr.Clone(ctx, sourceWS, targetAbsPath, cloneOpts); err != nil {
+					return fmt.Errorf("clone workspace error: %w", err)
+				}
+			} else {
+				// 不是路径则作为空间克隆
+				logger.Info("cloning space ...")
+				if targetWS, err = mgr.CloneSpace(ctx, source, targetAbsPath, cloneOpts); err != nil {
+					return fmt.Errorf("clone space error: %w", err)
+				}
 			}
 
 			// 展开 workspace
-			logger.Info("expanding workspace ...")
-			if err := targetWS.Expand(ctx); err != nil {
-				return fmt.Errorf("expand workspace error: %w", err)
+			if err := mgr.Apply(ctx, targetWS, nil); err != nil {
+				return err
 			}
 
 			return nil
 		},
 	}
 
+	// 绑定选项到命令行参数
+	opts.AddPFlags(cmd.Flags())
+
 	return cmd
 }
@@ -3,7 +3,6 @@ package commands
 import (
 	"fmt"
 
-	"github.com/go-logr/logr"
 	"github.com/spf13/cobra"
 
 	"github.com/yhlooo/stackcrisp/pkg/commands/options"
@@ -24,7 +23,6 @@ func NewCommitCommandWithOptions(opts *options.CommitOptions) *cobra.Command {
 		Args: cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			ctx := cmd.Context()
-			logger := logr.FromContextOrDiscard(ctx).WithName(loggerName)
 
 			// 获取管理器
 			mgr := cmdutil.ManagerFromContext(ctx)
@@ -41,16 +39,9 @@ func NewCommitCommandWithOptions(opts *options.CommitOptions) *cobra.Command {
 				return fmt.Errorf("commit error: %w", err)
 			}
 
-			// 展开 workspace
-			logger.Info("expanding workspace ...")
-			if err := newWS.Expand(ctx); err != nil {
-				return fmt.Errorf("expand workspace error: %w", err)
-			}
-
-			// 回收旧的 workspace
-			logger.Info("removing old workspace mount ...")
-			if err := mgr.RemoveWorkspaceMount(ctx, ws); err != nil {
-				return fmt.Errorf("remove old workspace mount error: %w", err)
+			// 展开新的 workspace 并回收旧的 workspace
+			if err := mgr.Apply(ctx, newWS, ws); err != nil {
+				return err
 			}
 
 			return nil
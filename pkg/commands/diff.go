@@ -0,0 +1,204 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"github.com/spf13/cobra"
+
+	"github.com/yhlooo/stackcrisp/pkg/changes"
+	"github.com/yhlooo/stackcrisp/pkg/commands/options"
+	cmdutil "github.com/yhlooo/stackcrisp/pkg/utils/cmd"
+	"github.com/yhlooo/stackcrisp/pkg/utils/diff"
+)
+
+// NewDiffCommandWithOptions 创建一个基于选项的 diff 命令
+func NewDiffCommandWithOptions(opts *options.DiffOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "diff [<revision>] [<revision>]",
+		Short: "Show changes between commits, commit and working tree, etc",
+		Long: "Show changes between commits, commit and working tree, etc.\n\n" +
+			"With no <revision>, show changes of the working tree relative to HEAD. " +
+			"With one <revision>, show changes of the working tree relative to the named revision. " +
+			"With two <revision>, show changes between the two revisions.",
+		GroupID: groupState,
+		Annotations: map[string]string{
+			cmdutil.AnnotationRunAsRoot:      cmdutil.AnnotationValueTrue,
+			cmdutil.AnnotationRequireManager: cmdutil.AnnotationValueTrue,
+		},
+		Args: cobra.MaximumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			logger := logr.FromContextOrDiscard(ctx).WithName(loggerName)
+
+			fromRef := "HEAD"
+			if len(args) > 0 {
+				fromRef = args[0]
+			}
+			toRef := ""
+			if len(args) > 1 {
+				toRef = args[1]
+			}
+			logger.V(1).Info(fmt.Sprintf("from: %q, to: %q", fromRef, toRef))
+
+			// 获取管理器
+			mgr := cmdutil.ManagerFromContext(ctx)
+
+			// 找到当前目录对应 workspace
+			ws, err := mgr.GetWorkspaceFromPath(ctx, ".")
+			if err != nil {
+				return fmt.Errorf("get workspace from path \".\" error: %w", err)
+			}
+
+			// 获取视图
+			from, err := ws.GetView(ctx, fromRef)
+			if err != nil {
+				return err
+			}
+			var to changes.View
+			if toRef == "" {
+				to, err = ws.GetWorkingView(ctx)
+			} else {
+				to, err = ws.GetView(ctx, toRef)
+			}
+			if err != nil {
+				return err
+			}
+
+			// 比较
+			changeList, err := changes.Compare(from, to)
+			if err != nil {
+				return fmt.Errorf("compare revisions error: %w", err)
+			}
+
+			// 打印
+			for _, c := range changeList {
+				switch {
+				case opts.NameOnly:
+					if !c.IsDir {
+						fmt.Println(c.Path)
+					}
+				case opts.NameStatus:
+					if !c.IsDir {
+						code, _ := statusCode(c.Kind)
+						fmt.Printf("%s\t%s\n", code, c.Path)
+					}
+				default:
+					if err := printFileDiff(from, to, c, opts.Unified); err != nil {
+						return err
+					}
+				}
+			}
+
+			return nil
+		},
+	}
+
+	// 绑定选项到命令行参数
+	opts.AddPFlags(cmd.Flags())
+
+	return cmd
+}
+
+// printFileDiff 打印一个文件的差异
+func printFileDiff(from, to changes.View, c changes.Change, contextLines int) error {
+	if c.IsDir {
+		return nil
+	}
+
+	var fromEntry, toEntry *changes.Entry
+	var err error
+	if c.Kind != changes.Added {
+		if fromEntry, err = from.Lstat(c.Path); err != nil {
+			return err
+		}
+	}
+	if c.Kind != changes.Deleted {
+		if toEntry, err = to.Lstat(c.Path); err != nil {
+			return err
+		}
+	}
+
+	fromName, toName := "a/"+c.Path, "b/"+c.Path
+	fmt.Printf("\033[1mdiff --stackcrisp %s %s\033[0m\n", fromName, toName)
+	switch {
+	case fromEntry == nil:
+		fmt.Printf("\033[1mnew file mode %s\033[0m\n", fileModeString(toEntry.Info))
+		fromName = "/dev/null"
+	case toEntry == nil:
+		fmt.Printf("\033[1mdeleted file mode %s\033[0m\n", fileModeString(fromEntry.Info))
+		toName = "/dev/null"
+	case fromEntry.Info.Mode() != toEntry.Info.Mode():
+		fmt.Printf("\033[1mold mode %s\033[0m\n", fileModeString(fromEntry.Info))
+		fmt.Printf("\033[1mnew mode %s\033[0m\n", fileModeString(toEntry.Info))
+	}
+
+	fromContent, err := readEntryContent(fromEntry)
+	if err != nil {
+		return err
+	}
+	toContent, err := readEntryContent(toEntry)
+	if err != nil {
+		return err
+	}
+	if diff.IsBinary(fromContent) || diff.IsBinary(toContent) {
+		fmt.Printf("Binary files %s and %s differ\n", fromName, toName)
+		return nil
+	}
+
+	for _, line := range strings.SplitAfter(diff.Unified(fromName, toName, fromContent, toContent, contextLines), "\n") {
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "---"), strings.HasPrefix(line, "+++"):
+			fmt.Printf("\033[1m%s\033[0m\n", strings.TrimSuffix(line, "\n"))
+		case strings.HasPrefix(line, "@@"):
+			fmt.Printf("\033[36m%s\033[0m\n", strings.TrimSuffix(line, "\n"))
+		case strings.HasPrefix(line, "-"):
+			fmt.Printf("\033[31m%s\033[0m\n", strings.TrimSuffix(line, "\n"))
+		case strings.HasPrefix(line, "+"):
+			fmt.Printf("\033[32m%s\033[0m\n", strings.TrimSuffix(line, "\n"))
+		default:
+			fmt.Print(line)
+		}
+	}
+	return nil
+}
+
+// readEntryContent 读取用于比较的文件内容
+//
+// 软链返回其指向的路径，其它非普通文件返回空内容
+func readEntryContent(entry *changes.Entry) ([]byte, error) {
+	switch {
+	case entry == nil:
+		return nil, nil
+	case entry.Info.Mode()&os.ModeSymlink != 0:
+		target, err := os.Readlink(entry.RealPath)
+		if err != nil {
+			return nil, fmt.Errorf("read link %q error: %w", entry.RealPath, err)
+		}
+		return []byte(target), nil
+	case entry.Info.Mode().IsRegular():
+		content, err := os.ReadFile(entry.RealPath)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("read file %q error: %w", entry.RealPath, err)
+		}
+		return content, nil
+	default:
+		return nil, nil
+	}
+}
+
+// fileModeString 返回 git 风格的文件模式
+func fileModeString(info os.FileInfo) string {
+	switch {
+	case info.Mode()&os.ModeSymlink != 0:
+		return "120000"
+	case info.Mode().IsRegular():
+		return fmt.Sprintf("100%03o", info.Mode().Perm())
+	default:
+		return fmt.Sprintf("%06o", uint32(info.Mode()))
+	}
+}
@@ -0,0 +1,116 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/yhlooo/stackcrisp/pkg/commands/options"
+	"github.com/yhlooo/stackcrisp/pkg/manager"
+	cmdutil "github.com/yhlooo/stackcrisp/pkg/utils/cmd"
+)
+
+// NewDUCommandWithOptions 创建一个基于选项的 du 命令
+func NewDUCommandWithOptions(opts *options.DUOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "du [<space>]",
+		Short: "Show disk usage of spaces, branches and commits",
+		Long: "Show disk usage of spaces, branches and commits.\n\n" +
+			"For each branch, EXCLUSIVE is the size of commits only reachable from that branch, " +
+			"which can be reclaimed by deleting the branch and pruning, " +
+			"and SHARED is the size of commits also reachable from other branches, tags or detached workspaces.",
+		GroupID: groupMaintain,
+		Annotations: map[string]string{
+			cmdutil.AnnotationRunAsRoot:      cmdutil.AnnotationValueTrue,
+			cmdutil.AnnotationRequireManager: cmdutil.AnnotationValueTrue,
+		},
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			spaceRef := ""
+			if len(args) > 0 {
+				spaceRef = args[0]
+			}
+
+			// 获取管理器
+			mgr := cmdutil.ManagerFromContext(ctx)
+
+			// 统计
+			list, err := mgr.DiskUsage(ctx, spaceRef)
+			if err != nil {
+				return fmt.Errorf("get disk usage error: %w", err)
+			}
+
+			if opts.JSON {
+				if !opts.Commits {
+					for i := range list {
+						list[i].Commits = nil
+					}
+				}
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(list)
+			}
+			for i, usage := range list {
+				if i > 0 {
+					fmt.Println()
+				}
+				printSpaceUsage(&usage, opts.Commits)
+			}
+			return nil
+		},
+	}
+
+	// 绑定选项到命令行参数
+	opts.AddPFlags(cmd.Flags())
+
+	return cmd
+}
+
+// printSpaceUsage 打印空间占用的空间
+func printSpaceUsage(usage *manager.SpaceUsage, commits bool) {
+	if usage.Name != "" {
+		fmt.Printf("Space %s (%s)\n", usage.ID, usage.Name)
+	} else {
+		fmt.Printf("Space %s\n", usage.ID)
+	}
+	fmt.Printf(
+		"Total: %s (uncommitted %s, flattened %s)\n",
+		formatBytes(usage.TotalBytes), formatBytes(usage.UncommittedBytes), formatBytes(usage.FlattenedBytes),
+	)
+
+	if len(usage.Branches) > 0 {
+		fmt.Println()
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "BRANCH\tWORKSPACE\tHEAD\tTOTAL\tEXCLUSIVE\tSHARED")
+		for _, b := range usage.Branches {
+			ws := b.Workspace
+			if ws == "" {
+				ws = "-"
+			}
+			_, _ = fmt.Fprintf(
+				w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+				b.Name, ws, b.Commit,
+				formatBytes(b.TotalBytes), formatBytes(b.ExclusiveBytes), formatBytes(b.SharedBytes),
+			)
+		}
+		_ = w.Flush()
+	}
+
+	if commits && len(usage.Commits) > 0 {
+		fmt.Println()
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "COMMIT\tSIZE\tFILES\tINODES\tSUBJECT")
+		for _, c := range usage.Commits {
+			_, _ = fmt.Fprintf(
+				w, "%s\t%s\t%d\t%d\t%s\n",
+				c.ID, formatBytes(c.Usage.Bytes), c.Usage.Files, c.Usage.Inodes, c.Subject,
+			)
+		}
+		_ = w.Flush()
+	}
+}
@@ -0,0 +1,55 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	cmdutil "github.com/yhlooo/stackcrisp/pkg/utils/cmd"
+)
+
+// NewFlattenCommand 创建一个 flatten 命令
+func NewFlattenCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "flatten [<commit>]",
+		Short: "Flatten layers up to a commit into a cached base layer",
+		Long: "Merge the layers from the root up to <commit> (HEAD by default) into a cached base layer. " +
+			"Mounts of the commit or its descendants stack the base layer instead of these layers " +
+			"the next time they are mounted (e.g. after commit or checkout). " +
+			"The original layers are kept for history and diff. " +
+			"gc reclaims base layers that are no longer used by any workspace.",
+		GroupID: groupMaintain,
+		Annotations: map[string]string{
+			cmdutil.AnnotationRunAsRoot:      cmdutil.AnnotationValueTrue,
+			cmdutil.AnnotationRequireManager: cmdutil.AnnotationValueTrue,
+		},
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			revision := "HEAD"
+			if len(args) > 0 {
+				revision = args[0]
+			}
+
+			// 获取管理器
+			mgr := cmdutil.ManagerFromContext(ctx)
+
+			// 找到当前目录对应 workspace
+			ws, err := mgr.GetWorkspaceFromPath(ctx, ".")
+			if err != nil {
+				return fmt.Errorf("get workspace from path \".\" error: %w", err)
+			}
+
+			// 合并
+			result, err := mgr.Flatten(ctx, ws, revision)
+			if err != nil {
+				return fmt.Errorf("flatten error: %w", err)
+			}
+			fmt.Printf("Flattened %d layers up to %s\n", result.Layers, result.Commit)
+			return nil
+		},
+	}
+
+	return cmd
+}
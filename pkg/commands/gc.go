@@ -0,0 +1,87 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/go-logr/logr"
+	"github.com/spf13/cobra"
+
+	"github.com/yhlooo/stackcrisp/pkg/commands/options"
+	"github.com/yhlooo/stackcrisp/pkg/manager"
+	cmdutil "github.com/yhlooo/stackcrisp/pkg/utils/cmd"
+)
+
+// NewGCCommandWithOptions 创建一个基于选项的 gc 命令
+func NewGCCommandWithOptions(opts *options.GCOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "gc [--dry-run]",
+		Short:   "Remove orphaned layers and stale mounts",
+		GroupID: groupMaintain,
+		Annotations: map[string]string{
+			cmdutil.AnnotationRunAsRoot:      cmdutil.AnnotationValueTrue,
+			cmdutil.AnnotationRequireManager: cmdutil.AnnotationValueTrue,
+		},
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			logger := logr.FromContextOrDiscard(ctx).WithName(loggerName)
+			logger.V(1).Info(fmt.Sprintf("dry run: %t", opts.DryRun))
+
+			// 获取管理器
+			mgr := cmdutil.ManagerFromContext(ctx)
+
+			// 回收
+			result, err := mgr.GC(ctx, manager.GCOptions{DryRun: opts.DryRun, Force: opts.Force})
+			if err != nil {
+				return fmt.Errorf("gc error: %w", err)
+			}
+
+			// 输出结果
+			action := "Removed"
+			if opts.DryRun {
+				action = "Would remove"
+			}
+			for _, id := range result.Mounts {
+				fmt.Printf("%s mount %s\n", action, id)
+			}
+			for _, id := range result.Layers {
+				fmt.Printf("%s layer %s\n", action, id)
+			}
+			for _, id := range result.FlattenedLayers {
+				fmt.Printf("%s flattened layer %s\n", action, id)
+			}
+			for _, id := range result.Flattened {
+				if opts.DryRun {
+					fmt.Printf("Would flatten layers up to %s\n", id)
+				} else {
+					fmt.Printf("Flattened layers up to %s\n", id)
+				}
+			}
+			if opts.DryRun {
+				fmt.Printf("Would reclaim %s\n", formatBytes(result.ReclaimedBytes))
+			} else {
+				fmt.Printf("Reclaimed %s\n", formatBytes(result.ReclaimedBytes))
+			}
+			return nil
+		},
+	}
+
+	// 绑定选项到命令行参数
+	opts.AddPFlags(cmd.Flags())
+
+	return cmd
+}
+
+// formatBytes 将字节数格式化为便于阅读的形式
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
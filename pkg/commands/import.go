@@ -0,0 +1,117 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/yhlooo/stackcrisp/pkg/commands/options"
+	"github.com/yhlooo/stackcrisp/pkg/manager"
+	cmdutil "github.com/yhlooo/stackcrisp/pkg/utils/cmd"
+	"github.com/yhlooo/stackcrisp/pkg/utils/compress"
+	fsutil "github.com/yhlooo/stackcrisp/pkg/utils/fs"
+	"github.com/yhlooo/stackcrisp/pkg/workspaces"
+)
+
+// NewImportCommandWithOptions 创建一个基于选项的 import 命令
+func NewImportCommandWithOptions(opts *options.ImportOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "import <tar|dir>",
+		Short: "Record the content of a tarball or directory as a new commit",
+		Long: "Record the content of a tarball or directory as a new commit, without copying through a mount.\n\n" +
+			"The content is the full state after the commit. Files missing from it are deleted from the base. " +
+			"Tarballs may be compressed with gzip or zstd (requires the zstd command). Use - to read a tar from stdin.",
+		GroupID: groupWork,
+		Annotations: map[string]string{
+			cmdutil.AnnotationRunAsRoot:      cmdutil.AnnotationValueTrue,
+			cmdutil.AnnotationRequireManager: cmdutil.AnnotationValueTrue,
+		},
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			// 获取管理器
+			mgr := cmdutil.ManagerFromContext(ctx)
+
+			// 找到当前目录对应 workspace
+			var ws workspaces.Workspace
+			if opts.Space == "" {
+				var err error
+				ws, err = mgr.GetWorkspaceFromPath(ctx, ".")
+				if err != nil {
+					return fmt.Errorf("get workspace from path \".\" error: %w", err)
+				}
+			}
+
+			// 打开导入的内容
+			src := manager.ImportSource{Name: args[0]}
+			if args[0] != "-" && fsutil.IsDir(args[0]) {
+				src.Dir = args[0]
+			} else {
+				r, err := openArchiveReader(args[0])
+				if err != nil {
+					return err
+				}
+				defer func() { _ = r.Close() }()
+				src.Tar = r
+			}
+
+			// 导入
+			result, err := mgr.Import(ctx, ws, src, manager.ImportOptions{
+				Space:   opts.Space,
+				Base:    opts.Base,
+				Branch:  opts.Branch,
+				Message: opts.Message,
+			})
+			if err != nil {
+				return fmt.Errorf("import error: %w", err)
+			}
+			base := result.Base
+			if base == "" {
+				base = "root"
+			}
+			if result.Branch != "" {
+				fmt.Printf("Imported %s as %s on %s (branch %s)\n", args[0], result.Commit, base, result.Branch)
+			} else {
+				fmt.Printf("Imported %s as %s on %s\n", args[0], result.Commit, base)
+			}
+			return nil
+		},
+	}
+
+	// 绑定选项到命令行参数
+	opts.AddPFlags(cmd.Flags())
+
+	return cmd
+}
+
+// openArchiveReader 打开 tar 归档，根据文件头识别并解压 gzip 或 zstd 压缩， - 表示从标准输入读取
+func openArchiveReader(path string) (io.ReadCloser, error) {
+	f := os.Stdin
+	if path != "-" {
+		var err error
+		if f, err = os.Open(path); err != nil {
+			return nil, fmt.Errorf("open %q error: %w", path, err)
+		}
+	}
+	r, err := compress.NewReader(f)
+	if err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("read %q error: %w", path, err)
+	}
+	return &fileReadCloser{ReadCloser: r, file: f}, nil
+}
+
+// fileReadCloser 读取并解压文件的 io.ReadCloser
+type fileReadCloser struct {
+	io.ReadCloser
+	file *os.File
+}
+
+// Close 关闭解压器和文件
+func (r *fileReadCloser) Close() error {
+	_ = r.ReadCloser.Close()
+	return r.file.Close()
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/yhlooo/stackcrisp/pkg/commands/options"
+	"github.com/yhlooo/stackcrisp/pkg/manager"
 	cmdutil "github.com/yhlooo/stackcrisp/pkg/utils/cmd"
 	fsutil "github.com/yhlooo/stackcrisp/pkg/utils/fs"
 )
@@ -46,15 +47,17 @@ func NewInitCommandWithOptions(opts *options.InitOptions) *cobra.Command {
 			mgr := cmdutil.ManagerFromContext(ctx)
 			// 创建 workspace
 			logger.Info("creating workspace ...")
-			ws, err := mgr.CreateWorkspace(ctx, targetAbsPath, opts.InitialBranch)
+			ws, err := mgr.CreateWorkspace(ctx, targetAbsPath, manager.CreateWorkspaceOptions{
+				Branch:    opts.InitialBranch,
+				SpaceName: opts.Name,
+			})
 			if err != nil {
 				return fmt.Errorf("create workspace error: %w", err)
 			}
 
 			// 展开 workspace
-			logger.Info("expanding workspace ...")
-			if err := ws.Expand(ctx); err != nil {
-				return fmt.Errorf("expand workspace error: %w", err)
+			if err := mgr.Apply(ctx, ws, nil); err != nil {
+				return err
 			}
 
 			return nil
@@ -75,7 +75,7 @@ func NewLogCommandWithOptions(_ *options.LogOptions) *cobra.Command {
 				} else {
 					fmt.Printf("\033[33mcommit %s\033[0m\n", c.ID().Hex())
 				}
-				if c.Date != nil {
+				if c.Date() != nil {
 					fmt.Printf("Date:  %s\n", c.Date().Format(time.ANSIC+" -0700"))
 				}
 				if c.Message() != "" {
@@ -0,0 +1,123 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/go-logr/logr"
+	"github.com/spf13/cobra"
+
+	"github.com/yhlooo/stackcrisp/pkg/commands/options"
+	"github.com/yhlooo/stackcrisp/pkg/manager"
+	cmdutil "github.com/yhlooo/stackcrisp/pkg/utils/cmd"
+	"github.com/yhlooo/stackcrisp/pkg/workspaces"
+)
+
+// NewExportOCICommandWithOptions 创建一个基于选项的 export-oci 命令
+func NewExportOCICommandWithOptions(opts *options.ExportOCIOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export-oci <revision> <dir>",
+		Short: "Export the history of a revision as an OCI image layout",
+		Long: "Export the history of a revision as an OCI image layout, with one image layer per commit " +
+			"from the root to the revision. The directory must be empty or not exist.\n\n" +
+			"Without root privileges, opaque directories and trusted xattrs can not be read.",
+		GroupID: groupState,
+		Annotations: map[string]string{
+			cmdutil.AnnotationRequireManager: cmdutil.AnnotationValueTrue,
+		},
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			logger := logr.FromContextOrDiscard(ctx).WithName(loggerName)
+
+			if os.Geteuid() != 0 {
+				logger.Info("WARN not running as root, opaque directories and trusted xattrs can not be read")
+			}
+
+			// 获取管理器
+			mgr := cmdutil.ManagerFromContext(ctx)
+
+			// 找到当前目录对应 workspace
+			var ws workspaces.Workspace
+			if opts.Space == "" {
+				var err error
+				ws, err = mgr.GetWorkspaceFromPath(ctx, ".")
+				if err != nil {
+					return fmt.Errorf("get workspace from path \".\" error: %w", err)
+				}
+			}
+
+			// 导出
+			result, err := mgr.ExportOCI(ctx, ws, args[0], args[1], manager.ExportOCIOptions{
+				Space: opts.Space,
+				Tag:   opts.Tag,
+			})
+			if err != nil {
+				return fmt.Errorf("export error: %w", err)
+			}
+			fmt.Printf("Exported %s as %s with %d layers\n", result.Commit, result.Digest, result.Layers)
+			return nil
+		},
+	}
+
+	// 绑定选项到命令行参数
+	opts.AddPFlags(cmd.Flags())
+
+	return cmd
+}
+
+// NewImportOCICommandWithOptions 创建一个基于选项的 import-oci 命令
+func NewImportOCICommandWithOptions(opts *options.ImportOCIOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "import-oci <dir>",
+		Short: "Record the layers of an image in an OCI image layout as new commits",
+		Long: "Record the layers of an image in a local OCI image layout as new commits, one commit per layer. " +
+			"Commit messages and dates are taken from the image history.",
+		GroupID: groupWork,
+		Annotations: map[string]string{
+			cmdutil.AnnotationRunAsRoot:      cmdutil.AnnotationValueTrue,
+			cmdutil.AnnotationRequireManager: cmdutil.AnnotationValueTrue,
+		},
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			// 获取管理器
+			mgr := cmdutil.ManagerFromContext(ctx)
+
+			// 找到当前目录对应 workspace
+			var ws workspaces.Workspace
+			if opts.Space == "" {
+				var err error
+				ws, err = mgr.GetWorkspaceFromPath(ctx, ".")
+				if err != nil {
+					return fmt.Errorf("get workspace from path \".\" error: %w", err)
+				}
+			}
+
+			// 导入
+			result, err := mgr.ImportOCI(ctx, ws, args[0], opts.Ref, manager.ImportOptions{
+				Space:  opts.Space,
+				Base:   opts.Base,
+				Branch: opts.Branch,
+			})
+			if err != nil {
+				return fmt.Errorf("import error: %w", err)
+			}
+			for _, commit := range result.Commits {
+				fmt.Println(commit)
+			}
+			if result.Branch != "" {
+				fmt.Printf("Imported %d layers as %s (branch %s)\n", len(result.Commits), result.Commit, result.Branch)
+			} else {
+				fmt.Printf("Imported %d layers as %s\n", len(result.Commits), result.Commit)
+			}
+			return nil
+		},
+	}
+
+	// 绑定选项到命令行参数
+	opts.AddPFlags(cmd.Flags())
+
+	return cmd
+}
@@ -0,0 +1,39 @@
+package options
+
+import "github.com/spf13/pflag"
+
+// NewDefaultArchiveOptions 创建一个默认 archive 命令选项
+func NewDefaultArchiveOptions() ArchiveOptions {
+	return ArchiveOptions{
+		Output: "",
+		ToDir:  "",
+		Space:  "",
+	}
+}
+
+// ArchiveOptions archive 命令选项
+type ArchiveOptions struct {
+	// 输出的归档文件路径
+	Output string `json:"output,omitempty" yaml:"output,omitempty"`
+	// 输出的目录路径
+	ToDir string `json:"toDir,omitempty" yaml:"toDir,omitempty"`
+	// 从指定空间而不是当前工作空间中获取 revision
+	Space string `json:"space,omitempty" yaml:"space,omitempty"`
+}
+
+// AddPFlags 将选项绑定到命令行参数
+func (o *ArchiveOptions) AddPFlags(flags *pflag.FlagSet) {
+	flags.StringVarP(
+		&o.Output, "output", "o", o.Output,
+		"Write the archive to <file>. The format is inferred from the suffix: .tar, .tar.gz (.tgz) or "+
+			".tar.zst (.tzst, requires the zstd command). Use - to write a tar to stdout.",
+	)
+	flags.StringVar(
+		&o.ToDir, "to-dir", o.ToDir,
+		"Write the files into <dir> instead of an archive. The directory must be empty or not exist.",
+	)
+	flags.StringVar(
+		&o.Space, "space", o.Space,
+		"Resolve <revision> in the space with the given ID or name instead of the current workspace.",
+	)
+}
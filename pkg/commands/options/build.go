@@ -0,0 +1,77 @@
+package options
+
+import (
+	"github.com/spf13/pflag"
+
+	"github.com/yhlooo/stackcrisp/pkg/builds"
+)
+
+// NewDefaultBuildOptions 创建一个默认 build 命令选项
+func NewDefaultBuildOptions() BuildOptions {
+	return BuildOptions{
+		File:     builds.DefaultFileName,
+		Revision: "",
+		Branch:   "",
+		NoCache:  false,
+		Chroot:   false,
+		AsRoot:   false,
+		Workdir:  "",
+		Env:      nil,
+		Space:    "",
+	}
+}
+
+// BuildOptions build 命令选项
+type BuildOptions struct {
+	// 构建文件路径
+	File string `json:"file,omitempty" yaml:"file,omitempty"`
+	// 构建的基础提交
+	Revision string `json:"revision,omitempty" yaml:"revision,omitempty"`
+	// 构建完成后指向最终提交的分支
+	Branch string `json:"branch,omitempty" yaml:"branch,omitempty"`
+	// 不复用缓存
+	NoCache bool `json:"noCache,omitempty" yaml:"noCache,omitempty"`
+	// chroot 到挂载中运行步骤
+	Chroot bool `json:"chroot,omitempty" yaml:"chroot,omitempty"`
+	// 以 root 运行步骤
+	AsRoot bool `json:"asRoot,omitempty" yaml:"asRoot,omitempty"`
+	// 步骤的工作目录
+	Workdir string `json:"workdir,omitempty" yaml:"workdir,omitempty"`
+	// 额外的环境变量
+	Env []string `json:"env,omitempty" yaml:"env,omitempty"`
+	// 在指定空间中构建而不是当前工作空间所属空间
+	Space string `json:"space,omitempty" yaml:"space,omitempty"`
+}
+
+// AddPFlags 将选项绑定到命令行参数
+func (o *BuildOptions) AddPFlags(flags *pflag.FlagSet) {
+	flags.StringVarP(&o.File, "file", "f", o.File, "Read build steps from the given file. Use - to read from stdin.")
+	flags.StringVar(
+		&o.Revision, "rev", o.Revision,
+		"Build on <revision>. Defaults to the HEAD of --branch if it exists, "+
+			"otherwise the HEAD of the current workspace, or the root of the space given by --space.",
+	)
+	flags.StringVarP(
+		&o.Branch, "branch", "b", o.Branch,
+		"Point <branch> to the final commit. If it exists, the build is based on its HEAD, "+
+			"otherwise it is created. Branches in a space given by --space are global branches.",
+	)
+	flags.BoolVar(&o.NoCache, "no-cache", o.NoCache, "Run all steps instead of reusing cached commits.")
+	flags.BoolVar(&o.Chroot, "chroot", o.Chroot, "Change the root directory to the mount before running each step.")
+	flags.BoolVar(
+		&o.AsRoot, "as-root", o.AsRoot,
+		"Run steps as root. By default they run as the user who executed stackcrisp. It is part of the cache key.",
+	)
+	flags.StringVarP(
+		&o.Workdir, "workdir", "w", o.Workdir,
+		"Run steps in the given directory inside the mount. Defaults to the root of the mount.",
+	)
+	flags.StringArrayVarP(
+		&o.Env, "env", "e", o.Env,
+		"Set an environment variable <key>=<value> for steps. It is part of the cache key.",
+	)
+	flags.StringVar(
+		&o.Space, "space", o.Space,
+		"Build in the space with the given ID or name instead of the space of the current workspace.",
+	)
+}
@@ -1,9 +1,38 @@
 package options
 
+import "github.com/spf13/pflag"
+
 // NewDefaultCheckoutOptions 创建一个默认 checkout 命令选项
 func NewDefaultCheckoutOptions() CheckoutOptions {
-	return CheckoutOptions{}
+	return CheckoutOptions{
+		NewBranch:      "",
+		ForceNewBranch: "",
+		Detach:         false,
+	}
 }
 
 // CheckoutOptions checkout 命令选项
-type CheckoutOptions struct{}
+type CheckoutOptions struct {
+	// 创建并切换到新分支
+	NewBranch string `json:"newBranch,omitempty" yaml:"newBranch,omitempty"`
+	// 创建或重置并切换到新分支
+	ForceNewBranch string `json:"forceNewBranch,omitempty" yaml:"forceNewBranch,omitempty"`
+	// 以分离头指针状态切换
+	Detach bool `json:"detach,omitempty" yaml:"detach,omitempty"`
+}
+
+// AddPFlags 将选项绑定到命令行参数
+func (o *CheckoutOptions) AddPFlags(flags *pflag.FlagSet) {
+	flags.StringVarP(
+		&o.NewBranch, "new-branch", "b", o.NewBranch,
+		"Create a new branch named <new-branch> and start it at <start-point>.",
+	)
+	flags.StringVarP(
+		&o.ForceNewBranch, "force-new-branch", "B", o.ForceNewBranch,
+		"Creates the branch <new-branch>, start it at <start-point>; if it already exists, then reset it to <start-point>.",
+	)
+	flags.BoolVarP(
+		&o.Detach, "detach", "d", o.Detach,
+		"Rather than checking out a branch to work on it, check out <commit> for inspection and discardable experiments.",
+	)
+}
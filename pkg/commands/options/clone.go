@@ -1,9 +1,33 @@
 package options
 
+import "github.com/spf13/pflag"
+
 // NewDefaultCloneOptions 创建一个默认 clone 命令选项
 func NewDefaultCloneOptions() CloneOptions {
-	return CloneOptions{}
+	return CloneOptions{
+		Branch:   "",
+		Revision: "",
+	}
 }
 
 // CloneOptions clone 命令选项
-type CloneOptions struct{}
+type CloneOptions struct {
+	// 新工作空间所处的分支
+	Branch string `json:"branch,omitempty" yaml:"branch,omitempty"`
+	// 克隆的起始位置
+	Revision string `json:"revision,omitempty" yaml:"revision,omitempty"`
+}
+
+// AddPFlags 将选项绑定到命令行参数
+func (o *CloneOptions) AddPFlags(flags *pflag.FlagSet) {
+	flags.StringVarP(
+		&o.Branch, "branch", "b", o.Branch,
+		"Point the new workspace to <branch> instead of the current branch of the source workspace "+
+			"(or \"main\" when cloning from a space). A global branch is tracked, other branches are copied "+
+			"as a local branch of the new workspace. With --revision, create <branch> at <revision>.",
+	)
+	flags.StringVar(
+		&o.Revision, "revision", o.Revision,
+		"Start the new workspace at <revision>, detached unless --branch is also given.",
+	)
+}
@@ -0,0 +1,32 @@
+package options
+
+import "github.com/spf13/pflag"
+
+// NewDefaultDiffOptions 创建一个默认 diff 命令选项
+func NewDefaultDiffOptions() DiffOptions {
+	return DiffOptions{
+		Unified:    3,
+		NameOnly:   false,
+		NameStatus: false,
+	}
+}
+
+// DiffOptions diff 命令选项
+type DiffOptions struct {
+	// 差异上下文行数
+	Unified int `json:"unified,omitempty" yaml:"unified,omitempty"`
+	// 仅显示变更的文件名
+	NameOnly bool `json:"nameOnly,omitempty" yaml:"nameOnly,omitempty"`
+	// 仅显示变更的文件名和变更类型
+	NameStatus bool `json:"nameStatus,omitempty" yaml:"nameStatus,omitempty"`
+}
+
+// AddPFlags 将选项绑定到命令行参数
+func (o *DiffOptions) AddPFlags(flags *pflag.FlagSet) {
+	flags.IntVarP(&o.Unified, "unified", "U", o.Unified, "Generate diffs with <n> lines of context.")
+	flags.BoolVar(&o.NameOnly, "name-only", o.NameOnly, "Show only names of changed files.")
+	flags.BoolVar(
+		&o.NameStatus, "name-status", o.NameStatus,
+		"Show only names and status of changed files.",
+	)
+}
@@ -0,0 +1,25 @@
+package options
+
+import "github.com/spf13/pflag"
+
+// NewDefaultDUOptions 创建一个默认 du 命令选项
+func NewDefaultDUOptions() DUOptions {
+	return DUOptions{
+		Commits: false,
+		JSON:    false,
+	}
+}
+
+// DUOptions du 命令选项
+type DUOptions struct {
+	// 列出每个提交占用的空间
+	Commits bool `json:"commits,omitempty" yaml:"commits,omitempty"`
+	// 以 JSON 格式输出
+	JSON bool `json:"json,omitempty" yaml:"json,omitempty"`
+}
+
+// AddPFlags 将选项绑定到命令行参数
+func (o *DUOptions) AddPFlags(flags *pflag.FlagSet) {
+	flags.BoolVar(&o.Commits, "commits", o.Commits, "Also show the disk usage of each commit.")
+	flags.BoolVar(&o.JSON, "json", o.JSON, "Give the output in JSON format.")
+}
@@ -0,0 +1,31 @@
+package options
+
+import "github.com/spf13/pflag"
+
+// NewDefaultGCOptions 创建一个默认 gc 命令选项
+func NewDefaultGCOptions() GCOptions {
+	return GCOptions{
+		DryRun: false,
+		Force:  false,
+	}
+}
+
+// GCOptions gc 命令选项
+type GCOptions struct {
+	// 仅列出可以回收的内容，不实际删除
+	DryRun bool `json:"dryRun,omitempty" yaml:"dryRun,omitempty"`
+	// 失效的挂载仍被进程占用时延迟卸载，而不是跳过
+	Force bool `json:"force,omitempty" yaml:"force,omitempty"`
+}
+
+// AddPFlags 将选项绑定到命令行参数
+func (o *GCOptions) AddPFlags(flags *pflag.FlagSet) {
+	flags.BoolVarP(
+		&o.DryRun, "dry-run", "n", o.DryRun,
+		"Do not remove anything; just report what would be removed and how much space would be reclaimed.",
+	)
+	flags.BoolVarP(
+		&o.Force, "force", "f", o.Force,
+		"Lazily unmount stale mounts even if processes are still using them, instead of skipping them.",
+	)
+}
@@ -2,6 +2,7 @@ package options
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/spf13/pflag"
 )
@@ -9,11 +10,13 @@ import (
 // NewDefaultGlobalOptions 返回默认全局选项
 func NewDefaultGlobalOptions() GlobalOptions {
 	return GlobalOptions{
-		Verbosity: 0,
-		Chdir:     "",
-		DataRoot:  "/var/lib/stackcrisp",
-		UID:       -1,
-		GID:       -1,
+		Verbosity:        0,
+		Chdir:            "",
+		DataRoot:         "/var/lib/stackcrisp",
+		LockTimeout:      30 * time.Second,
+		FlattenThreshold: 64,
+		UID:              -1,
+		GID:              -1,
 	}
 }
 
@@ -25,6 +28,10 @@ type GlobalOptions struct {
 	Chdir string `json:"chdir,omitempty" yaml:"chdir,omitempty"`
 	// 数据存储根目录
 	DataRoot string `json:"dataRoot" yaml:"dataRoot"`
+	// 等待其它进程释放空间和工作空间锁的最长时间
+	LockTimeout time.Duration `json:"lockTimeout" yaml:"lockTimeout"`
+	// 挂载时最多叠加的已提交层数，超过时将深处的祖先层合并为一个扁平化的基础层， 0 表示不合并
+	FlattenThreshold int `json:"flattenThreshold" yaml:"flattenThreshold"`
 	// 执行命令的原始用户 ID
 	UID int `json:"uid" yaml:"uid"`
 	// 执行命令的原始用户组 ID
@@ -36,6 +43,12 @@ func (o *GlobalOptions) Validate() error {
 	if o.Verbosity > 2 {
 		return fmt.Errorf("invalid log verbosity: %d (expected: 0, 1 or 2)", o.Verbosity)
 	}
+	if o.LockTimeout < 0 {
+		return fmt.Errorf("invalid lock timeout: %s (expected: not negative)", o.LockTimeout)
+	}
+	if o.FlattenThreshold < 0 {
+		return fmt.Errorf("invalid flatten threshold: %d (expected: not negative)", o.FlattenThreshold)
+	}
 	return nil
 }
 
@@ -45,6 +58,15 @@ func (o *GlobalOptions) AddPFlags(flags *pflag.FlagSet) {
 	flags.StringVarP(&o.Chdir, "chdir", "C", o.Chdir, "Change to directory before doing anything")
 
 	flags.StringVar(&o.DataRoot, "data-root", o.DataRoot, "Root directory of persistent data")
+	flags.DurationVar(
+		&o.LockTimeout, "lock-timeout", o.LockTimeout,
+		"Maximum time to wait for a space or workspace locked by another process",
+	)
+	flags.IntVar(
+		&o.FlattenThreshold, "flatten-threshold", o.FlattenThreshold,
+		"Maximum number of committed layers stacked in a mount, "+
+			"deeper ancestors are flattened into a cached base layer when mounting or ahead of time by gc (0 to disable)",
+	)
 	flags.IntVar(&o.UID, "uid", o.UID, "The uid of the user who executed the original command")
 	flags.IntVar(&o.GID, "gid", o.GID, "The uid of the user who executed the original command")
 }
@@ -53,6 +75,10 @@ func (o *GlobalOptions) AddPFlags(flags *pflag.FlagSet) {
 type GlobalOptionsGetter interface {
 	// GetDataRoot 数据存储根目录
 	GetDataRoot() string
+	// GetLockTimeout 等待其它进程释放锁的最长时间
+	GetLockTimeout() time.Duration
+	// GetFlattenThreshold 挂载时最多叠加的已提交层数
+	GetFlattenThreshold() int
 	// GetUID 执行命令的原始用户 ID
 	GetUID() int
 	// GetGID 执行命令的原始用户组 ID
@@ -69,6 +95,16 @@ func (o *GlobalOptions) GetDataRoot() string {
 	return o.DataRoot
 }
 
+// GetLockTimeout 等待其它进程释放锁的最长时间
+func (o *GlobalOptions) GetLockTimeout() time.Duration {
+	return o.LockTimeout
+}
+
+// GetFlattenThreshold 挂载时最多叠加的已提交层数
+func (o *GlobalOptions) GetFlattenThreshold() int {
+	return o.FlattenThreshold
+}
+
 // GetUID 执行命令的原始用户 ID
 func (o *GlobalOptions) GetUID() int {
 	return o.UID
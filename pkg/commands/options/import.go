@@ -0,0 +1,44 @@
+package options
+
+import "github.com/spf13/pflag"
+
+// NewDefaultImportOptions 创建一个默认 import 命令选项
+func NewDefaultImportOptions() ImportOptions {
+	return ImportOptions{
+		Branch:  "",
+		Message: "",
+		Base:    "",
+		Space:   "",
+	}
+}
+
+// ImportOptions import 命令选项
+type ImportOptions struct {
+	// 导入后指向新提交的分支
+	Branch string `json:"branch,omitempty" yaml:"branch,omitempty"`
+	// commit 信息
+	Message string `json:"message,omitempty" yaml:"message,omitempty"`
+	// 基础提交
+	Base string `json:"base,omitempty" yaml:"base,omitempty"`
+	// 导入到指定空间而不是当前工作空间所属空间
+	Space string `json:"space,omitempty" yaml:"space,omitempty"`
+}
+
+// AddPFlags 将选项绑定到命令行参数
+func (o *ImportOptions) AddPFlags(flags *pflag.FlagSet) {
+	flags.StringVarP(
+		&o.Branch, "branch", "b", o.Branch,
+		"Point <branch> to the new commit. If it exists, the new commit is based on its HEAD, "+
+			"otherwise it is created. Branches in a space given by --space are global branches.",
+	)
+	flags.StringVarP(&o.Message, "message", "m", o.Message, "Use the given message as the commit message.")
+	flags.StringVar(
+		&o.Base, "base", o.Base,
+		"Base the new commit on <revision>, only changes from it are stored. "+
+			"Defaults to the HEAD of --branch if it exists, otherwise the content is imported as a root layer.",
+	)
+	flags.StringVar(
+		&o.Space, "space", o.Space,
+		"Import into the space with the given ID or name instead of the space of the current workspace.",
+	)
+}
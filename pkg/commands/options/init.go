@@ -6,6 +6,7 @@ import "github.com/spf13/pflag"
 func NewDefaultInitOptions() InitOptions {
 	return InitOptions{
 		InitialBranch: "main",
+		Name:          "",
 	}
 }
 
@@ -13,6 +14,8 @@ func NewDefaultInitOptions() InitOptions {
 type InitOptions struct {
 	// 初始分支
 	InitialBranch string `json:"initialBranch,omitempty" yaml:"initialBranch,omitempty"`
+	// 新空间的名字
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
 }
 
 // AddPFlags 将选项绑定到命令行参数
@@ -22,4 +25,8 @@ func (o *InitOptions) AddPFlags(flags *pflag.FlagSet) {
 		"initial-branch", "b", o.InitialBranch,
 		"Use the specified name for the initial branch in the newly created space.",
 	)
+	flags.StringVar(
+		&o.Name, "name", o.Name,
+		"Give the newly created space a name, which can be used in place of its ID.",
+	)
 }
@@ -0,0 +1,74 @@
+package options
+
+import "github.com/spf13/pflag"
+
+// NewDefaultExportOCIOptions 创建一个默认 export-oci 命令选项
+func NewDefaultExportOCIOptions() ExportOCIOptions {
+	return ExportOCIOptions{
+		Tag:   "latest",
+		Space: "",
+	}
+}
+
+// ExportOCIOptions export-oci 命令选项
+type ExportOCIOptions struct {
+	// 镜像在布局中的引用名
+	Tag string `json:"tag,omitempty" yaml:"tag,omitempty"`
+	// 从指定空间而不是当前工作空间中获取 revision
+	Space string `json:"space,omitempty" yaml:"space,omitempty"`
+}
+
+// AddPFlags 将选项绑定到命令行参数
+func (o *ExportOCIOptions) AddPFlags(flags *pflag.FlagSet) {
+	flags.StringVarP(
+		&o.Tag, "tag", "t", o.Tag,
+		"Record the image in the layout with the given reference name. Empty means no reference name.",
+	)
+	flags.StringVar(
+		&o.Space, "space", o.Space,
+		"Resolve <revision> in the space with the given ID or name instead of the current workspace.",
+	)
+}
+
+// NewDefaultImportOCIOptions 创建一个默认 import-oci 命令选项
+func NewDefaultImportOCIOptions() ImportOCIOptions {
+	return ImportOCIOptions{
+		Ref:    "",
+		Branch: "",
+		Base:   "",
+		Space:  "",
+	}
+}
+
+// ImportOCIOptions import-oci 命令选项
+type ImportOCIOptions struct {
+	// 导入的镜像在布局中的引用名
+	Ref string `json:"ref,omitempty" yaml:"ref,omitempty"`
+	// 导入后指向最后一个提交的分支
+	Branch string `json:"branch,omitempty" yaml:"branch,omitempty"`
+	// 基础提交
+	Base string `json:"base,omitempty" yaml:"base,omitempty"`
+	// 导入到指定空间而不是当前工作空间所属空间
+	Space string `json:"space,omitempty" yaml:"space,omitempty"`
+}
+
+// AddPFlags 将选项绑定到命令行参数
+func (o *ImportOCIOptions) AddPFlags(flags *pflag.FlagSet) {
+	flags.StringVar(
+		&o.Ref, "ref", o.Ref,
+		"Import the image with the given reference name. Required if the layout contains more than one image.",
+	)
+	flags.StringVarP(
+		&o.Branch, "branch", "b", o.Branch,
+		"Point <branch> to the last imported commit. If it exists, the layers are stacked on its HEAD, "+
+			"otherwise it is created. Branches in a space given by --space are global branches.",
+	)
+	flags.StringVar(
+		&o.Base, "base", o.Base,
+		"Stack the layers on <revision>. Defaults to the HEAD of --branch if it exists, otherwise the root.",
+	)
+	flags.StringVar(
+		&o.Space, "space", o.Space,
+		"Import into the space with the given ID or name instead of the space of the current workspace.",
+	)
+}
@@ -0,0 +1,25 @@
+package options
+
+import "github.com/spf13/pflag"
+
+// NewDefaultPruneOptions 创建一个默认 prune 命令选项
+func NewDefaultPruneOptions() PruneOptions {
+	return PruneOptions{
+		Force: false,
+	}
+}
+
+// PruneOptions prune 命令选项
+type PruneOptions struct {
+	// 有分支、标签或工作空间指向要删除的提交时仍然删除
+	Force bool `json:"force,omitempty" yaml:"force,omitempty"`
+}
+
+// AddPFlags 将选项绑定到命令行参数
+func (o *PruneOptions) AddPFlags(flags *pflag.FlagSet) {
+	flags.BoolVarP(
+		&o.Force, "force", "f", o.Force,
+		"Prune even if branches, tags or workspaces point into the removed commits. "+
+			"Those branches and tags are deleted and those workspaces are unmounted and removed.",
+	)
+}
@@ -0,0 +1,21 @@
+package options
+
+import "github.com/spf13/pflag"
+
+// NewDefaultReflogOptions 创建一个默认 reflog 命令选项
+func NewDefaultReflogOptions() ReflogOptions {
+	return ReflogOptions{
+		Date: false,
+	}
+}
+
+// ReflogOptions reflog 命令选项
+type ReflogOptions struct {
+	// 显示移动发生的时间而不是序号
+	Date bool `json:"date,omitempty" yaml:"date,omitempty"`
+}
+
+// AddPFlags 将选项绑定到命令行参数
+func (o *ReflogOptions) AddPFlags(flags *pflag.FlagSet) {
+	flags.BoolVar(&o.Date, "date", o.Date, "Show the time of each entry instead of its index.")
+}
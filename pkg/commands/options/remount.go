@@ -0,0 +1,29 @@
+package options
+
+import "github.com/spf13/pflag"
+
+// NewDefaultRemountOptions 创建一个默认 remount 命令选项
+func NewDefaultRemountOptions() RemountOptions {
+	return RemountOptions{
+		All:         false,
+		SystemdUnit: false,
+	}
+}
+
+// RemountOptions remount 命令选项
+type RemountOptions struct {
+	// 重新挂载所有工作空间
+	All bool `json:"all,omitempty" yaml:"all,omitempty"`
+	// 输出开机时重新挂载所有工作空间的 systemd 服务单元，而不是重新挂载
+	SystemdUnit bool `json:"systemdUnit,omitempty" yaml:"systemdUnit,omitempty"`
+}
+
+// AddPFlags 将选项绑定到命令行参数
+func (o *RemountOptions) AddPFlags(flags *pflag.FlagSet) {
+	flags.BoolVarP(&o.All, "all", "a", o.All, "Remount all workspaces.")
+	flags.BoolVar(
+		&o.SystemdUnit, "systemd-unit", o.SystemdUnit,
+		"Print a systemd service unit which remounts all workspaces at boot instead of remounting. "+
+			"Save it to /etc/systemd/system/stackcrisp-remount.service and enable it with systemctl.",
+	)
+}
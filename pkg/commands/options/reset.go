@@ -0,0 +1,41 @@
+package options
+
+import "github.com/spf13/pflag"
+
+// NewDefaultResetOptions 创建一个默认 reset 命令选项
+func NewDefaultResetOptions() ResetOptions {
+	return ResetOptions{
+		Soft:  false,
+		Mixed: false,
+		Hard:  false,
+	}
+}
+
+// ResetOptions reset 命令选项
+type ResetOptions struct {
+	// 移动分支头指针并重新挂载，保留工作空间的内容
+	Soft bool `json:"soft,omitempty" yaml:"soft,omitempty"`
+	// 移动分支头指针并重新挂载，保留尚未提交的变更
+	Mixed bool `json:"mixed,omitempty" yaml:"mixed,omitempty"`
+	// 移动分支头指针并重新挂载，丢弃尚未提交的变更
+	Hard bool `json:"hard,omitempty" yaml:"hard,omitempty"`
+}
+
+// AddPFlags 将选项绑定到命令行参数
+func (o *ResetOptions) AddPFlags(flags *pflag.FlagSet) {
+	flags.BoolVar(
+		&o.Soft, "soft", o.Soft,
+		"Move the current branch head to <commit> and remount the working tree on it, keeping the content "+
+			"of the working tree unchanged. Differences from <commit> become uncommitted changes.",
+	)
+	flags.BoolVar(
+		&o.Mixed, "mixed", o.Mixed,
+		"Move the current branch head to <commit> and remount the working tree on it, "+
+			"keeping uncommitted changes if they do not conflict. This is the default action.",
+	)
+	flags.BoolVar(
+		&o.Hard, "hard", o.Hard,
+		"Move the current branch head to <commit> and remount the working tree on it, "+
+			"discarding uncommitted changes.",
+	)
+}
@@ -0,0 +1,77 @@
+package options
+
+import "github.com/spf13/pflag"
+
+// NewDefaultRunOptions 创建一个默认 run 命令选项
+func NewDefaultRunOptions() RunOptions {
+	return RunOptions{
+		Revision: "",
+		Commit:   false,
+		Message:  "",
+		Branch:   "",
+		Chroot:   false,
+		AsRoot:   false,
+		Workdir:  "",
+		Env:      nil,
+		Space:    "",
+	}
+}
+
+// RunOptions run 命令选项
+type RunOptions struct {
+	// 运行命令的基础提交
+	Revision string `json:"revision,omitempty" yaml:"revision,omitempty"`
+	// 命令成功退出后将变更保存为新提交
+	Commit bool `json:"commit,omitempty" yaml:"commit,omitempty"`
+	// commit 信息
+	Message string `json:"message,omitempty" yaml:"message,omitempty"`
+	// 提交后指向新提交的分支
+	Branch string `json:"branch,omitempty" yaml:"branch,omitempty"`
+	// chroot 到挂载中运行命令
+	Chroot bool `json:"chroot,omitempty" yaml:"chroot,omitempty"`
+	// 以 root 运行命令
+	AsRoot bool `json:"asRoot,omitempty" yaml:"asRoot,omitempty"`
+	// 命令的工作目录
+	Workdir string `json:"workdir,omitempty" yaml:"workdir,omitempty"`
+	// 额外的环境变量
+	Env []string `json:"env,omitempty" yaml:"env,omitempty"`
+	// 在指定空间中运行而不是当前工作空间所属空间
+	Space string `json:"space,omitempty" yaml:"space,omitempty"`
+}
+
+// AddPFlags 将选项绑定到命令行参数
+func (o *RunOptions) AddPFlags(flags *pflag.FlagSet) {
+	flags.StringVar(
+		&o.Revision, "rev", o.Revision,
+		"Run the command on <revision>. Defaults to the HEAD of --branch if it exists, "+
+			"otherwise the HEAD of the current workspace, or the root of the space given by --space.",
+	)
+	flags.BoolVar(
+		&o.Commit, "commit", o.Commit,
+		"Record the changes made by the command as a new commit if it exits successfully. "+
+			"Changes are discarded by default.",
+	)
+	flags.StringVarP(
+		&o.Message, "message", "m", o.Message,
+		"Use the given message as the commit message. Defaults to the command.",
+	)
+	flags.StringVarP(
+		&o.Branch, "branch", "b", o.Branch,
+		"Point <branch> to the new commit. If it exists, the new commit is based on its HEAD, "+
+			"otherwise it is created. Branches in a space given by --space are global branches.",
+	)
+	flags.BoolVar(&o.Chroot, "chroot", o.Chroot, "Change the root directory to the mount before running the command.")
+	flags.BoolVar(
+		&o.AsRoot, "as-root", o.AsRoot,
+		"Run the command as root. By default it runs as the user who executed stackcrisp.",
+	)
+	flags.StringVarP(
+		&o.Workdir, "workdir", "w", o.Workdir,
+		"Run the command in the given directory inside the mount. Defaults to the root of the mount.",
+	)
+	flags.StringArrayVarP(&o.Env, "env", "e", o.Env, "Set an environment variable <key>=<value> for the command.")
+	flags.StringVar(
+		&o.Space, "space", o.Space,
+		"Run on a commit of the space with the given ID or name instead of the space of the current workspace.",
+	)
+}
@@ -0,0 +1,55 @@
+package options
+
+import "github.com/spf13/pflag"
+
+// NewDefaultSpaceOptions 创建一个默认 space 命令选项
+func NewDefaultSpaceOptions() SpaceOptions {
+	return SpaceOptions{
+		List:     NewDefaultSpaceListOptions(),
+		Describe: NewDefaultSpaceDescribeOptions(),
+	}
+}
+
+// SpaceOptions space 命令选项
+type SpaceOptions struct {
+	// list 子命令选项
+	List SpaceListOptions `json:"list,omitempty" yaml:"list,omitempty"`
+	// describe 子命令选项
+	Describe SpaceDescribeOptions `json:"describe,omitempty" yaml:"describe,omitempty"`
+}
+
+// NewDefaultSpaceListOptions 创建一个默认 space list 命令选项
+func NewDefaultSpaceListOptions() SpaceListOptions {
+	return SpaceListOptions{
+		JSON: false,
+	}
+}
+
+// SpaceListOptions space list 命令选项
+type SpaceListOptions struct {
+	// 以 JSON 格式输出
+	JSON bool `json:"json,omitempty" yaml:"json,omitempty"`
+}
+
+// AddPFlags 将选项绑定到命令行参数
+func (o *SpaceListOptions) AddPFlags(flags *pflag.FlagSet) {
+	flags.BoolVar(&o.JSON, "json", o.JSON, "Give the output in JSON format.")
+}
+
+// NewDefaultSpaceDescribeOptions 创建一个默认 space describe 命令选项
+func NewDefaultSpaceDescribeOptions() SpaceDescribeOptions {
+	return SpaceDescribeOptions{
+		JSON: false,
+	}
+}
+
+// SpaceDescribeOptions space describe 命令选项
+type SpaceDescribeOptions struct {
+	// 以 JSON 格式输出
+	JSON bool `json:"json,omitempty" yaml:"json,omitempty"`
+}
+
+// AddPFlags 将选项绑定到命令行参数
+func (o *SpaceDescribeOptions) AddPFlags(flags *pflag.FlagSet) {
+	flags.BoolVar(&o.JSON, "json", o.JSON, "Give the output in JSON format.")
+}
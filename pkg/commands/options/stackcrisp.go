@@ -3,14 +3,31 @@ package options
 // NewDefaultOptions 创建一个默认运行选项
 func NewDefaultOptions() Options {
 	return Options{
-		Global:   NewDefaultGlobalOptions(),
-		Init:     NewDefaultInitOptions(),
-		Clone:    NewDefaultCloneOptions(),
-		Commit:   NewDefaultCommitOptions(),
-		Checkout: NewDefaultCheckoutOptions(),
-		Branch:   NewDefaultBranchOptions(),
-		Tag:      NewDefaultTagOptions(),
-		Log:      NewDefaultLogOptions(),
+		Global:    NewDefaultGlobalOptions(),
+		Init:      NewDefaultInitOptions(),
+		Clone:     NewDefaultCloneOptions(),
+		Commit:    NewDefaultCommitOptions(),
+		Checkout:  NewDefaultCheckoutOptions(),
+		Switch:    NewDefaultSwitchOptions(),
+		Reset:     NewDefaultResetOptions(),
+		Prune:     NewDefaultPruneOptions(),
+		Branch:    NewDefaultBranchOptions(),
+		Tag:       NewDefaultTagOptions(),
+		Log:       NewDefaultLogOptions(),
+		Reflog:    NewDefaultReflogOptions(),
+		Status:    NewDefaultStatusOptions(),
+		Diff:      NewDefaultDiffOptions(),
+		GC:        NewDefaultGCOptions(),
+		Remount:   NewDefaultRemountOptions(),
+		Workspace: NewDefaultWorkspaceOptions(),
+		Space:     NewDefaultSpaceOptions(),
+		DU:        NewDefaultDUOptions(),
+		Archive:   NewDefaultArchiveOptions(),
+		Import:    NewDefaultImportOptions(),
+		ExportOCI: NewDefaultExportOCIOptions(),
+		ImportOCI: NewDefaultImportOCIOptions(),
+		Run:       NewDefaultRunOptions(),
+		Build:     NewDefaultBuildOptions(),
 	}
 }
 
@@ -26,10 +43,44 @@ type Options struct {
 	Commit CommitOptions `json:"commit,omitempty" yaml:"commit,omitempty"`
 	// checkout 命令选项
 	Checkout CheckoutOptions `json:"checkout,omitempty" yaml:"checkout,omitempty"`
+	// switch 命令选项
+	Switch SwitchOptions `json:"switch,omitempty" yaml:"switch,omitempty"`
+	// reset 命令选项
+	Reset ResetOptions `json:"reset,omitempty" yaml:"reset,omitempty"`
+	// prune 命令选项
+	Prune PruneOptions `json:"prune,omitempty" yaml:"prune,omitempty"`
 	// branch 命令选项
 	Branch BranchOptions `json:"branch,omitempty" yaml:"branch,omitempty"`
 	// tag 命令选项
 	Tag TagOptions `json:"tag,omitempty" yaml:"tag,omitempty"`
 	// log 命令选项
 	Log LogOptions `json:"log,omitempty" yaml:"log,omitempty"`
+	// reflog 命令选项
+	Reflog ReflogOptions `json:"reflog,omitempty" yaml:"reflog,omitempty"`
+	// status 命令选项
+	Status StatusOptions `json:"status,omitempty" yaml:"status,omitempty"`
+	// diff 命令选项
+	Diff DiffOptions `json:"diff,omitempty" yaml:"diff,omitempty"`
+	// gc 命令选项
+	GC GCOptions `json:"gc,omitempty" yaml:"gc,omitempty"`
+	// remount 命令选项
+	Remount RemountOptions `json:"remount,omitempty" yaml:"remount,omitempty"`
+	// workspace 命令选项
+	Workspace WorkspaceOptions `json:"workspace,omitempty" yaml:"workspace,omitempty"`
+	// space 命令选项
+	Space SpaceOptions `json:"space,omitempty" yaml:"space,omitempty"`
+	// du 命令选项
+	DU DUOptions `json:"du,omitempty" yaml:"du,omitempty"`
+	// archive 命令选项
+	Archive ArchiveOptions `json:"archive,omitempty" yaml:"archive,omitempty"`
+	// import 命令选项
+	Import ImportOptions `json:"import,omitempty" yaml:"import,omitempty"`
+	// export-oci 命令选项
+	ExportOCI ExportOCIOptions `json:"exportOCI,omitempty" yaml:"exportOCI,omitempty"`
+	// import-oci 命令选项
+	ImportOCI ImportOCIOptions `json:"importOCI,omitempty" yaml:"importOCI,omitempty"`
+	// run 命令选项
+	Run RunOptions `json:"run,omitempty" yaml:"run,omitempty"`
+	// build 命令选项
+	Build BuildOptions `json:"build,omitempty" yaml:"build,omitempty"`
 }
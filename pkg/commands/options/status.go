@@ -0,0 +1,33 @@
+package options
+
+import "github.com/spf13/pflag"
+
+// NewDefaultStatusOptions 创建一个默认 status 命令选项
+func NewDefaultStatusOptions() StatusOptions {
+	return StatusOptions{
+		Short:     false,
+		Porcelain: false,
+		JSON:      false,
+	}
+}
+
+// StatusOptions status 命令选项
+type StatusOptions struct {
+	// 以短格式输出
+	Short bool `json:"short,omitempty" yaml:"short,omitempty"`
+	// 以便于脚本解析的格式输出
+	Porcelain bool `json:"porcelain,omitempty" yaml:"porcelain,omitempty"`
+	// 以 JSON 格式输出
+	JSON bool `json:"json,omitempty" yaml:"json,omitempty"`
+}
+
+// AddPFlags 将选项绑定到命令行参数
+func (o *StatusOptions) AddPFlags(flags *pflag.FlagSet) {
+	flags.BoolVarP(&o.Short, "short", "s", o.Short, "Give the output in the short-format.")
+	flags.BoolVar(
+		&o.Porcelain, "porcelain", o.Porcelain,
+		"Give the output in an easy-to-parse format for scripts. "+
+			"This is similar to the short output, but will remain stable across versions.",
+	)
+	flags.BoolVar(&o.JSON, "json", o.JSON, "Give the output in JSON format.")
+}
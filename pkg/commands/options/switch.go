@@ -0,0 +1,39 @@
+package options
+
+import "github.com/spf13/pflag"
+
+// NewDefaultSwitchOptions 创建一个默认 switch 命令选项
+func NewDefaultSwitchOptions() SwitchOptions {
+	return SwitchOptions{
+		Create:      "",
+		ForceCreate: "",
+		Detach:      false,
+	}
+}
+
+// SwitchOptions switch 命令选项
+type SwitchOptions struct {
+	// 创建并切换到新分支
+	Create string `json:"create,omitempty" yaml:"create,omitempty"`
+	// 创建或重置并切换到新分支
+	ForceCreate string `json:"forceCreate,omitempty" yaml:"forceCreate,omitempty"`
+	// 以分离头指针状态切换
+	Detach bool `json:"detach,omitempty" yaml:"detach,omitempty"`
+}
+
+// AddPFlags 将选项绑定到命令行参数
+func (o *SwitchOptions) AddPFlags(flags *pflag.FlagSet) {
+	flags.StringVarP(
+		&o.Create, "create", "c", o.Create,
+		"Create a new branch named <new-branch> starting at <start-point> before switching to the branch.",
+	)
+	// -C 已经被全局选项 --chdir 占用，所以不设置短选项
+	flags.StringVar(
+		&o.ForceCreate, "force-create", o.ForceCreate,
+		"Similar to --create except that if <new-branch> already exists, it will be reset to <start-point>.",
+	)
+	flags.BoolVarP(
+		&o.Detach, "detach", "d", o.Detach,
+		"Switch to a commit for inspection and discardable experiments.",
+	)
+}
@@ -0,0 +1,61 @@
+package options
+
+import "github.com/spf13/pflag"
+
+// NewDefaultWorkspaceOptions 创建一个默认 workspace 命令选项
+func NewDefaultWorkspaceOptions() WorkspaceOptions {
+	return WorkspaceOptions{
+		List:   NewDefaultWorkspaceListOptions(),
+		Remove: NewDefaultWorkspaceRemoveOptions(),
+	}
+}
+
+// WorkspaceOptions workspace 命令选项
+type WorkspaceOptions struct {
+	// list 子命令选项
+	List WorkspaceListOptions `json:"list,omitempty" yaml:"list,omitempty"`
+	// remove 子命令选项
+	Remove WorkspaceRemoveOptions `json:"remove,omitempty" yaml:"remove,omitempty"`
+}
+
+// NewDefaultWorkspaceListOptions 创建一个默认 workspace list 命令选项
+func NewDefaultWorkspaceListOptions() WorkspaceListOptions {
+	return WorkspaceListOptions{
+		JSON: false,
+	}
+}
+
+// WorkspaceListOptions workspace list 命令选项
+type WorkspaceListOptions struct {
+	// 以 JSON 格式输出
+	JSON bool `json:"json,omitempty" yaml:"json,omitempty"`
+}
+
+// AddPFlags 将选项绑定到命令行参数
+func (o *WorkspaceListOptions) AddPFlags(flags *pflag.FlagSet) {
+	flags.BoolVar(&o.JSON, "json", o.JSON, "Give the output in JSON format.")
+}
+
+// NewDefaultWorkspaceRemoveOptions 创建一个默认 workspace remove 命令选项
+func NewDefaultWorkspaceRemoveOptions() WorkspaceRemoveOptions {
+	return WorkspaceRemoveOptions{
+		DiscardChanges: false,
+		Force:          false,
+	}
+}
+
+// WorkspaceRemoveOptions workspace remove 命令选项
+type WorkspaceRemoveOptions struct {
+	// 丢弃尚未提交的变更
+	DiscardChanges bool `json:"discardChanges,omitempty" yaml:"discardChanges,omitempty"`
+	// 挂载被占用时延迟卸载
+	Force bool `json:"force,omitempty" yaml:"force,omitempty"`
+}
+
+// AddPFlags 将选项绑定到命令行参数
+func (o *WorkspaceRemoveOptions) AddPFlags(flags *pflag.FlagSet) {
+	flags.BoolVar(&o.DiscardChanges, "discard-changes", o.DiscardChanges,
+		"Remove the workspace even if it has uncommitted changes, which will be lost.")
+	flags.BoolVarP(&o.Force, "force", "f", o.Force,
+		"Lazily unmount the workspace even if it is still in use by some processes.")
+}
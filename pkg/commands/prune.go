@@ -0,0 +1,64 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/go-logr/logr"
+	"github.com/spf13/cobra"
+
+	"github.com/yhlooo/stackcrisp/pkg/commands/options"
+	"github.com/yhlooo/stackcrisp/pkg/manager"
+	cmdutil "github.com/yhlooo/stackcrisp/pkg/utils/cmd"
+)
+
+// NewPruneCommandWithOptions 创建一个基于选项的 prune 命令
+func NewPruneCommandWithOptions(opts *options.PruneOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "prune [-f] <commit>",
+		Short:   "Remove a commit and all its descendants",
+		GroupID: groupWork,
+		Annotations: map[string]string{
+			cmdutil.AnnotationRunAsRoot:      cmdutil.AnnotationValueTrue,
+			cmdutil.AnnotationRequireManager: cmdutil.AnnotationValueTrue,
+		},
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			logger := logr.FromContextOrDiscard(ctx).WithName(loggerName)
+			logger.V(1).Info(fmt.Sprintf("target commit: %q, force: %t", args[0], opts.Force))
+
+			// 获取管理器
+			mgr := cmdutil.ManagerFromContext(ctx)
+
+			// 找到当前目录对应 workspace
+			ws, err := mgr.GetWorkspaceFromPath(ctx, ".")
+			if err != nil {
+				return fmt.Errorf("get workspace from path \".\" error: %w", err)
+			}
+
+			// 删除
+			result, err := mgr.Prune(ctx, ws, args[0], manager.PruneOptions{Force: opts.Force})
+			if result != nil {
+				for _, id := range result.Mounts {
+					fmt.Printf("Removed mount %s\n", id)
+				}
+				for _, p := range result.Workspaces {
+					fmt.Printf("Removed workspace %s\n", p)
+				}
+				for _, id := range result.Nodes {
+					fmt.Printf("Removed commit %s\n", id)
+				}
+				fmt.Printf("Reclaimed %s\n", formatBytes(result.ReclaimedBytes))
+			}
+			if err != nil {
+				return fmt.Errorf("prune error: %w", err)
+			}
+			return nil
+		},
+	}
+
+	// 绑定选项到命令行参数
+	opts.AddPFlags(cmd.Flags())
+
+	return cmd
+}
@@ -0,0 +1,74 @@
+package commands
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/spf13/cobra"
+
+	"github.com/yhlooo/stackcrisp/pkg/commands/options"
+	cmdutil "github.com/yhlooo/stackcrisp/pkg/utils/cmd"
+)
+
+// NewReflogCommandWithOptions 创建一个基于选项的 reflog 命令
+func NewReflogCommandWithOptions(opts *options.ReflogOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "reflog [<ref>]",
+		Short:   "Show where HEAD and branches have been",
+		Long:    "Show the reflog of HEAD or a branch. Each entry can be referred to as <ref>@{n}.",
+		GroupID: groupState,
+		Annotations: map[string]string{
+			cmdutil.AnnotationRequireManager: cmdutil.AnnotationValueTrue,
+		},
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			logger := logr.FromContextOrDiscard(ctx).WithName(loggerName)
+
+			ref := "HEAD"
+			if len(args) > 0 {
+				ref = args[0]
+			}
+			logger.V(1).Info(fmt.Sprintf("ref: %q", ref))
+
+			// 获取管理器
+			mgr := cmdutil.ManagerFromContext(ctx)
+
+			// 获取工作空间
+			ws, err := mgr.GetWorkspaceFromPath(ctx, ".")
+			if err != nil {
+				return fmt.Errorf("get workspace from path \".\" error: %w", err)
+			}
+
+			// 获取 reflog
+			reflog, err := ws.Reflog(ref)
+			if err != nil {
+				return err
+			}
+			entries, err := reflog.Entries()
+			if err != nil {
+				return fmt.Errorf("read reflog of %q error: %w", ref, err)
+			}
+
+			// 打印
+			for i, e := range entries {
+				id := e.New
+				if id == "" {
+					id = "(deleted)"
+				}
+				selector := fmt.Sprintf("%s@{%d}", ref, i)
+				if opts.Date {
+					selector = fmt.Sprintf("%s@{%s}", ref, e.Time.Local().Format(time.ANSIC+" -0700"))
+				}
+				fmt.Printf("\033[33m%s\033[0m %s: %s: %s\n", id, selector, e.Command, e.Message)
+			}
+			return nil
+		},
+	}
+
+	// 绑定选项到命令行参数
+	opts.AddPFlags(cmd.Flags())
+
+	return cmd
+}
@@ -0,0 +1,105 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/yhlooo/stackcrisp/pkg/commands/options"
+	cmdutil "github.com/yhlooo/stackcrisp/pkg/utils/cmd"
+)
+
+// remountSystemdUnit 开机时重新挂载所有工作空间的 systemd 服务单元
+const remountSystemdUnit = `[Unit]
+Description=Remount stackcrisp workspaces
+After=local-fs.target
+RequiresMountsFor=%[2]s
+
+[Service]
+Type=oneshot
+ExecStart=%[1]s --data-root %[2]s remount --all
+RemainAfterExit=yes
+
+[Install]
+WantedBy=multi-user.target
+`
+
+// NewRemountCommandWithOptions 创建一个基于选项的 remount 命令
+func NewRemountCommandWithOptions(opts *options.RemountOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "remount [--all | <path>]",
+		Short: "Mount workspaces again after a reboot",
+		Long: "Mount the workspace containing <path> (the current directory by default), " +
+			"or all workspaces with --all, again. " +
+			"Mounts do not survive a reboot, the layers are rebuilt from the head of each workspace " +
+			"and the uncommitted changes are kept.",
+		GroupID: groupMaintain,
+		Annotations: map[string]string{
+			cmdutil.AnnotationRunAsRoot:      cmdutil.AnnotationValueTrue,
+			cmdutil.AnnotationRequireManager: cmdutil.AnnotationValueTrue,
+		},
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			if opts.SystemdUnit {
+				return printRemountSystemdUnit(cmd)
+			}
+
+			path := "."
+			switch {
+			case opts.All && len(args) > 0:
+				return fmt.Errorf("--all and <path> can not be used together")
+			case opts.All:
+				path = ""
+			case len(args) > 0:
+				path = args[0]
+			}
+
+			// 获取管理器
+			mgr := cmdutil.ManagerFromContext(ctx)
+
+			// 重新挂载
+			result, err := mgr.Remount(ctx, path)
+			if result != nil {
+				for _, p := range result.Remounted {
+					fmt.Printf("Remounted %s\n", p)
+				}
+				if !opts.All {
+					for _, p := range result.AlreadyMounted {
+						fmt.Printf("%s is already mounted\n", p)
+					}
+				}
+			}
+			if err != nil {
+				return fmt.Errorf("remount error: %w", err)
+			}
+			return nil
+		},
+	}
+
+	// 绑定选项到命令行参数
+	opts.AddPFlags(cmd.Flags())
+
+	return cmd
+}
+
+// printRemountSystemdUnit 输出开机时重新挂载所有工作空间的 systemd 服务单元
+func printRemountSystemdUnit(cmd *cobra.Command) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("get executable path error: %w", err)
+	}
+	dataRoot := "/var/lib/stackcrisp"
+	if f := cmd.Flag("data-root"); f != nil {
+		dataRoot = f.Value.String()
+	}
+	dataRoot, err = filepath.Abs(dataRoot)
+	if err != nil {
+		return fmt.Errorf("get absolute path of data root error: %w", err)
+	}
+	fmt.Printf(remountSystemdUnit, exe, dataRoot)
+	return nil
+}
@@ -0,0 +1,74 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/go-logr/logr"
+	"github.com/spf13/cobra"
+
+	"github.com/yhlooo/stackcrisp/pkg/commands/options"
+	"github.com/yhlooo/stackcrisp/pkg/manager"
+	cmdutil "github.com/yhlooo/stackcrisp/pkg/utils/cmd"
+)
+
+// NewResetCommandWithOptions 创建一个基于选项的 reset 命令
+func NewResetCommandWithOptions(opts *options.ResetOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "reset [--soft | --mixed | --hard] [<commit>]",
+		Short:   "Reset current HEAD to the specified state",
+		GroupID: groupWork,
+		Annotations: map[string]string{
+			cmdutil.AnnotationRunAsRoot:      cmdutil.AnnotationValueTrue,
+			cmdutil.AnnotationRequireManager: cmdutil.AnnotationValueTrue,
+		},
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			logger := logr.FromContextOrDiscard(ctx).WithName(loggerName)
+
+			targetCommit := "HEAD"
+			if len(args) > 0 {
+				targetCommit = args[0]
+			}
+			mode := manager.ResetMixed
+			switch {
+			case opts.Soft:
+				mode = manager.ResetSoft
+			case opts.Hard:
+				mode = manager.ResetHard
+			}
+			logger.V(1).Info(fmt.Sprintf("target commit: %q, mode: %s", targetCommit, mode))
+
+			// 获取管理器
+			mgr := cmdutil.ManagerFromContext(ctx)
+
+			// 找到当前目录对应 workspace
+			ws, err := mgr.GetWorkspaceFromPath(ctx, ".")
+			if err != nil {
+				return fmt.Errorf("get workspace from path \".\" error: %w", err)
+			}
+
+			// reset
+			newWS, err := mgr.Reset(ctx, ws, targetCommit, mode)
+			if err != nil {
+				return fmt.Errorf("reset error: %w", err)
+			}
+			if newWS.Mount().ID().Hex() == ws.Mount().ID().Hex() {
+				// 挂载没有变化
+				return nil
+			}
+
+			// 展开新的 workspace 并回收旧的 workspace
+			if err := mgr.Apply(ctx, newWS, ws); err != nil {
+				return err
+			}
+			return nil
+		},
+	}
+
+	// 绑定选项到命令行参数
+	opts.AddPFlags(cmd.Flags())
+	cmd.MarkFlagsMutuallyExclusive("soft", "mixed", "hard")
+
+	return cmd
+}
@@ -0,0 +1,96 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/yhlooo/stackcrisp/pkg/commands/options"
+	"github.com/yhlooo/stackcrisp/pkg/manager"
+	cmdutil "github.com/yhlooo/stackcrisp/pkg/utils/cmd"
+	"github.com/yhlooo/stackcrisp/pkg/workspaces"
+)
+
+// NewRunCommandWithOptions 创建一个基于选项的 run 命令
+func NewRunCommandWithOptions(opts *options.RunOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "run [flags] [--] <command> [args...]",
+		Short: "Run a command on a revision in an isolated mount",
+		Long: "Run a command on a revision in an isolated mount.\n\n" +
+			"The revision is mounted with a throwaway upper layer in a private mount namespace, " +
+			"which is invisible to other processes and is released when the command exits. " +
+			"The command runs in the mount, or with the mount as its root directory with --chroot. " +
+			"Changes are discarded unless --commit is given and the command exits successfully. " +
+			"Uncommitted changes in the workspace are not visible to the command.",
+		GroupID: groupWork,
+		Annotations: map[string]string{
+			cmdutil.AnnotationRunAsRoot:      cmdutil.AnnotationValueTrue,
+			cmdutil.AnnotationRequireManager: cmdutil.AnnotationValueTrue,
+		},
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			// 获取管理器
+			mgr := cmdutil.ManagerFromContext(ctx)
+
+			// 找到当前目录对应 workspace
+			var ws workspaces.Workspace
+			if opts.Space == "" {
+				var err error
+				ws, err = mgr.GetWorkspaceFromPath(ctx, ".")
+				if err != nil {
+					return fmt.Errorf("get workspace from path \".\" error: %w", err)
+				}
+			}
+
+			// 运行
+			var env []string
+			if len(opts.Env) > 0 {
+				env = append(os.Environ(), opts.Env...)
+			}
+			result, err := mgr.Run(ctx, ws, manager.RunOptions{
+				Space:    opts.Space,
+				Revision: opts.Revision,
+				Command:  args,
+				Env:      env,
+				Dir:      opts.Workdir,
+				Chroot:   opts.Chroot,
+				AsRoot:   opts.AsRoot,
+				Stdin:    os.Stdin,
+				Stdout:   os.Stdout,
+				Stderr:   os.Stderr,
+				Commit:   opts.Commit,
+				Message:  opts.Message,
+				Branch:   opts.Branch,
+			})
+			if err != nil {
+				return fmt.Errorf("run error: %w", err)
+			}
+			if result.ExitCode != 0 {
+				return fmt.Errorf("command exited with code %d, changes discarded", result.ExitCode)
+			}
+			if result.Commit == "" {
+				return nil
+			}
+			base := result.Base
+			if base == "" {
+				base = "root"
+			}
+			if result.Branch != "" {
+				fmt.Printf("Committed %s on %s (branch %s)\n", result.Commit, base, result.Branch)
+			} else {
+				fmt.Printf("Committed %s on %s\n", result.Commit, base)
+			}
+			return nil
+		},
+	}
+
+	// 命令之后的参数都属于命令
+	cmd.Flags().SetInterspersed(false)
+	// 绑定选项到命令行参数
+	opts.AddPFlags(cmd.Flags())
+
+	return cmd
+}
@@ -0,0 +1,232 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/yhlooo/stackcrisp/pkg/commands/options"
+	"github.com/yhlooo/stackcrisp/pkg/manager"
+	cmdutil "github.com/yhlooo/stackcrisp/pkg/utils/cmd"
+)
+
+// NewSpaceCommandWithOptions 创建一个基于选项的 space 命令
+func NewSpaceCommandWithOptions(opts *options.SpaceOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "space",
+		Short:   "Manage spaces in the data root",
+		GroupID: groupMaintain,
+		Args:    cobra.NoArgs,
+	}
+
+	// 添加子命令
+	cmd.AddCommand(
+		newSpaceListCommandWithOptions(&opts.List),
+		newSpaceDescribeCommandWithOptions(&opts.Describe),
+		newSpaceRenameCommand(),
+		newSpaceDeleteCommand(),
+	)
+
+	return cmd
+}
+
+// newSpaceListCommandWithOptions 创建一个基于选项的 space list 命令
+func newSpaceListCommandWithOptions(opts *options.SpaceListOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "List all spaces",
+		Annotations: map[string]string{
+			cmdutil.AnnotationRunAsRoot:      cmdutil.AnnotationValueTrue,
+			cmdutil.AnnotationRequireManager: cmdutil.AnnotationValueTrue,
+		},
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			// 获取管理器
+			mgr := cmdutil.ManagerFromContext(ctx)
+
+			// 列出空间
+			list, err := mgr.ListSpaces(ctx)
+			if err != nil {
+				return fmt.Errorf("list spaces error: %w", err)
+			}
+
+			if opts.JSON {
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(list)
+			}
+			printSpaceList(list)
+			return nil
+		},
+	}
+
+	// 绑定选项到命令行参数
+	opts.AddPFlags(cmd.Flags())
+
+	return cmd
+}
+
+// newSpaceDescribeCommandWithOptions 创建一个基于选项的 space describe 命令
+func newSpaceDescribeCommandWithOptions(opts *options.SpaceDescribeOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "describe <space>",
+		Aliases: []string{"show"},
+		Short:   "Show details of a space",
+		Annotations: map[string]string{
+			cmdutil.AnnotationRunAsRoot:      cmdutil.AnnotationValueTrue,
+			cmdutil.AnnotationRequireManager: cmdutil.AnnotationValueTrue,
+		},
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			// 获取管理器
+			mgr := cmdutil.ManagerFromContext(ctx)
+
+			status, err := mgr.DescribeSpace(ctx, args[0])
+			if err != nil {
+				return fmt.Errorf("describe space error: %w", err)
+			}
+
+			if opts.JSON {
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(status)
+			}
+			printSpace(status)
+			return nil
+		},
+	}
+
+	// 绑定选项到命令行参数
+	opts.AddPFlags(cmd.Flags())
+
+	return cmd
+}
+
+// newSpaceRenameCommand 创建 space rename 命令
+func newSpaceRenameCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "rename <space> <new-name>",
+		Short: "Rename a space, an empty name removes it",
+		Annotations: map[string]string{
+			cmdutil.AnnotationRunAsRoot:      cmdutil.AnnotationValueTrue,
+			cmdutil.AnnotationRequireManager: cmdutil.AnnotationValueTrue,
+		},
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			// 获取管理器
+			mgr := cmdutil.ManagerFromContext(ctx)
+
+			if err := mgr.RenameSpace(ctx, args[0], args[1]); err != nil {
+				return fmt.Errorf("rename space error: %w", err)
+			}
+			return nil
+		},
+	}
+}
+
+// newSpaceDeleteCommand 创建 space delete 命令
+func newSpaceDeleteCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:     "delete <space>",
+		Aliases: []string{"rm"},
+		Short:   "Delete a space and all its layers, which must not be used by any workspace",
+		Annotations: map[string]string{
+			cmdutil.AnnotationRunAsRoot:      cmdutil.AnnotationValueTrue,
+			cmdutil.AnnotationRequireManager: cmdutil.AnnotationValueTrue,
+		},
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			// 获取管理器
+			mgr := cmdutil.ManagerFromContext(ctx)
+
+			ret, err := mgr.DeleteSpace(ctx, args[0])
+			if err != nil {
+				return fmt.Errorf("delete space error: %w", err)
+			}
+			fmt.Printf("Deleted space %s\n", ret.ID)
+			fmt.Printf("Reclaimed %s (%d layers)\n", formatBytes(ret.ReclaimedBytes), len(ret.Layers))
+			return nil
+		},
+	}
+}
+
+// printSpaceList 以表格形式打印空间列表
+func printSpaceList(list []manager.SpaceStatus) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "ID\tNAME\tCREATED\tCOMMITS\tBRANCHES\tWORKSPACES\tSIZE")
+	for _, space := range list {
+		name := space.Name
+		if name == "" {
+			name = "-"
+		}
+		_, _ = fmt.Fprintf(
+			w, "%s\t%s\t%s\t%d\t%s\t%d\t%s\n",
+			space.ID, name, formatCreationTime(space.CreationTime), space.Commits,
+			strings.Join(spaceBranchNames(space.Branches), ","), len(space.Workspaces),
+			formatBytes(space.DiskUsageBytes),
+		)
+	}
+	_ = w.Flush()
+}
+
+// printSpace 打印空间详情
+func printSpace(space *manager.SpaceStatus) {
+	name := space.Name
+	if name == "" {
+		name = "-"
+	}
+	fmt.Printf("ID:          %s\n", space.ID)
+	fmt.Printf("Name:        %s\n", name)
+	fmt.Printf("Created:     %s\n", formatCreationTime(space.CreationTime))
+	fmt.Printf("Commits:     %d\n", space.Commits)
+	fmt.Printf("Disk Usage:  %s\n", formatBytes(space.DiskUsageBytes))
+	fmt.Println("Branches:")
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	for _, b := range space.Branches {
+		ws := b.Workspace
+		if ws == "" && !strings.HasPrefix(b.Name, "origin/") {
+			ws = "(workspace removed)"
+		}
+		_, _ = fmt.Fprintf(w, "  %s\t%s\t%s\n", b.Name, b.Commit, ws)
+	}
+	_ = w.Flush()
+	fmt.Println("Workspaces:")
+	for _, path := range space.Workspaces {
+		fmt.Printf("  %s\n", path)
+	}
+}
+
+// spaceBranchNames 返回去重后的分支名
+func spaceBranchNames(branches []manager.SpaceBranch) []string {
+	var ret []string
+	seen := map[string]bool{}
+	for _, b := range branches {
+		if !seen[b.Name] {
+			seen[b.Name] = true
+			ret = append(ret, b.Name)
+		}
+	}
+	return ret
+}
+
+// formatCreationTime 格式化创建时间，没有记录时返回 -
+func formatCreationTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format(time.DateTime)
+}
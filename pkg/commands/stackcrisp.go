@@ -12,9 +12,10 @@ import (
 const (
 	loggerName = "commands"
 
-	groupStart = "start"
-	groupWork  = "work"
-	groupState = "state"
+	groupStart    = "start"
+	groupWork     = "work"
+	groupState    = "state"
+	groupMaintain = "maintain"
 )
 
 // NewStackCrispCommandWithOptions 创建一个基于选项的 stackcrisp 命令
@@ -46,6 +47,10 @@ func NewStackCrispCommandWithOptions(opts options.Options) *cobra.Command {
 			logger.V(1).Info(fmt.Sprintf("command: %q, args: %#v, options: %#v", cmd.Name(), args, opts))
 			return nil
 		},
+		PersistentPostRunE: func(cmd *cobra.Command, args []string) error {
+			// 释放 manager 持有的锁
+			return cmdutil.CloseManagerIfNecessary(cmd)
+		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return cmd.Help()
 		},
@@ -59,6 +64,7 @@ func NewStackCrispCommandWithOptions(opts options.Options) *cobra.Command {
 		&cobra.Group{ID: groupStart, Title: "Start a working area"},
 		&cobra.Group{ID: groupWork, Title: "Grow, mark and tweak your common history"},
 		&cobra.Group{ID: groupState, Title: "Examine the history and state"},
+		&cobra.Group{ID: groupMaintain, Title: "Maintain the data root"},
 	)
 
 	// 添加子命令
@@ -67,9 +73,27 @@ func NewStackCrispCommandWithOptions(opts options.Options) *cobra.Command {
 		NewCloneCommandWithOptions(&opts.Clone),
 		NewCommitCommandWithOptions(&opts.Commit),
 		NewCheckoutCommandWithOptions(&opts.Checkout),
+		NewSwitchCommandWithOptions(&opts.Switch),
+		NewResetCommandWithOptions(&opts.Reset),
+		NewPruneCommandWithOptions(&opts.Prune),
 		NewBranchCommandWithOptions(&opts.Branch),
 		NewTagCommandWithOptions(&opts.Tag),
 		NewLogCommandWithOptions(&opts.Log),
+		NewReflogCommandWithOptions(&opts.Reflog),
+		NewStatusCommandWithOptions(&opts.Status),
+		NewDiffCommandWithOptions(&opts.Diff),
+		NewArchiveCommandWithOptions(&opts.Archive),
+		NewImportCommandWithOptions(&opts.Import),
+		NewExportOCICommandWithOptions(&opts.ExportOCI),
+		NewImportOCICommandWithOptions(&opts.ImportOCI),
+		NewRunCommandWithOptions(&opts.Run),
+		NewBuildCommandWithOptions(&opts.Build),
+		NewGCCommandWithOptions(&opts.GC),
+		NewFlattenCommand(),
+		NewRemountCommandWithOptions(&opts.Remount),
+		NewWorkspaceCommandWithOptions(&opts.Workspace),
+		NewSpaceCommandWithOptions(&opts.Space),
+		NewDUCommandWithOptions(&opts.DU),
 	)
 
 	return cmd
@@ -0,0 +1,160 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/go-logr/logr"
+	"github.com/spf13/cobra"
+
+	"github.com/yhlooo/stackcrisp/pkg/changes"
+	"github.com/yhlooo/stackcrisp/pkg/commands/options"
+	cmdutil "github.com/yhlooo/stackcrisp/pkg/utils/cmd"
+	"github.com/yhlooo/stackcrisp/pkg/workspaces"
+)
+
+// NewStatusCommandWithOptions 创建一个基于选项的 status 命令
+func NewStatusCommandWithOptions(opts *options.StatusOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "status",
+		Short:   "Show the working tree status",
+		GroupID: groupState,
+		Annotations: map[string]string{
+			cmdutil.AnnotationRunAsRoot:      cmdutil.AnnotationValueTrue,
+			cmdutil.AnnotationRequireManager: cmdutil.AnnotationValueTrue,
+		},
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			logger := logr.FromContextOrDiscard(ctx).WithName(loggerName)
+
+			// 获取管理器
+			mgr := cmdutil.ManagerFromContext(ctx)
+
+			// 找到当前目录对应 workspace
+			ws, err := mgr.GetWorkspaceFromPath(ctx, ".")
+			if err != nil {
+				return fmt.Errorf("get workspace from path \".\" error: %w", err)
+			}
+
+			// 获取变更
+			logger.V(1).Info(fmt.Sprintf("get changes of upper layer %s", ws.Head().ID()))
+			changeList, err := ws.Status(ctx)
+			if err != nil {
+				return fmt.Errorf("get status of workspace error: %w", err)
+			}
+
+			// 打印
+			switch {
+			case opts.JSON:
+				return printStatusJSON(ws, changeList)
+			case opts.Porcelain:
+				printStatusShort(changeList, false)
+			case opts.Short:
+				printStatusShort(changeList, true)
+			default:
+				printStatusLong(ws, changeList)
+			}
+			return nil
+		},
+	}
+
+	// 绑定选项到命令行参数
+	opts.AddPFlags(cmd.Flags())
+
+	return cmd
+}
+
+// statusJSON JSON 格式输出的工作空间状态
+type statusJSON struct {
+	Branch  string           `json:"branch,omitempty"`
+	Head    string           `json:"head"`
+	Changes []changes.Change `json:"changes"`
+}
+
+// printStatusJSON 以 JSON 格式打印工作空间状态
+func printStatusJSON(ws workspaces.Workspace, changeList []changes.Change) error {
+	if changeList == nil {
+		changeList = []changes.Change{}
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(&statusJSON{
+		Branch:  ws.Branch().LocalName(),
+		Head:    ws.Head().Parent().ID().Hex(),
+		Changes: changeList,
+	})
+}
+
+// printStatusShort 以短格式打印工作空间状态
+func printStatusShort(changeList []changes.Change, color bool) {
+	for _, c := range changeList {
+		code, colorCode := statusCode(c.Kind)
+		if color {
+			fmt.Printf("\033[%sm%s\033[0m %s\n", colorCode, code, statusPath(c))
+		} else {
+			fmt.Printf("%s %s\n", code, statusPath(c))
+		}
+	}
+}
+
+// printStatusLong 以长格式打印工作空间状态
+func printStatusLong(ws workspaces.Workspace, changeList []changes.Change) {
+	if branch := ws.Branch(); branch.Name() != "" {
+		fmt.Printf("On branch %s\n", branch.LocalName())
+	} else {
+		fmt.Printf("\033[31mHEAD detached at %s\033[0m\n", ws.Head().Parent().ID().Hex())
+	}
+
+	if len(changeList) == 0 {
+		fmt.Println("nothing to commit, working tree clean")
+		return
+	}
+
+	fmt.Println("Changes not committed:")
+	fmt.Println("  (use \"stackcrisp commit\" to record them)")
+	fmt.Println()
+	for _, c := range changeList {
+		_, colorCode := statusCode(c.Kind)
+		fmt.Printf("\t\033[%sm%-10s %s\033[0m\n", colorCode, statusLabel(c.Kind)+":", statusPath(c))
+	}
+	fmt.Println()
+}
+
+// statusCode 返回变更类型对应的短格式代码和颜色
+func statusCode(kind changes.Kind) (string, string) {
+	switch kind {
+	case changes.Added:
+		return "A", "32"
+	case changes.Modified:
+		return "M", "33"
+	case changes.Deleted:
+		return "D", "31"
+	default:
+		return "?", "0"
+	}
+}
+
+// statusLabel 返回变更类型对应的长格式标签
+func statusLabel(kind changes.Kind) string {
+	switch kind {
+	case changes.Added:
+		return "added"
+	case changes.Modified:
+		return "modified"
+	case changes.Deleted:
+		return "deleted"
+	default:
+		return "unknown"
+	}
+}
+
+// statusPath 返回打印的变更路径，目录以 / 结尾
+func statusPath(c changes.Change) string {
+	if c.IsDir {
+		return c.Path + string(filepath.Separator)
+	}
+	return c.Path
+}
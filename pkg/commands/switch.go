@@ -0,0 +1,50 @@
+package commands
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/yhlooo/stackcrisp/pkg/commands/options"
+	"github.com/yhlooo/stackcrisp/pkg/manager"
+	cmdutil "github.com/yhlooo/stackcrisp/pkg/utils/cmd"
+)
+
+// NewSwitchCommandWithOptions 创建一个基于选项的 switch 命令
+func NewSwitchCommandWithOptions(opts *options.SwitchOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "switch [-c | --force-create <new-branch>] [--detach] [<branch> | <start-point>]",
+		Short:   "Switch branches",
+		GroupID: groupWork,
+		Annotations: map[string]string{
+			cmdutil.AnnotationRunAsRoot:      cmdutil.AnnotationValueTrue,
+			cmdutil.AnnotationRequireManager: cmdutil.AnnotationValueTrue,
+		},
+		Args: func(cmd *cobra.Command, args []string) error {
+			if opts.Create != "" || opts.ForceCreate != "" || opts.Detach {
+				return cobra.MaximumNArgs(1)(cmd, args)
+			}
+			return cobra.ExactArgs(1)(cmd, args)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			target := "HEAD"
+			if len(args) > 0 {
+				target = args[0]
+			}
+			switchOpts := manager.CheckoutOptions{
+				NewBranch:     opts.Create,
+				Detach:        opts.Detach,
+				RequireBranch: true,
+			}
+			if opts.ForceCreate != "" {
+				switchOpts.NewBranch = opts.ForceCreate
+				switchOpts.ForceNewBranch = true
+			}
+			return runCheckout(cmd, target, switchOpts)
+		},
+	}
+
+	// 绑定选项到命令行参数
+	opts.AddPFlags(cmd.Flags())
+	cmd.MarkFlagsMutuallyExclusive("create", "force-create", "detach")
+
+	return cmd
+}
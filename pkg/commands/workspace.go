@@ -0,0 +1,139 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/yhlooo/stackcrisp/pkg/commands/options"
+	"github.com/yhlooo/stackcrisp/pkg/manager"
+	cmdutil "github.com/yhlooo/stackcrisp/pkg/utils/cmd"
+)
+
+// NewWorkspaceCommandWithOptions 创建一个基于选项的 workspace 命令
+func NewWorkspaceCommandWithOptions(opts *options.WorkspaceOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "workspace",
+		Aliases: []string{"worktree"},
+		Short:   "Manage workspaces in the data root",
+		GroupID: groupMaintain,
+		Args:    cobra.NoArgs,
+	}
+
+	// 添加子命令
+	cmd.AddCommand(
+		newWorkspaceListCommandWithOptions(&opts.List),
+		newWorkspaceRemoveCommandWithOptions(&opts.Remove),
+	)
+
+	return cmd
+}
+
+// newWorkspaceListCommandWithOptions 创建一个基于选项的 workspace list 命令
+func newWorkspaceListCommandWithOptions(opts *options.WorkspaceListOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "List all workspaces",
+		Annotations: map[string]string{
+			cmdutil.AnnotationRunAsRoot:      cmdutil.AnnotationValueTrue,
+			cmdutil.AnnotationRequireManager: cmdutil.AnnotationValueTrue,
+		},
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			// 获取管理器
+			mgr := cmdutil.ManagerFromContext(ctx)
+
+			// 列出工作空间
+			list, err := mgr.ListWorkspaces(ctx)
+			if err != nil {
+				return fmt.Errorf("list workspaces error: %w", err)
+			}
+
+			if opts.JSON {
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(list)
+			}
+			printWorkspaceList(list)
+			return nil
+		},
+	}
+
+	// 绑定选项到命令行参数
+	opts.AddPFlags(cmd.Flags())
+
+	return cmd
+}
+
+// newWorkspaceRemoveCommandWithOptions 创建一个基于选项的 workspace remove 命令
+func newWorkspaceRemoveCommandWithOptions(opts *options.WorkspaceRemoveOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "remove <path>",
+		Aliases: []string{"rm"},
+		Short:   "Unmount a workspace and remove its path, mount, local branches and uncommitted layer",
+		Annotations: map[string]string{
+			cmdutil.AnnotationRunAsRoot:      cmdutil.AnnotationValueTrue,
+			cmdutil.AnnotationRequireManager: cmdutil.AnnotationValueTrue,
+		},
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			// 获取管理器
+			mgr := cmdutil.ManagerFromContext(ctx)
+
+			// 删除工作空间
+			ret, err := mgr.RemoveWorkspace(ctx, args[0], manager.RemoveWorkspaceOptions{
+				DiscardChanges: opts.DiscardChanges,
+				Force:          opts.Force,
+			})
+			if ret != nil {
+				fmt.Printf("Removed workspace %s\n", ret.Path)
+				for _, b := range ret.Branches {
+					fmt.Printf("Deleted branch %s\n", b)
+				}
+				fmt.Printf("Reclaimed %s\n", formatBytes(ret.ReclaimedBytes))
+			}
+			if err != nil {
+				return fmt.Errorf("remove workspace error: %w", err)
+			}
+			return nil
+		},
+	}
+
+	// 绑定选项到命令行参数
+	opts.AddPFlags(cmd.Flags())
+
+	return cmd
+}
+
+// printWorkspaceList 以表格形式打印工作空间列表
+func printWorkspaceList(list []manager.WorkspaceStatus) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "PATH\tSPACE\tBRANCH\tHEAD\tSTATE\tUNCOMMITTED")
+	for _, ws := range list {
+		branch := ws.Branch
+		if branch == "" {
+			branch = "(detached)"
+		}
+		head := ws.Head
+		if head == "" {
+			head = "-"
+		}
+		state := string(ws.State)
+		if ws.StaleReason != "" {
+			state += " (" + ws.StaleReason + ")"
+		}
+		_, _ = fmt.Fprintf(
+			w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			ws.Path, ws.SpaceID, branch, head, state, formatBytes(ws.UncommittedBytes),
+		)
+	}
+	_ = w.Flush()
+}
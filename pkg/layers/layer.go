@@ -1,6 +1,7 @@
 package layers
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -25,9 +26,27 @@ type Layer interface {
 	DiffDir() string
 	// Save 将相关信息持久化
 	Save() error
+	// Usage 返回层占用的空间，有缓存时使用缓存的结果，否则实时统计
+	Usage() (fsutil.Usage, error)
+	// RecordedUsage 返回缓存的层占用的空间，没有缓存时返回 false
+	RecordedUsage() (fsutil.Usage, bool)
+	// RecordUsage 统计层占用的空间并缓存
+	//
+	// 应该在层的内容不再变化（比如被提交）后调用
+	RecordUsage() (fsutil.Usage, error)
+	// OpaqueDirs 返回记录的不透明目录相对路径，没有记录时返回 false
+	OpaqueDirs() ([]string, bool)
+	// RecordOpaqueDirs 记录不透明目录的相对路径，使不能读取 trusted 扩展属性的非 root 用户也可以识别不透明目录
+	//
+	// 应该在层的内容不再变化（比如被提交）后调用
+	RecordOpaqueDirs(dirs []string) error
 }
 
-const layerDataSubPathDiff = "diff"
+const (
+	layerDataSubPathDiff   = "diff"
+	layerDataSubPathUsage  = "usage.json"
+	layerDataSubPathOpaque = "opaque.json"
+)
 
 // defaultLayer 是 Layer 的一个默认实现
 type defaultLayer struct {
@@ -57,3 +76,70 @@ func (l *defaultLayer) Save() error {
 	}
 	return nil
 }
+
+// Usage 返回层占用的空间，有缓存时使用缓存的结果，否则实时统计
+func (l *defaultLayer) Usage() (fsutil.Usage, error) {
+	if usage, ok := l.RecordedUsage(); ok {
+		return usage, nil
+	}
+	return fsutil.GetUsage(l.DiffDir())
+}
+
+// RecordedUsage 返回缓存的层占用的空间，没有缓存时返回 false
+func (l *defaultLayer) RecordedUsage() (fsutil.Usage, bool) {
+	var usage fsutil.Usage
+	raw, err := os.ReadFile(l.usageFilePath())
+	if err != nil || json.Unmarshal(raw, &usage) != nil {
+		return fsutil.Usage{}, false
+	}
+	return usage, true
+}
+
+// RecordUsage 统计层占用的空间并缓存
+func (l *defaultLayer) RecordUsage() (fsutil.Usage, error) {
+	usage, err := fsutil.GetUsage(l.DiffDir())
+	if err != nil {
+		return usage, fmt.Errorf("get usage of %q error: %w", l.DiffDir(), err)
+	}
+	raw, err := json.Marshal(&usage)
+	if err != nil {
+		return usage, fmt.Errorf("marshal usage to json error: %w", err)
+	}
+	if err := fsutil.WriteFileAtomic(l.usageFilePath(), raw, 0644); err != nil {
+		return usage, fmt.Errorf("write usage error: %w", err)
+	}
+	return usage, nil
+}
+
+// OpaqueDirs 返回记录的不透明目录相对路径，没有记录时返回 false
+func (l *defaultLayer) OpaqueDirs() ([]string, bool) {
+	dirs := []string{}
+	raw, err := os.ReadFile(filepath.Join(l.layerDataRoot, layerDataSubPathOpaque))
+	if err != nil || json.Unmarshal(raw, &dirs) != nil {
+		return nil, false
+	}
+	if dirs == nil {
+		dirs = []string{}
+	}
+	return dirs, true
+}
+
+// RecordOpaqueDirs 记录不透明目录的相对路径
+func (l *defaultLayer) RecordOpaqueDirs(dirs []string) error {
+	if dirs == nil {
+		dirs = []string{}
+	}
+	raw, err := json.Marshal(dirs)
+	if err != nil {
+		return fmt.Errorf("marshal opaque dirs to json error: %w", err)
+	}
+	if err := fsutil.WriteFileAtomic(filepath.Join(l.layerDataRoot, layerDataSubPathOpaque), raw, 0644); err != nil {
+		return fmt.Errorf("write opaque dirs error: %w", err)
+	}
+	return nil
+}
+
+// usageFilePath 返回缓存的层占用空间的存储路径
+func (l *defaultLayer) usageFilePath() string {
+	return filepath.Join(l.layerDataRoot, layerDataSubPathUsage)
+}
@@ -0,0 +1,151 @@
+package locks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+const (
+	// 等待锁时重试的间隔
+	pollInterval = 100 * time.Millisecond
+)
+
+// Owner 锁的持有者
+type Owner struct {
+	// 进程 ID
+	PID int `json:"pid"`
+	// 进程正在执行的命令
+	Command string `json:"command,omitempty"`
+	// 获取到锁的时间
+	Time time.Time `json:"time"`
+}
+
+// LockedError 等待超时后锁仍然被其它进程持有的错误
+type LockedError struct {
+	// 锁文件路径
+	Path string
+	// 持有者，未知时为 nil
+	Owner *Owner
+}
+
+// Error 返回错误描述
+func (err *LockedError) Error() string {
+	if err.Owner == nil {
+		return "locked by another process"
+	}
+	if err.Owner.Command == "" {
+		return fmt.Sprintf("locked by pid %d", err.Owner.PID)
+	}
+	return fmt.Sprintf("locked by pid %d running %s", err.Owner.PID, err.Owner.Command)
+}
+
+// Lock 基于文件的跨进程互斥锁
+//
+// 锁在持有者进程退出时自动释放，不会因为进程被终止而残留
+type Lock interface {
+	// Lock 获取锁，最多等待 timeout ，超时后返回 *LockedError
+	Lock(ctx context.Context, owner Owner, timeout time.Duration) error
+	// Unlock 释放锁
+	Unlock() error
+}
+
+// New 创建一个使用指定文件的 Lock
+func New(path string) Lock {
+	return &fileLock{path: path}
+}
+
+// NewShared 创建一个使用指定文件的共享 Lock
+//
+// 多个进程可以同时持有同一文件的共享锁，但与 New 创建的排他锁互斥。共享锁不记录持有者
+func NewShared(path string) Lock {
+	return &fileLock{path: path, shared: true}
+}
+
+// fileLock 是 Lock 的一个实现，使用 flock 锁定文件，并将持有者信息写在文件中
+type fileLock struct {
+	path   string
+	shared bool
+	file   *os.File
+}
+
+var _ Lock = &fileLock{}
+
+// Lock 获取锁，最多等待 timeout ，超时后返回 *LockedError
+func (l *fileLock) Lock(ctx context.Context, owner Owner, timeout time.Duration) error {
+	if l.file != nil {
+		return fmt.Errorf("lock %q is already held", l.path)
+	}
+
+	f, err := os.OpenFile(l.path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("open lock file %q error: %w", l.path, err)
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		ok, err := tryLock(f, l.shared)
+		if err != nil {
+			_ = f.Close()
+			return fmt.Errorf("lock file %q error: %w", l.path, err)
+		}
+		if ok {
+			break
+		}
+		if !time.Now().Before(deadline) {
+			_ = f.Close()
+			return &LockedError{Path: l.path, Owner: readOwner(l.path)}
+		}
+		select {
+		case <-ctx.Done():
+			_ = f.Close()
+			return ctx.Err()
+		case <-time.After(pollInterval):
+		}
+	}
+	l.file = f
+	if l.shared {
+		return nil
+	}
+
+	// 记录持有者，仅用于提示，失败不影响加锁
+	if raw, err := json.Marshal(&owner); err == nil {
+		if err := f.Truncate(0); err == nil {
+			_, _ = f.WriteAt(raw, 0)
+		}
+	}
+	return nil
+}
+
+// Unlock 释放锁
+func (l *fileLock) Unlock() error {
+	if l.file == nil {
+		return nil
+	}
+	f := l.file
+	l.file = nil
+	// 先清空持有者信息再解锁，关闭文件时锁被释放
+	if !l.shared {
+		_ = f.Truncate(0)
+	}
+	if err := unlock(f); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("unlock file %q error: %w", l.path, err)
+	}
+	return f.Close()
+}
+
+// readOwner 读取锁文件中记录的持有者
+func readOwner(path string) *Owner {
+	raw, err := os.ReadFile(path)
+	if err != nil || len(raw) == 0 {
+		return nil
+	}
+	owner := &Owner{}
+	if err := json.Unmarshal(raw, owner); err != nil {
+		return nil
+	}
+	return owner
+}
@@ -0,0 +1,35 @@
+//go:build linux
+
+package locks
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// tryLock 尝试以非阻塞方式获取文件的排他锁或共享锁，被其它进程以互斥的方式持有时返回 false
+func tryLock(f *os.File, shared bool) (bool, error) {
+	how := syscall.LOCK_EX
+	if shared {
+		how = syscall.LOCK_SH
+	}
+	for {
+		err := syscall.Flock(int(f.Fd()), how|syscall.LOCK_NB)
+		switch {
+		case err == nil:
+			return true, nil
+		case errors.Is(err, syscall.EINTR):
+			continue
+		case errors.Is(err, syscall.EWOULDBLOCK):
+			return false, nil
+		default:
+			return false, err
+		}
+	}
+}
+
+// unlock 释放文件锁
+func unlock(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
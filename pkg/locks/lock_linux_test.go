@@ -0,0 +1,91 @@
+//go:build linux
+
+package locks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestFileLock 测试 fileLock
+func TestFileLock(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "lock")
+	owner := Owner{PID: os.Getpid(), Command: "commit", Time: time.Now()}
+
+	l1 := New(path)
+	if err := l1.Lock(ctx, owner, 0); err != nil {
+		t.Fatalf("lock error: %v", err)
+	}
+
+	// 同一文件的另一个锁获取不到，并能看到持有者
+	l2 := New(path)
+	err := l2.Lock(ctx, Owner{PID: 1}, 3*pollInterval)
+	var lockedErr *LockedError
+	if !errors.As(err, &lockedErr) {
+		t.Fatalf("expected *LockedError, got %v", err)
+	}
+	if lockedErr.Owner == nil || lockedErr.Owner.PID != owner.PID || lockedErr.Owner.Command != "commit" {
+		t.Errorf("unexpected owner: %#v", lockedErr.Owner)
+	}
+	expectedMsg := fmt.Sprintf("locked by pid %d running commit", owner.PID)
+	if err.Error() != expectedMsg {
+		t.Errorf("expected error %q, got %q", expectedMsg, err.Error())
+	}
+
+	// 释放后可以在等待期间获取到
+	go func() {
+		time.Sleep(2 * pollInterval)
+		_ = l1.Unlock()
+	}()
+	if err := l2.Lock(ctx, Owner{PID: 1}, time.Second); err != nil {
+		t.Fatalf("lock after unlock error: %v", err)
+	}
+	if err := l2.Unlock(); err != nil {
+		t.Errorf("unlock error: %v", err)
+	}
+}
+
+// TestSharedFileLock 测试共享的 fileLock
+func TestSharedFileLock(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "lock")
+
+	// 共享锁可以同时持有
+	s1, s2 := NewShared(path), NewShared(path)
+	if err := s1.Lock(ctx, Owner{PID: 1}, 0); err != nil {
+		t.Fatalf("lock shared error: %v", err)
+	}
+	if err := s2.Lock(ctx, Owner{PID: 2}, 0); err != nil {
+		t.Fatalf("lock shared again error: %v", err)
+	}
+
+	// 有共享锁时获取不到排他锁
+	ex := New(path)
+	var lockedErr *LockedError
+	if err := ex.Lock(ctx, Owner{PID: 3}, 2*pollInterval); !errors.As(err, &lockedErr) {
+		t.Fatalf("expected *LockedError, got %v", err)
+	}
+
+	// 共享锁都释放后可以获取排他锁，之后获取不到共享锁
+	_ = s1.Unlock()
+	_ = s2.Unlock()
+	if err := ex.Lock(ctx, Owner{PID: 3, Command: "gc"}, 0); err != nil {
+		t.Fatalf("lock exclusive error: %v", err)
+	}
+	err := s1.Lock(ctx, Owner{PID: 1}, 2*pollInterval)
+	if !errors.As(err, &lockedErr) {
+		t.Fatalf("expected *LockedError, got %v", err)
+	}
+	if lockedErr.Owner == nil || lockedErr.Owner.Command != "gc" {
+		t.Errorf("unexpected owner: %#v", lockedErr.Owner)
+	}
+	if err := ex.Unlock(); err != nil {
+		t.Errorf("unlock error: %v", err)
+	}
+}
@@ -0,0 +1,17 @@
+//go:build !linux
+
+package locks
+
+import "os"
+
+// tryLock 尝试以非阻塞方式获取文件的排他锁或共享锁，被其它进程以互斥的方式持有时返回 false
+//
+// 非 Linux 平台不支持挂载，也就不会有修改数据的操作，总是成功
+func tryLock(*os.File, bool) (bool, error) {
+	return true, nil
+}
+
+// unlock 释放文件锁
+func unlock(*os.File) error {
+	return nil
+}
@@ -0,0 +1,125 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/go-logr/logr"
+
+	"github.com/yhlooo/stackcrisp/pkg/builds"
+	"github.com/yhlooo/stackcrisp/pkg/reflogs"
+	"github.com/yhlooo/stackcrisp/pkg/workspaces"
+)
+
+// Build 从基础提交开始依次运行构建步骤，每个步骤的变更保存为上一步骤提交的子提交
+func (mgr *defaultManager) Build(ctx context.Context, ws workspaces.Workspace, opts BuildOptions) (*BuildResult, error) {
+	logger := logr.FromContextOrDiscard(ctx).WithName(loggerName)
+	ctx = reflogs.NewContextWithMessage(ctx, "build")
+
+	// 确定空间、分支和基础提交
+	target, err := mgr.resolveImportTarget(ctx, ws, ImportOptions{
+		Space:  opts.Space,
+		Base:   opts.Revision,
+		Branch: opts.Branch,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if opts.Revision == "" && opts.Space == "" && target.branchHead == nil {
+		// 默认基于工作空间头指针
+		if target.base, _, err = ws.Resolve("HEAD"); err != nil {
+			return nil, err
+		}
+	}
+	space := target.space
+
+	// 构建不修改工作空间，不阻塞工作空间中的其它操作
+	if ws != nil {
+		if err := mgr.unlockWorkspace(ctx, ws.ID().Base32()); err != nil {
+			return nil, fmt.Errorf("unlock workspace error: %w", err)
+		}
+	}
+
+	user := ""
+	if uid, gid := mgr.runUser(opts.AsRoot); uid >= 0 && gid >= 0 {
+		user = fmt.Sprintf("%d:%d", uid, gid)
+	}
+	var env []string
+	if len(opts.Env) > 0 {
+		env = append(os.Environ(), opts.Env...)
+	}
+
+	// 依次运行步骤
+	ret := &BuildResult{Base: target.base.ID().Hex()}
+	if target.base.IsRoot() {
+		ret.Base = ""
+	}
+	parent := target.base
+	for i, step := range opts.Steps {
+		key := builds.CacheKey(builds.CacheKeyInput{
+			Parent:  parent.ID(),
+			Command: step.Command,
+			Env:     opts.Env,
+			Dir:     opts.Dir,
+			Chroot:  opts.Chroot,
+			User:    user,
+		})
+		if !opts.NoCache {
+			if cached := builds.FindCached(parent, key); cached != nil {
+				logger.Info(fmt.Sprintf("step %d/%d: using cache %s", i+1, len(opts.Steps), cached.ID().Hex()))
+				ret.Steps = append(ret.Steps, BuildStepResult{Commit: cached.ID().Hex(), Cached: true})
+				parent = cached
+				continue
+			}
+		}
+
+		logger.Info(fmt.Sprintf("step %d/%d: %s", i+1, len(opts.Steps), step.Command))
+		node, exitCode, err := mgr.runOnNode(ctx, space, parent, RunOptions{
+			Command: []string{"/bin/sh", "-c", step.Command},
+			Env:     env,
+			Dir:     opts.Dir,
+			Chroot:  opts.Chroot,
+			AsRoot:  opts.AsRoot,
+			Stdout:  opts.Stdout,
+			Stderr:  opts.Stderr,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("run step %d (line %d) error: %w", i+1, step.Line, err)
+		}
+		if exitCode != 0 {
+			return nil, fmt.Errorf("step %d (line %d) exited with code %d", i+1, step.Line, exitCode)
+		}
+
+		// 提交并立即保存，后续步骤失败时可以复用。运行步骤期间空间被重新加载， node 来自重新加载的空间
+		workspaces.NewCommitInfo(step.Command).SetToNode(node)
+		builds.SetCacheKey(node, key)
+		logger.Info(fmt.Sprintf("saving space %s ...", space.ID()))
+		if err := space.Save(ctx); err != nil {
+			return nil, fmt.Errorf("save space error: %w", err)
+		}
+		mgr.recordLayerInfo(ctx, node.ID())
+		ret.Steps = append(ret.Steps, BuildStepResult{Commit: node.ID().Hex()})
+		parent = node
+	}
+	ret.Commit = parent.ID().Hex()
+
+	// 更新分支头指针，构建期间分支被移动到其它位置时不覆盖
+	if target.branch != nil {
+		if target.branchHead != nil {
+			err = space.Tree().UpdateBranch(target.branch.FullName(), parent.ID(), false)
+		} else {
+			err = space.Tree().AddBranch(target.branch.FullName(), parent.ID())
+		}
+		if err != nil {
+			return nil, fmt.Errorf("update branch %q error: %w", target.branch.LocalName(), err)
+		}
+		logger.Info(fmt.Sprintf("saving space %s ...", space.ID()))
+		if err := space.Save(ctx); err != nil {
+			return nil, fmt.Errorf("save space error: %w", err)
+		}
+		ret.Branch = target.branch.LocalName()
+	}
+
+	return ret, nil
+}
@@ -0,0 +1,161 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-logr/logr"
+
+	"github.com/yhlooo/stackcrisp/pkg/reflogs"
+	"github.com/yhlooo/stackcrisp/pkg/spaces"
+	"github.com/yhlooo/stackcrisp/pkg/spaces/trees"
+	"github.com/yhlooo/stackcrisp/pkg/utils/uid"
+	"github.com/yhlooo/stackcrisp/pkg/workspaces"
+)
+
+// DefaultCloneBranch 从空间克隆且没有指定分支和 revision 时检出的分支
+const DefaultCloneBranch = "main"
+
+// Clone 克隆工作空间
+func (mgr *defaultManager) Clone(
+	ctx context.Context,
+	sourceWS workspaces.Workspace,
+	targetPath string,
+	opts CloneOptions,
+) (workspaces.Workspace, error) {
+	ctx = reflogs.NewContextWithMessage(ctx, fmt.Sprintf("from %s", sourceWS.Path()))
+	space := sourceWS.Space()
+
+	// 确定起始节点和分支
+	var node trees.Node
+	branch := opts.Branch
+	switch {
+	case opts.Revision != "":
+		var err error
+		if node, _, err = sourceWS.Resolve(opts.Revision); err != nil {
+			return nil, err
+		}
+	case opts.Branch != "":
+		// 优先使用源工作空间的本地分支，其次是全局分支和其它工作空间的本地分支
+		var ok bool
+		node, ok = space.Tree().GetByBranch(workspaces.NewLocalBranch(sourceWS.ID(), opts.Branch).FullName())
+		if !ok {
+			b, n, err := workspaces.FindBranch(space.Tree(), opts.Branch)
+			if err != nil {
+				return nil, err
+			}
+			node, branch = n, b.LocalName()
+		}
+	default:
+		node = sourceWS.Head().Parent()
+		branch = sourceWS.Branch().LocalName()
+	}
+
+	return mgr.clone(ctx, space, node, branch, targetPath)
+}
+
+// CloneSpace 从空间克隆出一个新的工作空间，不需要该空间有已经挂载的工作空间
+func (mgr *defaultManager) CloneSpace(
+	ctx context.Context,
+	spaceRef string,
+	targetPath string,
+	opts CloneOptions,
+) (workspaces.Workspace, error) {
+	spaceID, err := mgr.resolveSpaceID(ctx, spaceRef)
+	if err != nil {
+		return nil, err
+	}
+	space, err := mgr.loadSpace(ctx, spaceID)
+	if err != nil {
+		return nil, err
+	}
+	ctx = reflogs.NewContextWithMessage(ctx, fmt.Sprintf("from space %s", spaceID))
+
+	// 确定起始节点和分支
+	var node trees.Node
+	branch := opts.Branch
+	if opts.Revision != "" {
+		if node, _, err = workspaces.ResolveInSpace(space, opts.Revision); err != nil {
+			return nil, err
+		}
+	} else {
+		if branch == "" {
+			branch = DefaultCloneBranch
+		}
+		b, n, err := workspaces.FindBranch(space.Tree(), branch)
+		if err != nil {
+			return nil, fmt.Errorf("%w in space %s, specify a branch or revision to clone", err, spaceID)
+		}
+		node, branch = n, b.LocalName()
+	}
+
+	return mgr.clone(ctx, space, node, branch, targetPath)
+}
+
+// clone 基于空间中的指定节点创建一个新的工作空间，新工作空间处于本地名为 branch 的分支
+//
+// branch 是已经存在的全局分支的本地名时跟踪该全局分支，否则在新工作空间中创建指向该节点的本地分支。
+// branch 为空时新工作空间处于分离头指针状态
+func (mgr *defaultManager) clone(
+	ctx context.Context,
+	space spaces.Space,
+	node trees.Node,
+	branch string,
+	targetPath string,
+) (workspaces.Workspace, error) {
+	logger := logr.FromContextOrDiscard(ctx).WithName(loggerName)
+
+	if !workspaces.IsCommitted(node) {
+		return nil, fmt.Errorf("%s is not a commit", node.ID().Hex())
+	}
+	if name, ok := strings.CutPrefix(branch, "origin/"); ok {
+		// 跟踪的全局分支之后随新工作空间的提交移动，需要从其头指针开始
+		globalHead, ok := space.Tree().GetByBranch(workspaces.NewGlobalBranch(name).FullName())
+		if ok && globalHead.ID().Hex() != node.ID().Hex() {
+			return nil, fmt.Errorf(
+				"branch %q is at %s, not at %s, only its HEAD can be cloned to track it",
+				branch, globalHead.ID().Hex(), node.ID().Hex(),
+			)
+		}
+	}
+
+	wsID := uid.NewUID128()
+	if err := mgr.lockWorkspace(ctx, wsID.Base32()); err != nil {
+		return nil, err
+	}
+
+	// 基于指定节点创建新挂载
+	mount, head, err := mgr.createMount(ctx, space, node.ID())
+	if err != nil {
+		return nil, fmt.Errorf("create mount error: %w", err)
+	}
+	if err := mgr.beginIntent(ctx, intentOperationClone, wsID, targetPath, space, false, mount.ID(), nil); err != nil {
+		return nil, err
+	}
+	logger.Info(fmt.Sprintf("forward to new head %q", node.ID().Hex()))
+
+	// 记录分支，跟踪全局分支时不需要创建
+	newWS := workspaces.New(wsID, targetPath, space, mount, head, branch)
+	if b := newWS.Branch(); b.IsLocal() && b.Name() != "" {
+		if err := newWS.SetBranch(branch); err != nil {
+			return nil, fmt.Errorf("add branch to tree error: %w", err)
+		}
+	}
+
+	// 记录空间信息
+	logger.Info(fmt.Sprintf("saving space %s ...", space.ID()))
+	if err := space.Save(ctx); err != nil {
+		return nil, fmt.Errorf("save space error: %w", err)
+	}
+	// 记录工作空间信息
+	if err := mgr.saveWorkspaceInfo(ctx, newWS); err != nil {
+		return nil, fmt.Errorf("save workspace info error: %w", err)
+	}
+	if err := mgr.advanceIntent(ctx, mount.ID(), intentPhasePrepared); err != nil {
+		return nil, err
+	}
+	mgr.recordHeadMove(ctx, nil, newWS)
+
+	return newWS, nil
+}
@@ -0,0 +1,96 @@
+//go:build linux
+
+package manager
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/yhlooo/stackcrisp/pkg/workspaces"
+)
+
+// newTestWorkspaceWithGlobalBranch 创建一个有一个提交的工作空间，并创建指向该提交的全局分支 x
+func newTestWorkspaceWithGlobalBranch(t *testing.T) (*defaultManager, string, workspaces.Workspace, workspaces.BranchInfo) {
+	ctx := context.Background()
+	mgr, root := newTestManager(t)
+	ws := createTestWorkspace(t, mgr, filepath.Join(root, "ws"))
+	writeTestFiles(t, ws, map[string]string{"a": "1"})
+	ws = commitTestWorkspace(t, mgr, ws, "first")
+
+	global := workspaces.NewGlobalBranch("x")
+	if err := ws.Space().Tree().AddBranch(global.FullName(), ws.Head().Parent().ID()); err != nil {
+		t.Fatalf("add branch error: %v", err)
+	}
+	if err := ws.Space().Save(ctx); err != nil {
+		t.Fatalf("save space error: %v", err)
+	}
+	return mgr, root, ws, global
+}
+
+// cloneTestWorkspace 克隆并展开工作空间
+func cloneTestWorkspace(
+	t *testing.T,
+	mgr Manager,
+	ws workspaces.Workspace,
+	path string,
+	opts CloneOptions,
+) workspaces.Workspace {
+	ctx := context.Background()
+	newWS, err := mgr.Clone(ctx, ws, path, opts)
+	if err != nil {
+		t.Fatalf("clone error: %v", err)
+	}
+	if err := mgr.Apply(ctx, newWS, nil); err != nil {
+		t.Fatalf("apply workspace error: %v", err)
+	}
+	return newWS
+}
+
+// checkTrackingBranch 检查工作空间跟踪全局分支且没有创建本地分支
+func checkTrackingBranch(t *testing.T, ws workspaces.Workspace, global workspaces.BranchInfo) {
+	t.Helper()
+	if ws.Branch().FullName() != global.FullName() {
+		t.Errorf("expected workspace to track %q, got %q", global.FullName(), ws.Branch().FullName())
+	}
+	for _, name := range []string{global.Name(), global.LocalName()} {
+		if _, ok := ws.Space().Tree().GetByBranch(workspaces.NewLocalBranch(ws.ID(), name).FullName()); ok {
+			t.Errorf("expected no local branch %q in the new workspace", name)
+		}
+	}
+}
+
+// TestCloneBranchTracksGlobalBranch 测试克隆时指定的分支是全局分支时跟踪该分支
+func TestCloneBranchTracksGlobalBranch(t *testing.T) {
+	mgr, root, ws, global := newTestWorkspaceWithGlobalBranch(t)
+
+	newWS := cloneTestWorkspace(t, mgr, ws, filepath.Join(root, "clone"), CloneOptions{Branch: "x"})
+	checkTrackingBranch(t, newWS, global)
+
+	// 提交移动跟踪的全局分支
+	writeTestFiles(t, newWS, map[string]string{"b": "2"})
+	newWS = commitTestWorkspace(t, mgr, newWS, "second")
+	head, ok := newWS.Space().Tree().GetByBranch(global.FullName())
+	if !ok || head.ID().Hex() != newWS.Head().Parent().ID().Hex() {
+		t.Errorf("expected branch %q to point to %s, got %v", global.LocalName(), newWS.Head().Parent().ID().Hex(), head)
+	}
+}
+
+// TestCloneFromWorkspaceTrackingGlobalBranch 测试从跟踪全局分支的工作空间克隆时同样跟踪该分支
+func TestCloneFromWorkspaceTrackingGlobalBranch(t *testing.T) {
+	mgr, root, ws, global := newTestWorkspaceWithGlobalBranch(t)
+
+	tracking := cloneTestWorkspace(t, mgr, ws, filepath.Join(root, "tracking"), CloneOptions{Branch: global.LocalName()})
+	newWS := cloneTestWorkspace(t, mgr, tracking, filepath.Join(root, "clone"), CloneOptions{})
+	checkTrackingBranch(t, newWS, global)
+	checkTestFiles(t, newWS, map[string]string{"a": "1"})
+
+	// 源工作空间处于本地分支时创建同名本地分支
+	newWS = cloneTestWorkspace(t, mgr, ws, filepath.Join(root, "local"), CloneOptions{})
+	if !newWS.Branch().IsLocal() || newWS.Branch().Name() != "main" {
+		t.Errorf("expected workspace to be on local branch main, got %q", newWS.Branch().FullName())
+	}
+	if _, ok := newWS.Space().Tree().GetByBranch(newWS.Branch().FullName()); !ok {
+		t.Errorf("expected local branch main to be created")
+	}
+}
@@ -0,0 +1,217 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/go-logr/logr"
+
+	"github.com/yhlooo/stackcrisp/pkg/changes"
+	"github.com/yhlooo/stackcrisp/pkg/spaces"
+	"github.com/yhlooo/stackcrisp/pkg/spaces/trees"
+	fsutil "github.com/yhlooo/stackcrisp/pkg/utils/fs"
+	"github.com/yhlooo/stackcrisp/pkg/utils/uid"
+	"github.com/yhlooo/stackcrisp/pkg/workspaces"
+)
+
+// DiskUsage 统计空间及其中各提交和分支占用的空间
+func (mgr *defaultManager) DiskUsage(ctx context.Context, spaceRef string) ([]SpaceUsage, error) {
+	logger := logr.FromContextOrDiscard(ctx).WithName(loggerName)
+
+	var spaceIDs []string
+	if spaceRef != "" {
+		id, err := mgr.resolveSpaceID(ctx, spaceRef)
+		if err != nil {
+			return nil, err
+		}
+		spaceIDs = []string{id}
+	} else {
+		var err error
+		if spaceIDs, err = mgr.listSpaceIDs(ctx); err != nil {
+			return nil, fmt.Errorf("list spaces error: %w", err)
+		}
+	}
+	wsInfos, err := mgr.listWorkspaceInfos(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list workspaces error: %w", err)
+	}
+
+	ret := make([]SpaceUsage, 0, len(spaceIDs))
+	for _, spaceID := range spaceIDs {
+		id, err := uid.DecodeUID128FromBase32(spaceID)
+		if err != nil {
+			return nil, fmt.Errorf("parse space id %q error: %w", spaceID, err)
+		}
+		// 空间数据总是原子地写入，只读取不需要锁定
+		space := mgr.newSpace(id)
+		if err := space.Load(ctx); err != nil {
+			if spaceRef != "" {
+				return nil, fmt.Errorf("load space %s error: %w", spaceID, err)
+			}
+			logger.Info(fmt.Sprintf("WARN load space %s error: %v", spaceID, err))
+			continue
+		}
+		ret = append(ret, *mgr.spaceUsage(ctx, space, wsInfos))
+	}
+	return ret, nil
+}
+
+// spaceUsage 统计空间占用的空间
+func (mgr *defaultManager) spaceUsage(ctx context.Context, space spaces.Space, wsInfos []*WorkspaceInfo) *SpaceUsage {
+	logger := logr.FromContextOrDiscard(ctx).WithName(loggerName)
+
+	spaceID := space.ID().Base32()
+	ret := &SpaceUsage{
+		ID:   spaceID,
+		Name: space.Meta().Name,
+	}
+
+	// 各层
+	sizes := map[string]int64{}
+	trees.Walk(space.Tree().Root(), func(node trees.Node) {
+		usage, err := mgr.layerUsage(ctx, node)
+		if err != nil {
+			logger.Info(fmt.Sprintf("WARN get disk usage of layer %s error: %v", node.ID().Hex(), err))
+		}
+		ret.TotalBytes += usage.Bytes
+		if !workspaces.IsCommitted(node) {
+			ret.UncommittedBytes += usage.Bytes
+			return
+		}
+		sizes[node.ID().Hex()] = usage.Bytes
+		commit := CommitUsage{
+			ID:      node.ID().Hex(),
+			Subject: commitSubject(workspaces.CommitMessage(node)),
+			Usage:   usage,
+		}
+		if node.Parent() != nil {
+			commit.Parent = node.Parent().ID().Hex()
+		}
+		ret.Commits = append(ret.Commits, commit)
+	})
+	flattened, err := space.ListFlattened(ctx)
+	if err != nil {
+		logger.Info(fmt.Sprintf("WARN list flattened layers of space %s error: %v", spaceID, err))
+	}
+	for _, l := range flattened {
+		usage, err := l.Usage()
+		if err != nil {
+			logger.Info(fmt.Sprintf("WARN get disk usage of flattened layer %s error: %v", l.ID().Hex(), err))
+		}
+		ret.FlattenedBytes += usage.Bytes
+	}
+	ret.TotalBytes += ret.FlattenedBytes
+
+	// 引用：分支、标签和分离头指针状态的工作空间头指针
+	type ref struct {
+		branch *SpaceBranch
+		node   trees.Node
+	}
+	wsPaths := map[string]string{}
+	var refs []ref
+	for _, info := range wsInfos {
+		if info.SpaceID != spaceID || mgr.workspaceStaleReason(info) != "" {
+			continue
+		}
+		wsPaths[info.ID] = info.Path
+		if info.Branch != "" {
+			continue
+		}
+		if head, err := uid.DecodeUID128FromHex(info.Head); err == nil {
+			if node, ok := space.Tree().Get(head); ok && node.Parent() != nil {
+				refs = append(refs, ref{node: node.Parent()})
+			}
+		}
+	}
+	for _, node := range space.Tree().Tags() {
+		refs = append(refs, ref{node: node})
+	}
+	for fullName, node := range space.Tree().Branches() {
+		b, err := workspaces.ParseBranchFullName(fullName)
+		if err != nil {
+			continue
+		}
+		branch := &SpaceBranch{Name: b.LocalName(), Commit: node.ID().Hex()}
+		if b.IsLocal() {
+			branch.Workspace = wsPaths[b.WorkspaceID().Base32()]
+		}
+		refs = append(refs, ref{branch: branch, node: node})
+	}
+
+	// 统计每个提交可以从多少个引用访问到
+	refCounts := map[string]int{}
+	for _, r := range refs {
+		for cur := r.node; cur != nil; cur = cur.Parent() {
+			refCounts[cur.ID().Hex()]++
+		}
+	}
+	for _, r := range refs {
+		if r.branch == nil {
+			continue
+		}
+		usage := BranchUsage{SpaceBranch: *r.branch}
+		for cur := r.node; cur != nil; cur = cur.Parent() {
+			size := sizes[cur.ID().Hex()]
+			usage.TotalBytes += size
+			if refCounts[cur.ID().Hex()] == 1 {
+				usage.ExclusiveBytes += size
+			} else {
+				usage.SharedBytes += size
+			}
+		}
+		ret.Branches = append(ret.Branches, usage)
+	}
+	sort.Slice(ret.Branches, func(i, j int) bool {
+		if ret.Branches[i].Name != ret.Branches[j].Name {
+			return ret.Branches[i].Name < ret.Branches[j].Name
+		}
+		return ret.Branches[i].Workspace < ret.Branches[j].Workspace
+	})
+
+	return ret
+}
+
+// layerUsage 返回节点对应层占用的空间
+//
+// 已提交的层没有缓存时统计并缓存（比如旧版本提交的层）
+func (mgr *defaultManager) layerUsage(ctx context.Context, node trees.Node) (fsutil.Usage, error) {
+	l, err := mgr.layerManager.Get(ctx, node.ID())
+	if err != nil {
+		return fsutil.Usage{}, err
+	}
+	if !workspaces.IsCommitted(node) {
+		return fsutil.GetUsage(l.DiffDir())
+	}
+	if usage, ok := l.RecordedUsage(); ok {
+		return usage, nil
+	}
+	if mgr.skipLocks {
+		// 没有修改数据的权限
+		return l.Usage()
+	}
+	return l.RecordUsage()
+}
+
+// recordLayerInfo 统计并缓存已提交的层占用的空间和其中的不透明目录，失败时只打印警告
+//
+// 记录不透明目录后，非 root 用户执行的 archive 、 diff 等命令也可以识别不透明目录
+func (mgr *defaultManager) recordLayerInfo(ctx context.Context, id uid.UID) {
+	logger := logr.FromContextOrDiscard(ctx).WithName(loggerName)
+
+	l, err := mgr.layerManager.Get(ctx, id)
+	if err != nil {
+		logger.Info(fmt.Sprintf("WARN get layer %s error: %v", id.Hex(), err))
+		return
+	}
+	if _, err := l.RecordUsage(); err != nil {
+		logger.Info(fmt.Sprintf("WARN record disk usage of layer %s error: %v", id.Hex(), err))
+	}
+	opaqueDirs, err := changes.FindOpaqueDirs(l.DiffDir())
+	if err == nil {
+		err = l.RecordOpaqueDirs(opaqueDirs)
+	}
+	if err != nil {
+		logger.Info(fmt.Sprintf("WARN record opaque directories of layer %s error: %v", id.Hex(), err))
+	}
+}
@@ -0,0 +1,34 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/yhlooo/stackcrisp/pkg/workspaces"
+)
+
+// Flatten 将从根节点到指定提交的所有层合并为一个扁平化的基础层
+func (mgr *defaultManager) Flatten(
+	ctx context.Context,
+	ws workspaces.Workspace,
+	revision string,
+) (*FlattenResult, error) {
+	node, _, err := ws.Resolve(revision)
+	if err != nil {
+		return nil, err
+	}
+	if !workspaces.IsCommitted(node) {
+		return nil, fmt.Errorf("%q is not committed", revision)
+	}
+	layerSet, err := ws.Space().GetLayers(ctx, node.ID())
+	if err != nil {
+		return nil, fmt.Errorf("get layers of %q error: %w", node.ID().Hex(), err)
+	}
+	if _, err := ws.Space().Flatten(ctx, node.ID()); err != nil {
+		return nil, err
+	}
+	return &FlattenResult{
+		Commit: node.ID().Hex(),
+		Layers: len(layerSet),
+	}, nil
+}
@@ -0,0 +1,596 @@
+package manager
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-logr/logr"
+
+	"github.com/yhlooo/stackcrisp/pkg/layers"
+	"github.com/yhlooo/stackcrisp/pkg/mounts"
+	"github.com/yhlooo/stackcrisp/pkg/spaces"
+	"github.com/yhlooo/stackcrisp/pkg/spaces/trees"
+	fsutil "github.com/yhlooo/stackcrisp/pkg/utils/fs"
+	"github.com/yhlooo/stackcrisp/pkg/utils/uid"
+	"github.com/yhlooo/stackcrisp/pkg/workspaces"
+)
+
+const (
+	workspaceInfoFileSuffix = ".workspace"
+	mountDataSubPathMerged  = "merged"
+	mountDataSubPathWork    = "work"
+)
+
+// GC 回收不再被使用的层和失效的工作空间挂载
+//
+// 以下内容会被回收：
+//   - 路径已经不再指向其挂载的工作空间，以及没有工作空间信息的挂载数据
+//   - 空间中没有被提交、没有被任何工作空间使用、也没有被分支或标签引用的叶子节点（通常是切换后被丢弃的 upper 层）
+//   - 不属于任何空间的层
+//   - 没有被工作空间的挂载使用、也不会在工作空间下次挂载时使用的扁平化基础层
+//
+// 此外，工作空间头指针之上叠加的已提交层数超过阈值时，提前将其合并为扁平化基础层，避免下次挂载时等待合并
+func (mgr *defaultManager) GC(ctx context.Context, opts GCOptions) (*GCResult, error) {
+	logger := logr.FromContextOrDiscard(ctx).WithName(loggerName)
+	result := &GCResult{}
+
+	// 等待正在进行的操作完成，并阻止新的操作开始
+	if err := mgr.lockGC(ctx, true); err != nil {
+		return nil, err
+	}
+
+	// 回收失效的工作空间挂载
+	wsInfos, err := mgr.listWorkspaceInfos(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list workspaces error: %w", err)
+	}
+	knownMounts := map[string]bool{}
+	var liveInfos []*WorkspaceInfo
+	for _, info := range wsInfos {
+		knownMounts[info.MountID] = true
+		reason := mgr.workspaceStaleReason(info)
+		if reason == "" {
+			liveInfos = append(liveInfos, info)
+			continue
+		}
+		logger.Info(fmt.Sprintf("mount %s of workspace %q is stale: %s", info.MountID, info.Path, reason))
+		size, err := mgr.reclaimMount(ctx, info.MountID, reclaimOptions{DryRun: opts.DryRun, Lazy: opts.Force})
+		if err != nil {
+			logger.Info(fmt.Sprintf("WARN reclaim mount %s error: %v", info.MountID, err))
+			liveInfos = append(liveInfos, info)
+			continue
+		}
+		result.Mounts = append(result.Mounts, info.MountID)
+		result.ReclaimedBytes += size
+	}
+
+	// 回收没有工作空间信息的挂载数据
+	mountsDataRoot := filepath.Join(mgr.dataRoot, managerDataSubPathMounts)
+	entries, err := os.ReadDir(mountsDataRoot)
+	if err != nil {
+		return nil, fmt.Errorf("read mounts data root dir %q error: %w", mountsDataRoot, err)
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() || knownMounts[entry.Name()] {
+			continue
+		}
+		if _, err := uid.DecodeUID128FromBase32(entry.Name()); err != nil {
+			logger.Info(fmt.Sprintf(
+				"WARN unexpected dir in mounts data root: %q", filepath.Join(mountsDataRoot, entry.Name()),
+			))
+			continue
+		}
+		logger.Info(fmt.Sprintf("mount %s has no workspace", entry.Name()))
+		size, err := mgr.reclaimMount(ctx, entry.Name(), reclaimOptions{DryRun: opts.DryRun, Lazy: opts.Force})
+		if err != nil {
+			logger.Info(fmt.Sprintf("WARN reclaim mount %s error: %v", entry.Name(), err))
+			continue
+		}
+		result.Mounts = append(result.Mounts, entry.Name())
+		result.ReclaimedBytes += size
+	}
+
+	// 回收各空间中被丢弃的节点
+	spaceIDs, err := mgr.listSpaceIDs(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list spaces error: %w", err)
+	}
+	liveHeads := map[string]bool{}
+	for _, info := range liveInfos {
+		liveHeads[info.Head] = true
+	}
+	usedLayers := map[string]bool{}
+	allSpacesLoaded := true
+	for _, spaceID := range spaceIDs {
+		space, err := mgr.loadSpace(ctx, spaceID)
+		if err != nil {
+			logger.Info(fmt.Sprintf("WARN load space %s error: %v", spaceID, err))
+			allSpacesLoaded = false
+			continue
+		}
+
+		// 被分支和标签引用的节点
+		refs := map[string]bool{}
+		for _, node := range space.Tree().Branches() {
+			refs[node.ID().Hex()] = true
+		}
+		for _, node := range space.Tree().Tags() {
+			refs[node.ID().Hex()] = true
+		}
+
+		var garbage []trees.Node
+		trees.Walk(space.Tree().Root(), func(node trees.Node) {
+			usedLayers[node.ID().Hex()] = true
+			if node.IsLeaf() && !workspaces.IsCommitted(node) && !liveHeads[node.ID().Hex()] && !refs[node.ID().Hex()] {
+				garbage = append(garbage, node)
+			}
+		})
+
+		// 合并工作空间叠加太多层的头指针
+		for _, id := range mgr.flattenLiveHeads(ctx, space, liveInfos, opts.DryRun) {
+			result.Flattened = append(result.Flattened, id)
+		}
+
+		// 回收不再被工作空间使用的扁平化基础层
+		flattened, err := space.ListFlattened(ctx)
+		if err != nil {
+			logger.Info(fmt.Sprintf("WARN list flattened layers of space %s error: %v", spaceID, err))
+		}
+		usedFlattened := mgr.usedFlattenedLayers(ctx, space, flattened, liveInfos)
+		for _, l := range flattened {
+			if _, ok := space.Tree().Get(l.ID()); ok && usedFlattened[l.ID().Hex()] {
+				continue
+			}
+			size, err := mgr.reclaimFlattenedLayer(ctx, space, l, opts.DryRun)
+			if err != nil {
+				logger.Info(fmt.Sprintf("WARN reclaim flattened layer %s error: %v", l.ID().Hex(), err))
+				continue
+			}
+			result.FlattenedLayers = append(result.FlattenedLayers, l.ID().Hex())
+			result.ReclaimedBytes += size
+		}
+
+		if len(garbage) == 0 {
+			continue
+		}
+
+		if !opts.DryRun {
+			for _, node := range garbage {
+				logger.V(1).Info(fmt.Sprintf("delete node %s from space %s", node.ID().Hex(), spaceID))
+				space.Tree().DeleteNode(node.ID())
+			}
+			logger.Info(fmt.Sprintf("saving space %s ...", space.ID()))
+			if err := space.Save(ctx); err != nil {
+				return result, fmt.Errorf("save space error: %w", err)
+			}
+		}
+		for _, node := range garbage {
+			size, err := mgr.reclaimLayer(ctx, node.ID(), opts.DryRun)
+			if err != nil {
+				logger.Info(fmt.Sprintf("WARN reclaim layer %s error: %v", node.ID().Hex(), err))
+				continue
+			}
+			result.Layers = append(result.Layers, node.ID().Hex())
+			result.ReclaimedBytes += size
+		}
+	}
+
+	// 回收不属于任何空间的层
+	if !allSpacesLoaded {
+		// 无法确定层是否被使用
+		logger.Info("WARN skip reclaiming orphaned layers because some spaces can not be loaded")
+		return result, nil
+	}
+	allLayers, err := mgr.layerManager.List(ctx)
+	if err != nil {
+		return result, fmt.Errorf("list layers error: %w", err)
+	}
+	for _, l := range allLayers {
+		if usedLayers[l.ID().Hex()] {
+			continue
+		}
+		logger.Info(fmt.Sprintf("layer %s does not belong to any space", l.ID().Hex()))
+		size, err := mgr.reclaimLayer(ctx, l.ID(), opts.DryRun)
+		if err != nil {
+			logger.Info(fmt.Sprintf("WARN reclaim layer %s error: %v", l.ID().Hex(), err))
+			continue
+		}
+		result.Layers = append(result.Layers, l.ID().Hex())
+		result.ReclaimedBytes += size
+	}
+
+	return result, nil
+}
+
+// workspaceStaleReason 返回工作空间失效的原因，没有失效则返回空字符串
+func (mgr *defaultManager) workspaceStaleReason(info *WorkspaceInfo) string {
+	if !fsutil.IsDir(filepath.Join(mgr.dataRoot, managerDataSubPathSpaces, info.SpaceID)) {
+		return fmt.Sprintf("space %s not found", info.SpaceID)
+	}
+	mountDataRoot := filepath.Join(mgr.dataRoot, managerDataSubPathMounts, info.MountID)
+	if !fsutil.IsDir(mountDataRoot) {
+		return "mount data not found"
+	}
+	// 旧版本工作空间路径是链接到挂载点的软链
+	if fsutil.IsSymlink(info.Path) {
+		target, err := os.Readlink(info.Path)
+		if err != nil {
+			return fmt.Sprintf("read link %q error: %v", info.Path, err)
+		}
+		if filepath.Clean(target) != filepath.Join(mountDataRoot, mountDataSubPathMerged) {
+			return fmt.Sprintf("%q links to %q", info.Path, target)
+		}
+		return ""
+	}
+	if !fsutil.IsDir(info.Path) {
+		return fmt.Sprintf("%q is not a directory", info.Path)
+	}
+	// 路径上没有挂载时（比如重启后）仍然可以重新挂载，不是失效的
+	mounted, err := mounts.IsMounted(info.Path)
+	if err != nil {
+		return fmt.Sprintf("check mount point %q error: %v", info.Path, err)
+	}
+	if !mounted {
+		return ""
+	}
+	bound, err := mgr.isWorkspaceBound(info)
+	if err != nil {
+		return err.Error()
+	}
+	if !bound {
+		return fmt.Sprintf("another mount is on %q", info.Path)
+	}
+	return ""
+}
+
+// reclaimOptions 回收挂载的选项
+type reclaimOptions struct {
+	// 仅统计可以回收的空间大小，不实际删除
+	DryRun bool
+	// 不检查挂载是否被占用，直接延迟卸载
+	Lazy bool
+}
+
+// reclaimMount 卸载并删除挂载数据，返回回收的空间大小
+//
+// 挂载被占用且不是延迟卸载时返回 *mounts.BusyError ，不会删除任何数据
+func (mgr *defaultManager) reclaimMount(ctx context.Context, mountID string, opts reclaimOptions) (int64, error) {
+	logger := logr.FromContextOrDiscard(ctx).WithName(loggerName)
+
+	id, err := uid.DecodeUID128FromBase32(mountID)
+	if err != nil {
+		return 0, fmt.Errorf("parse mount id %q error: %w", mountID, err)
+	}
+	mountDataRoot := filepath.Join(mgr.dataRoot, managerDataSubPathMounts, mountID)
+
+	// 挂载点中是层的内容，在回收层时统计，这里只统计工作目录
+	size, err := fsutil.DiskUsage(filepath.Join(mountDataRoot, mountDataSubPathWork))
+	if err != nil && !os.IsNotExist(err) {
+		return 0, fmt.Errorf("get disk usage of mount %s error: %w", mountID, err)
+	}
+	if opts.DryRun {
+		return size, nil
+	}
+
+	// 卸载
+	mount := mounts.NewMountedMount(id, mounts.MountOptions{
+		MountDataRoot: mountDataRoot,
+		ChownUID:      mgr.chownUID,
+		ChownGID:      mgr.chownGID,
+	})
+	boundPath, err := mgr.boundWorkspacePath(ctx, mountID)
+	if err != nil {
+		return 0, err
+	}
+	mounted, err := mounts.IsMounted(mount.MountPath())
+	if err != nil {
+		return 0, fmt.Errorf("check mount point %q error: %w", mount.MountPath(), err)
+	}
+	if mounted && opts.Lazy {
+		if boundPath != "" {
+			if err := mounts.UmountPath(ctx, boundPath, true); err != nil {
+				return 0, fmt.Errorf("umount %q error: %w", boundPath, err)
+			}
+		}
+		if err := mount.Detach(ctx); err != nil {
+			return 0, fmt.Errorf("umount %q error: %w", mount.MountPath(), err)
+		}
+	}
+	if mounted && !opts.Lazy {
+		if err := mgr.checkMountNotBusy(ctx, mountID); err != nil {
+			return 0, err
+		}
+		// 先移动到根目录，避免当前进程占用挂载点
+		pwd, err := os.Getwd()
+		if err != nil {
+			return 0, fmt.Errorf("get pwd error: %w", err)
+		}
+		if err := os.Chdir("/"); err != nil {
+			return 0, fmt.Errorf("change working directory to \"/\" error: %w", err)
+		}
+		umountErr := func() error {
+			if boundPath != "" {
+				if err := mounts.UmountPath(ctx, boundPath, false); err != nil {
+					return fmt.Errorf("umount %q error: %w", boundPath, err)
+				}
+			}
+			if err := mount.Umount(ctx); err != nil {
+				return fmt.Errorf("umount %q error: %w", mount.MountPath(), err)
+			}
+			return nil
+		}()
+		// 移动回去，原目录可能正是被卸载的挂载点，此时不再移动回去
+		if err := os.Chdir(pwd); err != nil {
+			logger.V(1).Info(fmt.Sprintf("change working directory to %q error: %v", pwd, err))
+		}
+		if umountErr != nil {
+			return 0, umountErr
+		}
+	}
+
+	// 删除
+	if err := mgr.removeMountData(ctx, mountID); err != nil {
+		return 0, err
+	}
+	logger.Info(fmt.Sprintf("removed mount %s", mountID))
+	return size, nil
+}
+
+// checkMountNotBusy 检查挂载是否被除当前进程外的进程占用，被占用时返回 *mounts.BusyError
+func (mgr *defaultManager) checkMountNotBusy(ctx context.Context, mountID string) error {
+	mergedPath := filepath.Join(mgr.dataRoot, managerDataSubPathMounts, mountID, mountDataSubPathMerged)
+	mounted, err := mounts.IsMounted(mergedPath)
+	if err != nil {
+		return fmt.Errorf("check mount point %q error: %w", mergedPath, err)
+	}
+	if !mounted {
+		return nil
+	}
+	paths := []string{mergedPath}
+	busyPath := mergedPath
+	boundPath, err := mgr.boundWorkspacePath(ctx, mountID)
+	if err != nil {
+		return err
+	}
+	if boundPath != "" {
+		paths = append(paths, boundPath)
+		busyPath = boundPath
+	}
+	holders, err := mounts.FindHolders(paths...)
+	if err != nil {
+		return fmt.Errorf("find processes using %q error: %w", busyPath, err)
+	}
+	if len(holders) > 0 {
+		return &mounts.BusyError{Path: busyPath, Holders: holders}
+	}
+	return nil
+}
+
+// boundWorkspacePath 返回挂载绑定在的工作空间路径，没有绑定（或者已经被其它挂载替换）时返回空
+func (mgr *defaultManager) boundWorkspacePath(ctx context.Context, mountID string) (string, error) {
+	id, err := uid.DecodeUID128FromBase32(mountID)
+	if err != nil {
+		return "", fmt.Errorf("parse mount id %q error: %w", mountID, err)
+	}
+	info, err := mgr.loadWorkspaceInfo(ctx, id)
+	if err != nil {
+		// 没有工作空间信息
+		return "", nil
+	}
+	bound, err := mgr.isWorkspaceBound(info)
+	if err != nil || !bound {
+		return "", err
+	}
+	return info.Path, nil
+}
+
+// reclaimLayer 删除层，返回回收的空间大小
+func (mgr *defaultManager) reclaimLayer(ctx context.Context, id uid.UID, dryRun bool) (int64, error) {
+	logger := logr.FromContextOrDiscard(ctx).WithName(loggerName)
+
+	l, err := mgr.layerManager.Get(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+	usage, err := l.Usage()
+	if err != nil && !os.IsNotExist(err) {
+		return 0, fmt.Errorf("get disk usage of layer %s error: %w", id.Hex(), err)
+	}
+	size := usage.Bytes
+	if dryRun {
+		return size, nil
+	}
+	if _, err := mgr.layerManager.Delete(ctx, id); err != nil {
+		return 0, err
+	}
+	logger.Info(fmt.Sprintf("removed layer %s", id.Hex()))
+	return size, nil
+}
+
+// flattenLiveHeads 将空间中叠加的已提交层数超过阈值的工作空间头指针合并为扁平化基础层，返回合并的节点 ID
+func (mgr *defaultManager) flattenLiveHeads(
+	ctx context.Context,
+	space spaces.Space,
+	liveInfos []*WorkspaceInfo,
+	dryRun bool,
+) []string {
+	logger := logr.FromContextOrDiscard(ctx).WithName(loggerName)
+
+	var ret []string
+	seen := map[string]bool{}
+	for _, info := range liveInfos {
+		if info.SpaceID != space.ID().Base32() {
+			continue
+		}
+		id, err := uid.DecodeUID128FromHex(info.Head)
+		if err != nil {
+			continue
+		}
+		upper, ok := space.Tree().Get(id)
+		if !ok || upper.Parent() == nil || seen[upper.Parent().ID().Hex()] {
+			continue
+		}
+		head := upper.Parent()
+		seen[head.ID().Hex()] = true
+		needed, err := space.NeedsFlatten(ctx, head.ID())
+		if err != nil {
+			logger.Info(fmt.Sprintf("WARN check layers of %s error: %v", head.ID().Hex(), err))
+			continue
+		}
+		if !needed {
+			continue
+		}
+		if !dryRun {
+			if _, err := space.Flatten(ctx, head.ID()); err != nil {
+				logger.Info(fmt.Sprintf("WARN flatten layers up to %s error: %v", head.ID().Hex(), err))
+				continue
+			}
+		}
+		ret = append(ret, head.ID().Hex())
+	}
+	return ret
+}
+
+// usedFlattenedLayers 返回空间中仍被工作空间使用的扁平化基础层对应的节点 ID
+//
+// 包括工作空间当前挂载叠加的基础层，以及头指针的最深的已缓存的祖先基础层（下次挂载时使用）。
+// 挂载没有记录叠加的层时，头指针的所有祖先基础层都视为被使用
+func (mgr *defaultManager) usedFlattenedLayers(
+	ctx context.Context,
+	space spaces.Space,
+	flattened []layers.Layer,
+	liveInfos []*WorkspaceInfo,
+) map[string]bool {
+	logger := logr.FromContextOrDiscard(ctx).WithName(loggerName)
+
+	byID := map[string]layers.Layer{}
+	for _, l := range flattened {
+		// 被中断的合并只留下临时目录，不会被使用
+		if fsutil.IsDir(l.DiffDir()) {
+			byID[l.ID().Hex()] = l
+		}
+	}
+
+	used := map[string]bool{}
+	for _, info := range liveInfos {
+		if info.SpaceID != space.ID().Base32() {
+			continue
+		}
+
+		// 当前挂载叠加的基础层
+		lowerDirs, err := mounts.ReadLowerDirs(filepath.Join(mgr.dataRoot, managerDataSubPathMounts, info.MountID))
+		if err != nil {
+			logger.V(1).Info(fmt.Sprintf("read lower dirs of mount %s error: %v", info.MountID, err))
+		}
+		for _, dir := range lowerDirs {
+			for id, l := range byID {
+				if l.DiffDir() == dir {
+					used[id] = true
+				}
+			}
+		}
+
+		// 下次挂载时使用的基础层
+		id, err := uid.DecodeUID128FromHex(info.Head)
+		if err != nil {
+			continue
+		}
+		upper, ok := space.Tree().Get(id)
+		if !ok {
+			continue
+		}
+		for node := upper.Parent(); node != nil; node = node.Parent() {
+			if _, ok := byID[node.ID().Hex()]; !ok {
+				continue
+			}
+			used[node.ID().Hex()] = true
+			if lowerDirs != nil {
+				break
+			}
+		}
+	}
+	return used
+}
+
+// reclaimFlattenedLayer 删除扁平化基础层，返回回收的空间大小
+func (mgr *defaultManager) reclaimFlattenedLayer(
+	ctx context.Context,
+	space spaces.Space,
+	l layers.Layer,
+	dryRun bool,
+) (int64, error) {
+	logger := logr.FromContextOrDiscard(ctx).WithName(loggerName)
+
+	size, err := fsutil.DiskUsage(l.DiffDir())
+	if err != nil && !os.IsNotExist(err) {
+		return 0, fmt.Errorf("get disk usage of flattened layer %s error: %w", l.ID().Hex(), err)
+	}
+	if dryRun {
+		return size, nil
+	}
+	if err := space.DeleteFlattened(ctx, l.ID()); err != nil {
+		return 0, err
+	}
+	logger.Info(fmt.Sprintf("removed flattened layer %s", l.ID().Hex()))
+	return size, nil
+}
+
+// listWorkspaceInfos 列出所有工作空间信息
+func (mgr *defaultManager) listWorkspaceInfos(ctx context.Context) ([]*WorkspaceInfo, error) {
+	logger := logr.FromContextOrDiscard(ctx).WithName(loggerName)
+
+	mountsDataRoot := filepath.Join(mgr.dataRoot, managerDataSubPathMounts)
+	entries, err := os.ReadDir(mountsDataRoot)
+	if err != nil {
+		return nil, fmt.Errorf("read mounts data root dir %q error: %w", mountsDataRoot, err)
+	}
+
+	var ret []*WorkspaceInfo
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), workspaceInfoFileSuffix) {
+			continue
+		}
+		wsInfoFile := filepath.Join(mountsDataRoot, entry.Name())
+		wsInfoRaw, err := os.ReadFile(wsInfoFile)
+		if err != nil {
+			return nil, fmt.Errorf("read workspace info from file %q error: %w", wsInfoFile, err)
+		}
+		var wsInfo WorkspaceInfo
+		if err := json.Unmarshal(wsInfoRaw, &wsInfo); err != nil {
+			logger.Info(fmt.Sprintf("WARN unmarshal workspace info from file %q error: %v", wsInfoFile, err))
+			continue
+		}
+		ret = append(ret, &wsInfo)
+	}
+	return ret, nil
+}
+
+// listSpaceIDs 列出所有空间 ID
+func (mgr *defaultManager) listSpaceIDs(ctx context.Context) ([]string, error) {
+	logger := logr.FromContextOrDiscard(ctx).WithName(loggerName)
+
+	spacesDataRoot := filepath.Join(mgr.dataRoot, managerDataSubPathSpaces)
+	entries, err := os.ReadDir(spacesDataRoot)
+	if err != nil {
+		return nil, fmt.Errorf("read spaces data root dir %q error: %w", spacesDataRoot, err)
+	}
+
+	var ret []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if _, err := uid.DecodeUID128FromBase32(entry.Name()); err != nil {
+			logger.Info(fmt.Sprintf(
+				"WARN unexpected dir in spaces data root: %q", filepath.Join(spacesDataRoot, entry.Name()),
+			))
+			continue
+		}
+		ret = append(ret, entry.Name())
+	}
+	return ret, nil
+}
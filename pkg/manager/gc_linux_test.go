@@ -0,0 +1,59 @@
+//go:build linux
+
+package manager
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/yhlooo/stackcrisp/pkg/mounts"
+)
+
+// TestGCFlattenedLayers 测试挂载时合并深处的祖先层，以及 gc 回收不再被使用的扁平化基础层
+func TestGCFlattenedLayers(t *testing.T) {
+	ctx := context.Background()
+	mgr, root := newTestManager(t)
+	mgr.flattenThreshold = 2
+
+	ws := createTestWorkspace(t, mgr, filepath.Join(root, "ws"))
+	files := map[string]string{}
+	for i := 0; i < 6; i++ {
+		name := fmt.Sprintf("f%d", i)
+		files[name] = name
+		writeTestFiles(t, ws, map[string]string{name: name})
+		ws = commitTestWorkspace(t, mgr, ws, name)
+	}
+	checkTestFiles(t, ws, files)
+
+	// 挂载时已经合并，旧的基础层不再被使用
+	flattened, err := ws.Space().ListFlattened(ctx)
+	if err != nil {
+		t.Fatalf("list flattened layers error: %v", err)
+	}
+	if len(flattened) < 2 {
+		t.Fatalf("expected at least 2 flattened layers, got %d", len(flattened))
+	}
+	lowerDirs, err := mounts.ReadLowerDirs(filepath.Join(mgr.dataRoot, managerDataSubPathMounts, ws.Mount().ID().Base32()))
+	if err != nil {
+		t.Fatalf("read lower dirs error: %v", err)
+	}
+	base := lowerDirs[len(lowerDirs)-1]
+
+	result, err := mgr.GC(ctx, GCOptions{})
+	if err != nil {
+		t.Fatalf("gc error: %v", err)
+	}
+	if len(result.FlattenedLayers) != len(flattened)-1 {
+		t.Errorf("expected %d flattened layers reclaimed, got %v", len(flattened)-1, result.FlattenedLayers)
+	}
+	flattened, err = ws.Space().ListFlattened(ctx)
+	if err != nil {
+		t.Fatalf("list flattened layers error: %v", err)
+	}
+	if len(flattened) != 1 || flattened[0].DiffDir() != base {
+		t.Errorf("expected only flattened layer %q left, got %v", base, flattened)
+	}
+	checkTestFiles(t, ws, files)
+}
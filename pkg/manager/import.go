@@ -0,0 +1,275 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-logr/logr"
+
+	"github.com/yhlooo/stackcrisp/pkg/changes"
+	"github.com/yhlooo/stackcrisp/pkg/reflogs"
+	"github.com/yhlooo/stackcrisp/pkg/spaces"
+	"github.com/yhlooo/stackcrisp/pkg/spaces/trees"
+	fsutil "github.com/yhlooo/stackcrisp/pkg/utils/fs"
+	"github.com/yhlooo/stackcrisp/pkg/workspaces"
+)
+
+// Import 将 tar 归档或目录中的内容导入为空间中的一个新提交
+func (mgr *defaultManager) Import(
+	ctx context.Context,
+	ws workspaces.Workspace,
+	src ImportSource,
+	opts ImportOptions,
+) (*ImportResult, error) {
+	logger := logr.FromContextOrDiscard(ctx).WithName(loggerName)
+	if (src.Tar == nil) == (src.Dir == "") {
+		return nil, fmt.Errorf("exactly one of tar and dir is required to import")
+	}
+	message := opts.Message
+	if message == "" {
+		message = fmt.Sprintf("import %s", src.Name)
+	}
+	ctx = reflogs.NewContextWithMessage(ctx, fmt.Sprintf("import %s", src.Name))
+
+	target, err := mgr.resolveImportTarget(ctx, ws, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// 创建层
+	node, err := mgr.createImportedLayer(ctx, target.space, target.base, func(diffDir string) error {
+		logger.Info(fmt.Sprintf("writing %s to layer ...", src.Name))
+		if src.Tar != nil {
+			if err := changes.ExtractTar(src.Tar, diffDir); err != nil {
+				return fmt.Errorf("extract %s error: %w", src.Name, err)
+			}
+		} else {
+			if err := fsutil.CopyTree(src.Dir, diffDir); err != nil {
+				return fmt.Errorf("copy %s error: %w", src.Name, err)
+			}
+		}
+		// 基础提交是根节点时导入的内容原样作为新的层
+		if target.base.IsRoot() {
+			return nil
+		}
+		return mgr.makeDiffLayer(ctx, target.space, target.base, diffDir)
+	})
+	if err != nil {
+		return nil, err
+	}
+	workspaces.NewCommitInfo(message).SetToNode(node)
+	logger.Info(fmt.Sprintf("imported %s as %s", src.Name, node.ID().Hex()))
+
+	return mgr.finishImport(ctx, target, []trees.Node{node})
+}
+
+// importTarget 导入的目标位置
+type importTarget struct {
+	space spaces.Space
+	// 导入后指向新提交的分支，没有则为 nil
+	branch workspaces.BranchInfo
+	// 分支当前的头指针，分支不存在时为 nil
+	branchHead trees.Node
+	// 基础提交
+	base trees.Node
+}
+
+// resolveImportTarget 确定导入到的空间、分支和基础提交
+func (mgr *defaultManager) resolveImportTarget(
+	ctx context.Context,
+	ws workspaces.Workspace,
+	opts ImportOptions,
+) (*importTarget, error) {
+	target := &importTarget{}
+
+	// 获取空间
+	if opts.Space != "" {
+		spaceID, err := mgr.resolveSpaceID(ctx, opts.Space)
+		if err != nil {
+			return nil, err
+		}
+		if target.space, err = mgr.loadSpace(ctx, spaceID); err != nil {
+			return nil, err
+		}
+	} else {
+		target.space = ws.Space()
+	}
+
+	// 确定分支
+	if opts.Branch != "" {
+		var err error
+		if target.branch, target.branchHead, err = importBranch(target.space, ws, opts); err != nil {
+			return nil, err
+		}
+	}
+
+	// 确定基础提交
+	switch {
+	case opts.Base != "":
+		var err error
+		if opts.Space != "" {
+			target.base, _, err = workspaces.ResolveInSpace(target.space, opts.Base)
+		} else {
+			target.base, _, err = ws.Resolve(opts.Base)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !workspaces.IsCommitted(target.base) {
+			return nil, fmt.Errorf("%q is not a commit", opts.Base)
+		}
+		if target.branchHead != nil && target.branchHead.ID().Hex() != target.base.ID().Hex() {
+			return nil, fmt.Errorf(
+				"branch %q is at %s, not at the base %s, only a new commit on the branch HEAD can be added to it",
+				target.branch.LocalName(), target.branchHead.ID().Hex(), target.base.ID().Hex(),
+			)
+		}
+	case target.branchHead != nil:
+		target.base = target.branchHead
+	default:
+		target.base = target.space.Tree().Root()
+	}
+
+	return target, nil
+}
+
+// importBranch 确定导入的提交所在分支，返回分支及其当前头指针，分支不存在时头指针为 nil
+//
+// 导入到指定空间时使用全局分支，否则优先使用工作空间中已经存在的本地分支或全局分支，都不存在时创建本地分支
+func importBranch(
+	space spaces.Space,
+	ws workspaces.Workspace,
+	opts ImportOptions,
+) (workspaces.BranchInfo, trees.Node, error) {
+	var candidates []workspaces.BranchInfo
+	if opts.Space != "" {
+		name, _ := strings.CutPrefix(opts.Branch, "origin/")
+		candidates = []workspaces.BranchInfo{workspaces.NewGlobalBranch(name)}
+	} else {
+		candidates = workspaces.ParseBranchLocalName(ws.ID(), opts.Branch)
+	}
+	for _, b := range candidates {
+		head, ok := space.Tree().GetByBranch(b.FullName())
+		if !ok {
+			continue
+		}
+		if ws != nil && opts.Space == "" && b.FullName() == ws.Branch().FullName() {
+			// 工作空间的提交会继续移动该分支，不能移动到其它位置
+			return nil, nil, fmt.Errorf(
+				"branch %q is checked out in the workspace, use another branch and then reset to it",
+				b.LocalName(),
+			)
+		}
+		return b, head, nil
+	}
+	// 带有 origin/ 前缀时创建全局分支
+	return candidates[len(candidates)-1], nil, nil
+}
+
+// createImportedLayer 基于 parent 创建一个新的层，并通过 fill 写入层的 diff 目录
+//
+// 写入失败时删除该层
+func (mgr *defaultManager) createImportedLayer(
+	ctx context.Context,
+	space spaces.Space,
+	parent trees.Node,
+	fill func(diffDir string) error,
+) (trees.Node, error) {
+	node, err := space.CreateLayer(ctx, parent.ID())
+	if err != nil {
+		return nil, err
+	}
+	l, err := mgr.layerManager.Get(ctx, node.ID())
+	if err == nil {
+		err = fill(l.DiffDir())
+	}
+	if err != nil {
+		mgr.discardImported(ctx, space, []trees.Node{node})
+		return nil, err
+	}
+	return node, nil
+}
+
+// makeDiffLayer 将 diffDir 中的完整内容转换为相对于基础提交的差异层
+func (mgr *defaultManager) makeDiffLayer(
+	ctx context.Context,
+	space spaces.Space,
+	base trees.Node,
+	diffDir string,
+) error {
+	logger := logr.FromContextOrDiscard(ctx).WithName(loggerName)
+
+	logger.Info(fmt.Sprintf("computing changes from %s ...", base.ID().Hex()))
+	baseLayers, err := space.GetLayers(ctx, base.ID())
+	if err != nil {
+		return fmt.Errorf("get layers of %q error: %w", base.ID().Hex(), err)
+	}
+	dirs := make([]string, len(baseLayers))
+	for i, l := range baseLayers {
+		dirs[i] = l.DiffDir()
+	}
+	if err := changes.MakeDiffLayer(changes.NewView(dirs), diffDir); err != nil {
+		return fmt.Errorf("compute changes from %s error: %w", base.ID().Hex(), err)
+	}
+	return nil
+}
+
+// discardImported 从树中删除导入的节点并回收其层， nodes 按从祖先到后代的顺序排列
+func (mgr *defaultManager) discardImported(ctx context.Context, space spaces.Space, nodes []trees.Node) {
+	logger := logr.FromContextOrDiscard(ctx).WithName(loggerName)
+	for i := len(nodes) - 1; i >= 0; i-- {
+		space.Tree().DeleteNode(nodes[i].ID())
+		if _, err := mgr.reclaimLayer(ctx, nodes[i].ID(), false); err != nil {
+			logger.Info(fmt.Sprintf("WARN reclaim layer %s error: %v", nodes[i].ID().Hex(), err))
+		}
+	}
+}
+
+// finishImport 将分支移动到最后一个导入的提交，并保存空间
+//
+// nodes 是导入的提交，按从祖先到后代的顺序排列
+func (mgr *defaultManager) finishImport(
+	ctx context.Context,
+	target *importTarget,
+	nodes []trees.Node,
+) (*ImportResult, error) {
+	logger := logr.FromContextOrDiscard(ctx).WithName(loggerName)
+	space := target.space
+	last := nodes[len(nodes)-1]
+
+	// 更新分支头指针
+	if target.branch != nil {
+		var err error
+		if target.branchHead != nil {
+			err = space.Tree().UpdateBranch(target.branch.FullName(), last.ID(), false)
+		} else {
+			err = space.Tree().AddBranch(target.branch.FullName(), last.ID())
+		}
+		if err != nil {
+			return nil, fmt.Errorf("update branch %q error: %w", target.branch.LocalName(), err)
+		}
+	}
+
+	// 记录空间信息
+	logger.Info(fmt.Sprintf("saving space %s ...", space.ID()))
+	if err := space.Save(ctx); err != nil {
+		return nil, fmt.Errorf("save space error: %w", err)
+	}
+
+	ret := &ImportResult{
+		Commit: last.ID().Hex(),
+		Base:   target.base.ID().Hex(),
+	}
+	if target.base.IsRoot() {
+		ret.Base = ""
+	}
+	if target.branch != nil {
+		ret.Branch = target.branch.LocalName()
+	}
+	for _, node := range nodes {
+		mgr.recordLayerInfo(ctx, node.ID())
+		ret.Commits = append(ret.Commits, node.ID().Hex())
+	}
+	return ret, nil
+}
@@ -0,0 +1,428 @@
+package manager
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-logr/logr"
+
+	"github.com/yhlooo/stackcrisp/pkg/mounts"
+	"github.com/yhlooo/stackcrisp/pkg/spaces"
+	fsutil "github.com/yhlooo/stackcrisp/pkg/utils/fs"
+	"github.com/yhlooo/stackcrisp/pkg/utils/uid"
+	"github.com/yhlooo/stackcrisp/pkg/workspaces"
+)
+
+const (
+	managerDataSubPathJournal = "journal"
+	intentFileSuffix          = ".json"
+)
+
+// intentPhase 操作进行到的阶段
+type intentPhase string
+
+// intentPhase 的合法值
+const (
+	// intentPhaseStarted 已经开始修改数据，但新工作空间还没有准备好，中断后需要回滚
+	intentPhaseStarted intentPhase = "Started"
+	// intentPhasePrepared 新工作空间信息已经保存，中断后继续展开新工作空间
+	intentPhasePrepared intentPhase = "Prepared"
+	// intentPhaseExpanded 新工作空间已经展开，中断后继续回收旧工作空间挂载
+	intentPhaseExpanded intentPhase = "Expanded"
+)
+
+// intentOperation 记录意图的操作
+type intentOperation string
+
+// intentOperation 的合法值
+const (
+	intentOperationInit     intentOperation = "init"
+	intentOperationCommit   intentOperation = "commit"
+	intentOperationCheckout intentOperation = "checkout"
+	intentOperationReset    intentOperation = "reset"
+	intentOperationClone    intentOperation = "clone"
+)
+
+// intent 记录在预写日志中的一次操作意图
+//
+// 每个会创建新工作空间挂载的操作（ init 、 commit 、 checkout 、 reset 、 clone 等）在修改持久化数据前记录意图，
+// 随着操作推进更新阶段，完成后删除。进程在中途被终止时，下次运行根据阶段回滚或者完成该操作。
+type intent struct {
+	// 操作名
+	Operation intentOperation `json:"operation"`
+	// 工作空间 ID
+	WorkspaceID string `json:"workspaceID,omitempty"`
+	// 工作空间路径
+	Path string `json:"path"`
+	// 空间 ID
+	SpaceID string `json:"spaceID"`
+	// 空间是否由该操作创建
+	NewSpace bool `json:"newSpace,omitempty"`
+	// 操作开始前空间持久化数据的快照
+	SpaceSnapshot []byte `json:"spaceSnapshot,omitempty"`
+	// 新工作空间挂载 ID
+	NewMountID string `json:"newMountID"`
+	// 被替换的旧工作空间挂载 ID ，没有则为空
+	OldMountID string `json:"oldMountID,omitempty"`
+	// 阶段
+	Phase intentPhase `json:"phase"`
+}
+
+// beginIntent 在修改空间持久化数据前记录操作意图
+//
+// 需要在创建新挂载后、第一次保存空间前调用
+func (mgr *defaultManager) beginIntent(
+	ctx context.Context,
+	operation intentOperation,
+	wsID uid.UID,
+	path string,
+	space spaces.Space,
+	newSpace bool,
+	newMountID uid.UID,
+	replaced workspaces.Workspace,
+) error {
+	in := &intent{
+		Operation:   operation,
+		WorkspaceID: wsID.Base32(),
+		Path:        path,
+		SpaceID:     space.ID().Base32(),
+		NewSpace:    newSpace,
+		NewMountID:  newMountID.Base32(),
+		Phase:       intentPhaseStarted,
+	}
+	if !newSpace {
+		snapshot, err := space.Snapshot()
+		if err != nil {
+			return fmt.Errorf("get snapshot of space error: %w", err)
+		}
+		in.SpaceSnapshot = snapshot
+	}
+	if replaced != nil {
+		in.OldMountID = replaced.Mount().ID().Base32()
+	}
+	if err := mgr.saveIntent(ctx, in); err != nil {
+		return err
+	}
+	mgr.setIntentStarted(in.NewMountID, true)
+	return nil
+}
+
+// advanceIntent 更新操作进行到的阶段
+func (mgr *defaultManager) advanceIntent(ctx context.Context, newMountID uid.UID, phase intentPhase) error {
+	in, err := mgr.loadIntent(newMountID.Base32())
+	if err != nil {
+		return err
+	}
+	in.Phase = phase
+	if err := mgr.saveIntent(ctx, in); err != nil {
+		return err
+	}
+	mgr.setIntentStarted(in.NewMountID, phase == intentPhaseStarted)
+	return nil
+}
+
+// finishIntent 操作完成，删除意图
+func (mgr *defaultManager) finishIntent(ctx context.Context, newMountID string) error {
+	logger := logr.FromContextOrDiscard(ctx).WithName(loggerName)
+
+	intentFile := mgr.intentFilePath(newMountID)
+	logger.V(1).Info(fmt.Sprintf("rm %q", intentFile))
+	if err := os.Remove(intentFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("remove intent %q error: %w", intentFile, err)
+	}
+	mgr.setIntentStarted(newMountID, false)
+	return nil
+}
+
+// setIntentStarted 记录本进程中的操作是否处于 Started 阶段
+func (mgr *defaultManager) setIntentStarted(newMountID string, started bool) {
+	mgr.startedIntentsLock.Lock()
+	defer mgr.startedIntentsLock.Unlock()
+	if !started {
+		delete(mgr.startedIntents, newMountID)
+		return
+	}
+	if mgr.startedIntents == nil {
+		mgr.startedIntents = map[string]bool{}
+	}
+	mgr.startedIntents[newMountID] = true
+}
+
+// rollbackStartedIntents 回滚本进程中开始后出错、尚未准备好的操作
+//
+// 需要在释放锁前调用。释放锁后其它进程可能修改空间，再由下次 Recover 恢复快照会覆盖这些修改
+func (mgr *defaultManager) rollbackStartedIntents(ctx context.Context) error {
+	logger := logr.FromContextOrDiscard(ctx).WithName(loggerName)
+
+	mgr.startedIntentsLock.Lock()
+	var ids []string
+	for id := range mgr.startedIntents {
+		ids = append(ids, id)
+	}
+	mgr.startedIntentsLock.Unlock()
+
+	var errs []error
+	for _, id := range ids {
+		in, err := mgr.loadIntent(id)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				mgr.setIntentStarted(id, false)
+				continue
+			}
+			errs = append(errs, err)
+			continue
+		}
+		logger.Info(fmt.Sprintf("WARN rolling back unfinished %s of workspace %q ...", in.Operation, in.Path))
+		if err := mgr.recoverIntent(ctx, in); err != nil {
+			errs = append(errs, fmt.Errorf("roll back %s of workspace %q error: %w", in.Operation, in.Path, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Apply 展开新的工作空间并回收被它替换的旧工作空间挂载，完成一次操作
+func (mgr *defaultManager) Apply(ctx context.Context, ws workspaces.Workspace, replaced workspaces.Workspace) error {
+	logger := logr.FromContextOrDiscard(ctx).WithName(loggerName)
+
+	// 展开 workspace
+	logger.Info("expanding workspace ...")
+	if err := ws.Expand(ctx); err != nil {
+		return fmt.Errorf("expand workspace error: %w", err)
+	}
+	if err := mgr.advanceIntent(ctx, ws.Mount().ID(), intentPhaseExpanded); err != nil {
+		return err
+	}
+
+	// 回收旧的 workspace
+	if replaced != nil {
+		logger.Info("removing old workspace mount ...")
+		if err := mgr.RemoveWorkspaceMount(ctx, replaced, true); err != nil {
+			return fmt.Errorf("remove old workspace mount error: %w", err)
+		}
+		// upper 层被提交时记录其占用的空间。
+		// 旧挂载没有变为只读，仍在其中的进程此后写入的内容会进入已提交的层，但不会被记录
+		if workspaces.IsCommitted(replaced.Head()) {
+			mgr.recordLayerInfo(ctx, replaced.Head().ID())
+		}
+		// 旧挂载被保留给仍在使用它的进程，包括执行命令的 shell
+		if pwd := os.Getenv("PWD"); pwd != "" && isSubPath(ws.Path(), pwd) {
+			logger.Info("WARN current shell is still in the old mount, run \"cd .\" to enter the new one")
+		}
+	}
+
+	return mgr.finishIntent(ctx, ws.Mount().ID().Base32())
+}
+
+// Recover 回滚或者完成上次被中断的操作
+func (mgr *defaultManager) Recover(ctx context.Context) error {
+	journalDir := filepath.Join(mgr.dataRoot, managerDataSubPathJournal)
+	entries, err := os.ReadDir(journalDir)
+	if err != nil {
+		return fmt.Errorf("read journal dir %q error: %w", journalDir, err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), intentFileSuffix) {
+			continue
+		}
+		if err := mgr.recoverOne(ctx, strings.TrimSuffix(entry.Name(), intentFileSuffix)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recoverOne 回滚或者完成指定新挂载对应的操作，完成后释放期间获取的锁
+func (mgr *defaultManager) recoverOne(ctx context.Context, newMountID string) error {
+	logger := logr.FromContextOrDiscard(ctx).WithName(loggerName)
+
+	in, err := mgr.loadIntent(newMountID)
+	if err != nil {
+		return err
+	}
+	if wsID := in.WorkspaceID; wsID != "" {
+		// 操作可能正在另一个进程中进行，等待其完成后重新检查
+		if err := mgr.lockWorkspace(ctx, wsID); err != nil {
+			return err
+		}
+		defer func() { _ = mgr.unlockWorkspace(ctx, wsID) }()
+		if in, err = mgr.loadIntent(newMountID); err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+	}
+	spaceID := in.SpaceID
+	defer func() { _ = mgr.unlockSpace(ctx, spaceID) }()
+
+	logger.Info(fmt.Sprintf(
+		"WARN recovering interrupted %s of workspace %q (phase: %s) ...",
+		in.Operation, in.Path, in.Phase,
+	))
+	if err := mgr.recoverIntent(ctx, in); err != nil {
+		return fmt.Errorf("recover interrupted %s of workspace %q error: %w", in.Operation, in.Path, err)
+	}
+	return nil
+}
+
+// recoverIntent 回滚或者完成指定操作
+func (mgr *defaultManager) recoverIntent(ctx context.Context, in *intent) error {
+	logger := logr.FromContextOrDiscard(ctx).WithName(loggerName)
+
+	switch in.Phase {
+	case intentPhaseStarted:
+		// 回滚空间
+		if err := mgr.lockSpace(ctx, in.SpaceID); err != nil {
+			return err
+		}
+		spaceDataRoot := filepath.Join(mgr.dataRoot, managerDataSubPathSpaces, in.SpaceID)
+		if in.NewSpace {
+			logger.Info(fmt.Sprintf("rolling back: removing space %s ...", in.SpaceID))
+			if err := os.RemoveAll(spaceDataRoot); err != nil {
+				return fmt.Errorf("remove space data error: %w", err)
+			}
+		} else {
+			logger.Info(fmt.Sprintf("rolling back: restoring space %s ...", in.SpaceID))
+			spaceID, err := uid.DecodeUID128FromBase32(in.SpaceID)
+			if err != nil {
+				return fmt.Errorf("parse space id %q error: %w", in.SpaceID, err)
+			}
+			space := mgr.newSpace(spaceID)
+			if err := space.RestoreSnapshot(ctx, in.SpaceSnapshot); err != nil {
+				return fmt.Errorf("restore space error: %w", err)
+			}
+		}
+		// 回收新挂载，新创建的层由 gc 回收
+		logger.Info(fmt.Sprintf("rolling back: removing mount %s ...", in.NewMountID))
+		if _, err := mgr.reclaimMount(ctx, in.NewMountID, reclaimOptions{Lazy: true}); err != nil {
+			return err
+		}
+		return mgr.finishIntent(ctx, in.NewMountID)
+
+	case intentPhasePrepared:
+		// 继续展开新工作空间
+		logger.Info(fmt.Sprintf("rolling forward: expanding workspace %q ...", in.Path))
+		if err := mgr.expandWorkspace(ctx, in.NewMountID); err != nil {
+			return err
+		}
+		in.Phase = intentPhaseExpanded
+		if err := mgr.saveIntent(ctx, in); err != nil {
+			return err
+		}
+		fallthrough
+
+	case intentPhaseExpanded:
+		// 继续回收旧工作空间挂载
+		if in.OldMountID != "" {
+			logger.Info(fmt.Sprintf("rolling forward: removing mount %s ...", in.OldMountID))
+			if _, err := mgr.reclaimMount(ctx, in.OldMountID, reclaimOptions{Lazy: true}); err != nil {
+				return err
+			}
+		}
+		return mgr.finishIntent(ctx, in.NewMountID)
+
+	default:
+		return fmt.Errorf("unknown phase %q", in.Phase)
+	}
+}
+
+// expandWorkspace 根据工作空间信息挂载（如果尚未挂载）并绑定到工作空间路径
+func (mgr *defaultManager) expandWorkspace(ctx context.Context, mountID string) error {
+	logger := logr.FromContextOrDiscard(ctx).WithName(loggerName)
+
+	id, err := uid.DecodeUID128FromBase32(mountID)
+	if err != nil {
+		return fmt.Errorf("parse mount id %q error: %w", mountID, err)
+	}
+	wsInfo, err := mgr.loadWorkspaceInfo(ctx, id)
+	if err != nil {
+		return fmt.Errorf("load workspace info error: %w", err)
+	}
+	mount, err := mgr.openMount(ctx, wsInfo)
+	if err != nil {
+		return err
+	}
+
+	// 挂载
+	mounted, err := mounts.IsMounted(mount.MountPath())
+	if err != nil {
+		return fmt.Errorf("check mount point %q error: %w", mount.MountPath(), err)
+	}
+	if !mounted {
+		logger.Info("mounting ...")
+		if err := mount.Mount(ctx); err != nil {
+			return fmt.Errorf("mount error: %w", err)
+		}
+	}
+
+	// 绑定到工作空间路径，替换其上原有的挂载
+	if err := mount.BindTo(ctx, wsInfo.Path); err != nil {
+		return fmt.Errorf("bind mount point to %q error: %w", wsInfo.Path, err)
+	}
+	return nil
+}
+
+// openMount 根据工作空间信息创建可以挂载的挂载
+func (mgr *defaultManager) openMount(ctx context.Context, wsInfo *WorkspaceInfo) (mounts.Mount, error) {
+	mountID, err := uid.DecodeUID128FromBase32(wsInfo.MountID)
+	if err != nil {
+		return nil, fmt.Errorf("parse mount id %q error: %w", wsInfo.MountID, err)
+	}
+	head, err := uid.DecodeUID128FromHex(wsInfo.Head)
+	if err != nil {
+		return nil, fmt.Errorf("parse workspace head id %q error: %w", wsInfo.Head, err)
+	}
+	space, err := mgr.loadSpace(ctx, wsInfo.SpaceID)
+	if err != nil {
+		return nil, err
+	}
+	layerSet, err := space.GetMountLayers(ctx, head)
+	if err != nil {
+		return nil, fmt.Errorf("get layers of %q error: %w", wsInfo.Head, err)
+	}
+	return mounts.New(ctx, mountID, layerSet, mounts.MountOptions{
+		MountDataRoot: filepath.Join(mgr.dataRoot, managerDataSubPathMounts, wsInfo.MountID),
+		ChownUID:      mgr.chownUID,
+		ChownGID:      mgr.chownGID,
+	})
+}
+
+// saveIntent 保存操作意图
+func (mgr *defaultManager) saveIntent(ctx context.Context, in *intent) error {
+	logger := logr.FromContextOrDiscard(ctx).WithName(loggerName)
+
+	raw, err := json.Marshal(in)
+	if err != nil {
+		return fmt.Errorf("marshal intent to json error: %w", err)
+	}
+	intentFile := mgr.intentFilePath(in.NewMountID)
+	logger.V(1).Info(fmt.Sprintf("write intent (phase: %s) to file %q", in.Phase, intentFile))
+	if err := fsutil.WriteFileAtomic(intentFile, raw, 0644); err != nil {
+		return fmt.Errorf("write intent to file error: %w", err)
+	}
+	return nil
+}
+
+// loadIntent 加载操作意图
+func (mgr *defaultManager) loadIntent(newMountID string) (*intent, error) {
+	intentFile := mgr.intentFilePath(newMountID)
+	raw, err := os.ReadFile(intentFile)
+	if err != nil {
+		return nil, fmt.Errorf("read intent from file %q error: %w", intentFile, err)
+	}
+	var in intent
+	if err := json.Unmarshal(raw, &in); err != nil {
+		return nil, fmt.Errorf("unmarshal intent from json error: %w", err)
+	}
+	return &in, nil
+}
+
+// intentFilePath 返回操作意图文件路径
+func (mgr *defaultManager) intentFilePath(newMountID string) string {
+	return filepath.Join(mgr.dataRoot, managerDataSubPathJournal, newMountID+intentFileSuffix)
+}
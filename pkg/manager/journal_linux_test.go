@@ -0,0 +1,228 @@
+//go:build linux
+
+package manager
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/yhlooo/stackcrisp/pkg/locks"
+	fsutil "github.com/yhlooo/stackcrisp/pkg/utils/fs"
+	"github.com/yhlooo/stackcrisp/pkg/workspaces"
+)
+
+// TestRecover 测试恢复在各阶段中断的提交
+func TestRecover(t *testing.T) {
+	cases := []struct {
+		name  string
+		phase intentPhase
+		// 是否回滚，否则完成操作
+		rolledBack bool
+	}{
+		{name: "Started", phase: intentPhaseStarted, rolledBack: true},
+		{name: "Prepared", phase: intentPhasePrepared, rolledBack: false},
+		{name: "Expanded", phase: intentPhaseExpanded, rolledBack: false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ctx := context.Background()
+			mgr, root := newTestManager(t)
+			dataRoot := filepath.Join(root, "data")
+			path := filepath.Join(root, "ws")
+
+			ws := createTestWorkspace(t, mgr, path)
+			writeTestFiles(t, ws, map[string]string{"a": "1"})
+			ws = commitTestWorkspace(t, mgr, ws, "first")
+			first := ws.Head().Parent()
+			writeTestFiles(t, ws, map[string]string{"b": "2"})
+
+			// 提交，停在指定阶段
+			newWS, err := mgr.Commit(ctx, ws, workspaces.NewCommitInfo("second"))
+			if err != nil {
+				t.Fatalf("commit error: %v", err)
+			}
+			second := newWS.Head().Parent()
+			switch c.phase {
+			case intentPhaseStarted:
+				// 在记录 Prepared 前中断，此时空间和新工作空间信息可能都已经保存
+				in, err := mgr.loadIntent(newWS.Mount().ID().Base32())
+				if err != nil {
+					t.Fatalf("load intent error: %v", err)
+				}
+				in.Phase = intentPhaseStarted
+				if err := mgr.saveIntent(ctx, in); err != nil {
+					t.Fatalf("save intent error: %v", err)
+				}
+			case intentPhaseExpanded:
+				// 展开后、回收旧挂载前中断
+				if err := newWS.Expand(ctx); err != nil {
+					t.Fatalf("expand workspace error: %v", err)
+				}
+				if err := mgr.advanceIntent(ctx, newWS.Mount().ID(), intentPhaseExpanded); err != nil {
+					t.Fatalf("advance intent error: %v", err)
+				}
+			}
+			if err := mgr.Close(ctx); err != nil {
+				t.Fatalf("close manager error: %v", err)
+			}
+
+			// 在新的管理器中恢复
+			mgr = openTestManager(t, dataRoot)
+			if err := mgr.Recover(ctx); err != nil {
+				t.Fatalf("recover error: %v", err)
+			}
+			// 恢复后释放工作空间锁和空间锁，只保留 gc 共享锁
+			if len(mgr.locks) > 1 {
+				t.Errorf("expected locks to be released after recovery, got %d locks", len(mgr.locks))
+			}
+			entries, err := os.ReadDir(filepath.Join(dataRoot, managerDataSubPathJournal))
+			if err != nil {
+				t.Fatalf("read journal dir error: %v", err)
+			}
+			if len(entries) != 0 {
+				t.Errorf("expected journal to be empty, got %d entries", len(entries))
+			}
+
+			// 检查工作空间和空间
+			expectedMount, reclaimedMount, expectedHead := newWS.Mount().ID(), ws.Mount().ID(), second
+			if c.rolledBack {
+				expectedMount, reclaimedMount, expectedHead = ws.Mount().ID(), newWS.Mount().ID(), first
+			}
+			recovered, err := mgr.GetWorkspaceFromPath(ctx, path)
+			if err != nil {
+				t.Fatalf("get workspace from path error: %v", err)
+			}
+			if recovered.Mount().ID().Hex() != expectedMount.Hex() {
+				t.Errorf("expected workspace mount to be %s, got %s", expectedMount, recovered.Mount().ID())
+			}
+			if recovered.Head().Parent().ID().Hex() != expectedHead.ID().Hex() {
+				t.Errorf("expected HEAD to be %s, got %s", expectedHead.ID().Hex(), recovered.Head().Parent().ID().Hex())
+			}
+			branchHead, ok := recovered.Space().Tree().GetByBranch(recovered.Branch().FullName())
+			if !ok || branchHead.ID().Hex() != expectedHead.ID().Hex() {
+				t.Errorf("expected branch main to point to %s, got %v", expectedHead.ID().Hex(), branchHead)
+			}
+			node, ok := recovered.Space().Tree().Get(second.ID())
+			if committed := ok && workspaces.IsCommitted(node); committed == c.rolledBack {
+				t.Errorf("expected %s committed: %t, got %t", second.ID().Hex(), !c.rolledBack, committed)
+			}
+			if fsutil.IsExists(filepath.Join(dataRoot, managerDataSubPathMounts, reclaimedMount.Base32())) {
+				t.Errorf("expected mount %s to be reclaimed", reclaimedMount)
+			}
+			checkTestFiles(t, recovered, map[string]string{"a": "1", "b": "2"})
+		})
+	}
+}
+
+// TestGCAfterRecover 测试恢复中断的操作后 gc 持有数据根目录的排他锁
+func TestGCAfterRecover(t *testing.T) {
+	ctx := context.Background()
+	mgr, root := newTestManager(t)
+	dataRoot := filepath.Join(root, "data")
+
+	// 提交后不展开，留下未完成的操作
+	ws := createTestWorkspace(t, mgr, filepath.Join(root, "ws"))
+	writeTestFiles(t, ws, map[string]string{"a": "1"})
+	if _, err := mgr.Commit(ctx, ws, workspaces.NewCommitInfo("first")); err != nil {
+		t.Fatalf("commit error: %v", err)
+	}
+	if err := mgr.Close(ctx); err != nil {
+		t.Fatalf("close manager error: %v", err)
+	}
+
+	// 恢复时获取了共享锁
+	mgr = openTestManager(t, dataRoot)
+	if err := mgr.Recover(ctx); err != nil {
+		t.Fatalf("recover error: %v", err)
+	}
+	gcLockFile := filepath.Join(dataRoot, managerDataSubPathLocks, locksFileGC)
+	if held, ok := mgr.locks[gcLockFile]; !ok || !held.shared {
+		t.Fatalf("expected a shared gc lock after recovery")
+	}
+
+	if _, err := mgr.GC(ctx, GCOptions{}); err != nil {
+		t.Fatalf("gc error: %v", err)
+	}
+	if held, ok := mgr.locks[gcLockFile]; !ok || held.shared {
+		t.Errorf("expected an exclusive gc lock during gc")
+	}
+	// 其它进程无法获取共享锁
+	err := locks.NewShared(gcLockFile).Lock(ctx, locks.Owner{PID: os.Getpid()}, 100*time.Millisecond)
+	var lockedErr *locks.LockedError
+	if !errors.As(err, &lockedErr) {
+		t.Errorf("expected gc lock to be locked exclusively, got error: %v", err)
+	}
+}
+
+// TestCloseRollsBackStartedIntent 测试出错的操作在释放锁前被回滚，之后其它进程对空间的修改不会被覆盖
+func TestCloseRollsBackStartedIntent(t *testing.T) {
+	ctx := context.Background()
+	mgr, root := newTestManager(t)
+	dataRoot := filepath.Join(root, "data")
+
+	ws := createTestWorkspace(t, mgr, filepath.Join(root, "ws"))
+	writeTestFiles(t, ws, map[string]string{"a": "1"})
+	ws = commitTestWorkspace(t, mgr, ws, "first")
+	first := ws.Head().Parent()
+	writeTestFiles(t, ws, map[string]string{"b": "2"})
+
+	// 模拟提交保存空间后出错
+	space := ws.Space()
+	mount, head, err := mgr.createMount(ctx, space, ws.Head().ID())
+	if err != nil {
+		t.Fatalf("create mount error: %v", err)
+	}
+	if err := mgr.beginIntent(ctx, intentOperationCommit, ws.ID(), ws.Path(), space, false, mount.ID(), ws); err != nil {
+		t.Fatalf("begin intent error: %v", err)
+	}
+	if err := space.Tree().UpdateBranch(ws.Branch().FullName(), head.Parent().ID(), false); err != nil {
+		t.Fatalf("update branch error: %v", err)
+	}
+	if err := space.Save(ctx); err != nil {
+		t.Fatalf("save space error: %v", err)
+	}
+	if err := mgr.Close(ctx); err != nil {
+		t.Fatalf("close manager error: %v", err)
+	}
+	if _, err := mgr.loadIntent(mount.ID().Base32()); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected intent to be removed, got error: %v", err)
+	}
+	if fsutil.IsExists(filepath.Join(dataRoot, managerDataSubPathMounts, mount.ID().Base32())) {
+		t.Errorf("expected mount %s to be reclaimed", mount.ID())
+	}
+
+	// 其它进程修改空间，之后的恢复不会覆盖修改
+	mgr = openTestManager(t, dataRoot)
+	ws, err = mgr.GetWorkspaceFromPath(ctx, ws.Path())
+	if err != nil {
+		t.Fatalf("get workspace from path error: %v", err)
+	}
+	branchHead, ok := ws.Space().Tree().GetByBranch(ws.Branch().FullName())
+	if !ok || branchHead.ID().Hex() != first.ID().Hex() {
+		t.Fatalf("expected branch main to point to %s, got %v", first.ID().Hex(), branchHead)
+	}
+	if err := ws.Space().Tree().AddTag("v1", first.ID()); err != nil {
+		t.Fatalf("add tag error: %v", err)
+	}
+	if err := ws.Space().Save(ctx); err != nil {
+		t.Fatalf("save space error: %v", err)
+	}
+	if err := mgr.Close(ctx); err != nil {
+		t.Fatalf("close manager error: %v", err)
+	}
+	mgr = openTestManager(t, dataRoot)
+	if err := mgr.Recover(ctx); err != nil {
+		t.Fatalf("recover error: %v", err)
+	}
+	ws, err = mgr.GetWorkspaceFromPath(ctx, ws.Path())
+	if err != nil {
+		t.Fatalf("get workspace from path error: %v", err)
+	}
+	if _, ok := ws.Space().Tree().GetByTag("v1"); !ok {
+		t.Errorf("expected tag v1 to be kept")
+	}
+}
@@ -0,0 +1,94 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/go-logr/logr"
+
+	"github.com/yhlooo/stackcrisp/pkg/spaces"
+	fsutil "github.com/yhlooo/stackcrisp/pkg/utils/fs"
+	"github.com/yhlooo/stackcrisp/pkg/utils/uid"
+)
+
+// ListWorkspaces 列出数据目录中的所有工作空间，按路径排序
+func (mgr *defaultManager) ListWorkspaces(ctx context.Context) ([]WorkspaceStatus, error) {
+	logger := logr.FromContextOrDiscard(ctx).WithName(loggerName)
+
+	infos, err := mgr.listWorkspaceInfos(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list workspaces error: %w", err)
+	}
+
+	// 只读取空间数据，不需要锁定，空间数据总是原子地写入
+	loadedSpaces := map[string]spaces.Space{}
+	getSpace := func(id string) spaces.Space {
+		if space, ok := loadedSpaces[id]; ok {
+			return space
+		}
+		var space spaces.Space
+		if spaceID, err := uid.DecodeUID128FromBase32(id); err == nil {
+			space = mgr.newSpace(spaceID)
+			if err := space.Load(ctx); err != nil {
+				logger.Info(fmt.Sprintf("WARN load space %s error: %v", id, err))
+				space = nil
+			}
+		}
+		loadedSpaces[id] = space
+		return space
+	}
+
+	ret := make([]WorkspaceStatus, 0, len(infos))
+	for _, info := range infos {
+		status := WorkspaceStatus{
+			ID:      info.ID,
+			Path:    info.Path,
+			SpaceID: info.SpaceID,
+			MountID: info.MountID,
+			Branch:  info.Branch,
+		}
+
+		// 挂载状态
+		if reason := mgr.workspaceStaleReason(info); reason != "" {
+			status.State = WorkspaceStale
+			status.StaleReason = reason
+		} else if bound, err := mgr.isWorkspaceBound(info); err != nil {
+			return nil, err
+		} else if bound {
+			status.State = WorkspaceMounted
+		} else {
+			status.State = WorkspaceNotMounted
+		}
+
+		// 头指针指向的提交，即 upper 层的父节点
+		head, err := uid.DecodeUID128FromHex(info.Head)
+		if err != nil {
+			logger.Info(fmt.Sprintf("WARN parse head id %q of workspace %q error: %v", info.Head, info.Path, err))
+			ret = append(ret, status)
+			continue
+		}
+		if space := getSpace(info.SpaceID); space != nil {
+			if node, ok := space.Tree().Get(head); ok && node.Parent() != nil {
+				status.Head = node.Parent().ID().Hex()
+			}
+		}
+
+		// 尚未提交的变更大小
+		if upper, err := mgr.layerManager.Get(ctx, head); err == nil {
+			size, err := fsutil.DiskUsage(upper.DiffDir())
+			if err != nil && !os.IsNotExist(err) {
+				logger.Info(fmt.Sprintf("WARN get disk usage of layer %s error: %v", info.Head, err))
+			}
+			status.UncommittedBytes = size
+		}
+
+		ret = append(ret, status)
+	}
+
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Path < ret[j].Path
+	})
+	return ret, nil
+}
@@ -0,0 +1,171 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	"github.com/yhlooo/stackcrisp/pkg/locks"
+	"github.com/yhlooo/stackcrisp/pkg/reflogs"
+	"github.com/yhlooo/stackcrisp/pkg/spaces"
+)
+
+const (
+	managerDataSubPathLocks = "locks"
+	locksSubPathSpaces      = "spaces"
+	locksSubPathWorkspaces  = "workspaces"
+	locksFileGC             = "gc"
+)
+
+// heldLock 管理器持有的锁
+type heldLock struct {
+	lock   locks.Lock
+	shared bool
+}
+
+// lockSpace 获取空间锁，在 Close 或 unlockSpace 前一直持有
+//
+// 加载空间后会修改并保存空间的操作需要在加载前获取锁，避免覆盖其它进程的修改
+func (mgr *defaultManager) lockSpace(ctx context.Context, spaceID string) error {
+	if err := mgr.lockGC(ctx, false); err != nil {
+		return err
+	}
+	return mgr.lock(ctx, "space", locksSubPathSpaces, spaceID, false)
+}
+
+// lockWorkspace 获取工作空间锁，在 Close 或 unlockWorkspace 前一直持有
+func (mgr *defaultManager) lockWorkspace(ctx context.Context, wsID string) error {
+	if err := mgr.lockGC(ctx, false); err != nil {
+		return err
+	}
+	return mgr.lock(ctx, "workspace", locksSubPathWorkspaces, wsID, false)
+}
+
+// unlockSpace 提前释放空间锁
+//
+// 释放后其它进程可能修改空间，再次修改空间前需要通过 reloadSpace 重新获取锁并重新加载
+func (mgr *defaultManager) unlockSpace(ctx context.Context, spaceID string) error {
+	return mgr.unlock(ctx, locksSubPathSpaces, spaceID)
+}
+
+// unlockWorkspace 提前释放工作空间锁
+func (mgr *defaultManager) unlockWorkspace(ctx context.Context, wsID string) error {
+	return mgr.unlock(ctx, locksSubPathWorkspaces, wsID)
+}
+
+// reloadSpace 重新获取空间锁，并重新加载释放锁期间可能被其它进程修改的空间
+func (mgr *defaultManager) reloadSpace(ctx context.Context, space spaces.Space) error {
+	if err := mgr.lockSpace(ctx, space.ID().Base32()); err != nil {
+		return err
+	}
+	if err := space.Load(ctx); err != nil {
+		return fmt.Errorf("reload space error: %w", err)
+	}
+	return nil
+}
+
+// lockGC 获取数据根目录的 gc 锁，在 Close 前一直持有
+//
+// gc 持有排他锁，其它操作在获取空间锁或工作空间锁前先获取共享锁，
+// 避免 gc 把正在创建、尚未记录到空间或工作空间信息中的层、挂载和工作空间当作垃圾回收
+func (mgr *defaultManager) lockGC(ctx context.Context, exclusive bool) error {
+	return mgr.lock(ctx, "data root", "", locksFileGC, !exclusive)
+}
+
+// lock 获取指定类型和 ID 的锁，已经持有时直接返回
+//
+// 已经持有共享锁但需要排他锁时，先释放共享锁再获取排他锁
+func (mgr *defaultManager) lock(ctx context.Context, kind, subPath, id string, shared bool) error {
+	if mgr.skipLocks {
+		return nil
+	}
+	logger := logr.FromContextOrDiscard(ctx).WithName(loggerName)
+
+	mgr.locksLock.Lock()
+	defer mgr.locksLock.Unlock()
+
+	lockFile := filepath.Join(mgr.dataRoot, managerDataSubPathLocks, subPath, id)
+	if held, ok := mgr.locks[lockFile]; ok {
+		if shared || !held.shared {
+			return nil
+		}
+		logger.V(1).Info(fmt.Sprintf("upgrading %s %s lock to exclusive ...", kind, id))
+		delete(mgr.locks, lockFile)
+		if err := held.lock.Unlock(); err != nil {
+			return fmt.Errorf("unlock shared %s %s error: %w", kind, id, err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(lockFile), 0755); err != nil {
+		return fmt.Errorf("make directory for lock %q error: %w", lockFile, err)
+	}
+
+	logger.V(1).Info(fmt.Sprintf("locking %s %s ...", kind, id))
+	l := locks.New(lockFile)
+	if shared {
+		l = locks.NewShared(lockFile)
+	}
+	owner := locks.Owner{
+		PID:     os.Getpid(),
+		Command: reflogs.CommandFromContext(ctx),
+		Time:    time.Now(),
+	}
+	if err := l.Lock(ctx, owner, mgr.lockTimeout); err != nil {
+		var lockedErr *locks.LockedError
+		if errors.As(err, &lockedErr) {
+			return fmt.Errorf("%s %s is %w (waited %s)", kind, id, err, mgr.lockTimeout)
+		}
+		return fmt.Errorf("lock %s %s error: %w", kind, id, err)
+	}
+	if mgr.locks == nil {
+		mgr.locks = map[string]*heldLock{}
+	}
+	mgr.locks[lockFile] = &heldLock{lock: l, shared: shared}
+	return nil
+}
+
+// unlock 释放指定类型和 ID 的锁，没有持有时直接返回
+func (mgr *defaultManager) unlock(ctx context.Context, subPath, id string) error {
+	if mgr.skipLocks {
+		return nil
+	}
+	logger := logr.FromContextOrDiscard(ctx).WithName(loggerName)
+
+	mgr.locksLock.Lock()
+	defer mgr.locksLock.Unlock()
+
+	lockFile := filepath.Join(mgr.dataRoot, managerDataSubPathLocks, subPath, id)
+	l, ok := mgr.locks[lockFile]
+	if !ok {
+		return nil
+	}
+	logger.V(1).Info(fmt.Sprintf("unlock %q", lockFile))
+	delete(mgr.locks, lockFile)
+	return l.lock.Unlock()
+}
+
+// Close 回滚本进程中尚未准备好的操作，并释放管理器持有的所有锁
+func (mgr *defaultManager) Close(ctx context.Context) error {
+	logger := logr.FromContextOrDiscard(ctx).WithName(loggerName)
+
+	var errs []error
+	if err := mgr.rollbackStartedIntents(ctx); err != nil {
+		errs = append(errs, err)
+	}
+
+	mgr.locksLock.Lock()
+	defer mgr.locksLock.Unlock()
+
+	for lockFile, l := range mgr.locks {
+		logger.V(1).Info(fmt.Sprintf("unlock %q", lockFile))
+		if err := l.lock.Unlock(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	mgr.locks = nil
+	return errors.Join(errs...)
+}
@@ -2,7 +2,12 @@ package manager
 
 import (
 	"context"
+	"io"
+	"time"
 
+	"github.com/yhlooo/stackcrisp/pkg/builds"
+	"github.com/yhlooo/stackcrisp/pkg/changes"
+	fsutil "github.com/yhlooo/stackcrisp/pkg/utils/fs"
 	"github.com/yhlooo/stackcrisp/pkg/workspaces"
 )
 
@@ -11,17 +16,485 @@ type Manager interface {
 	// Prepare 准备
 	Prepare(ctx context.Context) error
 	// CreateWorkspace 创建工作空间
-	CreateWorkspace(ctx context.Context, path, branch string) (workspaces.Workspace, error)
+	CreateWorkspace(ctx context.Context, path string, opts CreateWorkspaceOptions) (workspaces.Workspace, error)
 	// GetWorkspaceFromPath 从指定目录获取对应工作空间
 	GetWorkspaceFromPath(ctx context.Context, path string) (workspaces.Workspace, error)
+	// ListWorkspaces 列出数据目录中的所有工作空间，按路径排序
+	ListWorkspaces(ctx context.Context) ([]WorkspaceStatus, error)
+	// Apply 展开新的工作空间并回收被它替换的旧工作空间挂载，完成一次操作
+	//
+	// ws 是 CreateWorkspace 、 Clone 、 Commit 、 Checkout 、 Reset 返回的新工作空间，
+	// replaced 是被它替换的旧工作空间，没有则为 nil
+	Apply(ctx context.Context, ws workspaces.Workspace, replaced workspaces.Workspace) error
+	// Recover 回滚或者完成上次被中断的操作
+	Recover(ctx context.Context) error
+	// Remount 重新挂载没有挂载的工作空间（比如重启后）
+	//
+	// path 为空时重新挂载所有工作空间，否则只重新挂载包含 path 的工作空间。
+	// 以工作空间信息中的头指针重建层，并沿用原来的 upper 层和 work 目录
+	Remount(ctx context.Context, path string) (*RemountResult, error)
 	// RemoveWorkspaceMount 删除工作空间挂载
-	RemoveWorkspaceMount(ctx context.Context, ws workspaces.Workspace) error
+	//
+	// lazy 为 false 时挂载被其它进程占用则返回 *mounts.BusyError ，不删除任何数据，
+	// 为 true 时不检查占用，延迟卸载挂载，仍在使用挂载的进程可以继续使用它
+	RemoveWorkspaceMount(ctx context.Context, ws workspaces.Workspace, lazy bool) error
+	// RemoveWorkspace 删除包含指定路径的工作空间
+	//
+	// 卸载并删除工作空间挂载、工作空间路径、工作空间的本地分支和 upper 层。
+	// 有尚未提交的变更时，除非指定丢弃，否则返回错误
+	RemoveWorkspace(ctx context.Context, path string, opts RemoveWorkspaceOptions) (*RemoveWorkspaceResult, error)
 	// Clone 克隆工作空间
-	Clone(ctx context.Context, ws workspaces.Workspace, targetPath string) (workspaces.Workspace, error)
+	Clone(ctx context.Context, ws workspaces.Workspace, targetPath string, opts CloneOptions) (workspaces.Workspace, error)
+	// CloneSpace 从空间克隆出一个新的工作空间，不需要该空间有已经挂载的工作空间
+	//
+	// spaceRef 是 base32 或十六进制形式的空间 ID ，或者空间名
+	CloneSpace(ctx context.Context, spaceRef string, targetPath string, opts CloneOptions) (workspaces.Workspace, error)
 	// Commit 提交工作空间变更
 	Commit(ctx context.Context, ws workspaces.Workspace, info workspaces.CommitInfo) (workspaces.Workspace, error)
 	// Checkout 切换工作空间所处树的位置
-	Checkout(ctx context.Context, ws workspaces.Workspace, revision string) (workspaces.Workspace, error)
+	Checkout(
+		ctx context.Context,
+		ws workspaces.Workspace,
+		revision string,
+		opts CheckoutOptions,
+	) (workspaces.Workspace, error)
+	// Reset 将工作空间当前分支头指针重置到指定位置
+	Reset(ctx context.Context, ws workspaces.Workspace, revision string, mode ResetMode) (workspaces.Workspace, error)
+	// GC 回收不再被使用的层和失效的工作空间挂载
+	GC(ctx context.Context, opts GCOptions) (*GCResult, error)
+	// Prune 删除以指定提交为根的子树及其对应的层
+	Prune(ctx context.Context, ws workspaces.Workspace, revision string, opts PruneOptions) (*PruneResult, error)
+	// Flatten 将从根节点到指定提交的所有层合并为一个扁平化的基础层
+	//
+	// 之后基于该提交或其后代的挂载以基础层代替这些层，在下次挂载（比如 commit 、 checkout ）时生效
+	Flatten(ctx context.Context, ws workspaces.Workspace, revision string) (*FlattenResult, error)
+	// ListSpaces 列出数据目录中的所有空间
+	ListSpaces(ctx context.Context) ([]SpaceStatus, error)
+	// DescribeSpace 返回指定空间的状态， spaceRef 是空间 ID 或空间名
+	DescribeSpace(ctx context.Context, spaceRef string) (*SpaceStatus, error)
+	// RenameSpace 修改空间名， name 为空表示删除空间名
+	RenameSpace(ctx context.Context, spaceRef string, name string) error
+	// DeleteSpace 删除空间及其所有层，仍有工作空间使用该空间时返回错误
+	DeleteSpace(ctx context.Context, spaceRef string) (*DeleteSpaceResult, error)
+	// SpaceView 返回空间中指定 revision 的文件视图，不需要该空间有已经挂载的工作空间
+	SpaceView(ctx context.Context, spaceRef string, revision string) (changes.View, error)
+	// Import 将 tar 归档或目录中的内容导入为空间中的一个新提交
+	//
+	// 导入的内容是提交后的完整状态，基于非根节点导入时只保存与基础提交之间的变更。
+	// 指定 opts.Space 时导入到该空间， ws 可以为 nil ，否则导入到 ws 所属空间
+	Import(ctx context.Context, ws workspaces.Workspace, src ImportSource, opts ImportOptions) (*ImportResult, error)
+	// ExportOCI 将从根节点到指定提交的各层导出为 OCI 镜像布局目录 dir ，每个提交对应一个镜像层
+	//
+	// overlay whiteout 文件转换为 OCI whiteout 文件。指定 opts.Space 时从该空间导出， ws 可以为 nil
+	ExportOCI(
+		ctx context.Context,
+		ws workspaces.Workspace,
+		revision string,
+		dir string,
+		opts ExportOCIOptions,
+	) (*ExportOCIResult, error)
+	// ImportOCI 将 OCI 镜像布局目录 dir 中引用名为 ref 的镜像导入为一串提交，每个镜像层对应一个提交
+	//
+	// ref 为空时要求布局中只有一个镜像。镜像层原样叠加在基础提交上， OCI whiteout 文件转换为 overlay whiteout 文件。
+	// opts.Message 不生效，提交信息和日期来自镜像的构建历史
+	ImportOCI(
+		ctx context.Context,
+		ws workspaces.Workspace,
+		dir string,
+		ref string,
+		opts ImportOptions,
+	) (*ImportResult, error)
+	// Run 在私有的挂载命名空间中挂载基础提交和一个临时的上层，并在其中执行命令
+	//
+	// 挂载只在命令运行期间存在，其它进程不可见。命令成功退出且指定 opts.Commit 时将变更保存为基础提交上的新提交，
+	// 否则丢弃变更。命令退出码不为 0 时不返回错误，退出码记录在结果中
+	Run(ctx context.Context, ws workspaces.Workspace, opts RunOptions) (*RunResult, error)
+	// Build 从基础提交开始依次运行构建步骤，每个步骤的变更保存为上一步骤提交的子提交
+	//
+	// 每个步骤与 Run 一样在只有当前进程可见的临时挂载中运行，不使用工作空间的挂载。
+	// 提交上记录由父提交和步骤决定的缓存键，父提交已经有相同缓存键的子提交时复用它而不再运行步骤。
+	// 步骤失败时保留已经完成的步骤的提交，再次构建时复用
+	Build(ctx context.Context, ws workspaces.Workspace, opts BuildOptions) (*BuildResult, error)
+	// DiskUsage 统计空间及其中各提交和分支占用的空间， spaceRef 为空表示所有空间
+	DiskUsage(ctx context.Context, spaceRef string) ([]SpaceUsage, error)
+	// Close 回滚本进程中出错中断的操作，并释放管理器持有的空间和工作空间锁
+	Close(ctx context.Context) error
+}
+
+// WorkspaceState 工作空间挂载状态
+type WorkspaceState string
+
+// WorkspaceState 的合法值
+const (
+	// WorkspaceMounted 工作空间挂载绑定在工作空间路径上
+	WorkspaceMounted WorkspaceState = "Mounted"
+	// WorkspaceNotMounted 工作空间没有挂载（比如重启后），可以重新挂载
+	WorkspaceNotMounted WorkspaceState = "NotMounted"
+	// WorkspaceStale 工作空间已经失效（比如路径被删除或者挂载了其它内容），可以被 gc 回收
+	WorkspaceStale WorkspaceState = "Stale"
+)
+
+// WorkspaceStatus 工作空间状态
+type WorkspaceStatus struct {
+	// 工作空间 ID
+	ID string `json:"id"`
+	// 工作空间路径
+	Path string `json:"path"`
+	// 所属空间 ID
+	SpaceID string `json:"spaceID"`
+	// 挂载 ID
+	MountID string `json:"mountID"`
+	// 当前分支本地名，分离头指针状态时为空
+	Branch string `json:"branch,omitempty"`
+	// 头指针指向的提交 ID ，无法加载空间时为空
+	Head string `json:"head,omitempty"`
+	// 挂载状态
+	State WorkspaceState `json:"state"`
+	// 失效的原因
+	StaleReason string `json:"staleReason,omitempty"`
+	// 尚未提交的变更大小（字节）
+	UncommittedBytes int64 `json:"uncommittedBytes"`
+}
+
+// CreateWorkspaceOptions 创建工作空间的选项
+type CreateWorkspaceOptions struct {
+	// 初始分支
+	Branch string
+	// 新空间的名字，为空表示不命名
+	SpaceName string
+}
+
+// SpaceStatus 空间状态
+type SpaceStatus struct {
+	// 空间 ID
+	ID string `json:"id"`
+	// 空间名
+	Name string `json:"name,omitempty"`
+	// 创建时间，旧版本创建的空间没有记录
+	CreationTime time.Time `json:"creationTime"`
+	// 提交数，不包括根节点
+	Commits int `json:"commits"`
+	// 分支
+	Branches []SpaceBranch `json:"branches,omitempty"`
+	// 使用该空间的工作空间路径，不包括已经失效的工作空间
+	Workspaces []string `json:"workspaces,omitempty"`
+	// 空间中所有层占用的空间大小（字节）
+	DiskUsageBytes int64 `json:"diskUsageBytes"`
+}
+
+// SpaceBranch 空间中的分支
+type SpaceBranch struct {
+	// 分支名，全局分支带有 origin/ 前缀
+	Name string `json:"name"`
+	// 本地分支所属工作空间的路径，全局分支或者所属工作空间已经不存在时为空
+	Workspace string `json:"workspace,omitempty"`
+	// 分支指向的提交 ID
+	Commit string `json:"commit"`
+}
+
+// SpaceUsage 空间占用的空间
+type SpaceUsage struct {
+	// 空间 ID
+	ID string `json:"id"`
+	// 空间名
+	Name string `json:"name,omitempty"`
+	// 空间中所有层占用的空间大小（字节），包括尚未提交的层和扁平化基础层
+	TotalBytes int64 `json:"totalBytes"`
+	// 尚未提交的层占用的空间大小（字节）
+	UncommittedBytes int64 `json:"uncommittedBytes"`
+	// 扁平化基础层占用的空间大小（字节）
+	FlattenedBytes int64 `json:"flattenedBytes"`
+	// 各提交占用的空间，从根节点开始按树的先序排列
+	Commits []CommitUsage `json:"commits,omitempty"`
+	// 各分支占用的空间
+	Branches []BranchUsage `json:"branches,omitempty"`
+}
+
+// CommitUsage 提交占用的空间
+type CommitUsage struct {
+	// 提交 ID
+	ID string `json:"id"`
+	// 父提交 ID ，根节点为空
+	Parent string `json:"parent,omitempty"`
+	// 提交信息的第一行
+	Subject string `json:"subject,omitempty"`
+	// 提交对应层占用的空间
+	Usage fsutil.Usage `json:"usage"`
+}
+
+// BranchUsage 分支占用的空间
+type BranchUsage struct {
+	SpaceBranch
+	// 从根节点到分支头指针的所有提交占用的空间大小（字节）
+	TotalBytes int64 `json:"totalBytes"`
+	// 只能从该分支访问到的提交占用的空间大小（字节），即删除该分支并回收后可以释放的空间
+	ExclusiveBytes int64 `json:"exclusiveBytes"`
+	// 也能从其它分支、标签或分离头指针状态的工作空间访问到的提交占用的空间大小（字节）
+	SharedBytes int64 `json:"sharedBytes"`
+}
+
+// DeleteSpaceResult 删除空间的结果
+type DeleteSpaceResult struct {
+	// 被删除的空间 ID
+	ID string
+	// 被删除的层 ID
+	Layers []string
+	// 回收的空间大小（字节）
+	ReclaimedBytes int64
+}
+
+// ImportSource 导入的内容， Tar 和 Dir 只能指定其中一个
+type ImportSource struct {
+	// 未压缩的 tar 归档
+	Tar io.Reader
+	// 目录路径
+	Dir string
+	// 内容的描述（比如文件路径），用于默认提交信息和日志
+	Name string
+}
+
+// ImportOptions 导入的选项
+type ImportOptions struct {
+	// 导入到的空间 ID 或空间名，为空表示导入到工作空间所属空间
+	Space string
+	// 基础提交，为空时如果分支已经存在则基于分支头指针，否则基于根节点
+	Base string
+	// 导入后指向新提交的分支，已经存在时新提交需要基于分支头指针
+	Branch string
+	// 提交信息
+	Message string
+}
+
+// ImportResult 导入的结果
+type ImportResult struct {
+	// 新提交的 ID ，导入了多个提交时为最后一个
+	Commit string
+	// 导入的所有提交的 ID ，按从祖先到后代的顺序排列
+	Commits []string
+	// 基础提交的 ID ，基于根节点时为空
+	Base string
+	// 指向新提交的分支本地名
+	Branch string
+}
+
+// ExportOCIOptions 导出 OCI 镜像布局的选项
+type ExportOCIOptions struct {
+	// 从指定空间 ID 或空间名的空间导出，为空表示从工作空间所属空间导出
+	Space string
+	// 镜像在布局中的引用名，为空表示不设置
+	Tag string
+}
+
+// ExportOCIResult 导出 OCI 镜像布局的结果
+type ExportOCIResult struct {
+	// 导出的提交 ID
+	Commit string
+	// 镜像清单的摘要
+	Digest string
+	// 镜像层数
+	Layers int
+}
+
+// RunOptions 运行命令的选项
+type RunOptions struct {
+	// 运行命令的空间 ID 或空间名，为空表示工作空间所属空间
+	Space string
+	// 基础提交，为空时如果指定的分支已经存在则基于分支头指针，否则基于工作空间头指针，指定了空间时基于根节点
+	Revision string
+	// 命令及其参数
+	Command []string
+	// 命令的环境变量，为 nil 表示继承当前进程的环境变量
+	Env []string
+	// 命令的工作目录，是挂载中的路径，为空表示挂载的根目录
+	Dir string
+	// 是否 chroot 到挂载中运行命令
+	Chroot bool
+	// 是否以 root 运行命令，否则以执行 stackcrisp 的原始用户运行
+	AsRoot bool
+	// 命令的标准输入输出
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// 命令成功退出后是否将变更保存为新提交
+	Commit bool
+	// 提交信息，为空时使用运行的命令
+	Message string
+	// 提交后指向新提交的分支，已经存在时新提交需要基于分支头指针
+	Branch string
+}
+
+// BuildOptions 构建的选项
+type BuildOptions struct {
+	// 构建的空间 ID 或空间名，为空表示工作空间所属空间
+	Space string
+	// 基础提交，为空时如果指定的分支已经存在则基于分支头指针，否则基于工作空间头指针，指定了空间时基于根节点
+	Revision string
+	// 构建步骤
+	Steps []builds.Step
+	// 在当前进程的环境变量之上为步骤额外设置的环境变量，是缓存键的一部分
+	Env []string
+	// 步骤的工作目录，是挂载中的路径，为空表示挂载的根目录
+	Dir string
+	// 是否 chroot 到挂载中运行步骤
+	Chroot bool
+	// 是否以 root 运行步骤，否则以执行 stackcrisp 的原始用户运行
+	AsRoot bool
+	// 不复用缓存，总是运行步骤
+	NoCache bool
+	// 步骤的标准输出和标准错误，步骤的标准输入总是为空
+	Stdout io.Writer
+	Stderr io.Writer
+	// 构建完成后指向最终提交的分支，已经存在时需要基于分支头指针构建
+	Branch string
+}
+
+// BuildResult 构建的结果
+type BuildResult struct {
+	// 最终提交的 ID ，没有步骤时是基础提交
+	Commit string
+	// 基础提交的 ID ，基于根节点时为空
+	Base string
+	// 指向最终提交的分支本地名
+	Branch string
+	// 各步骤的结果
+	Steps []BuildStepResult
+}
+
+// BuildStepResult 构建步骤的结果
+type BuildStepResult struct {
+	// 步骤的提交 ID
+	Commit string
+	// 是否复用了缓存
+	Cached bool
+}
+
+// RunResult 运行命令的结果
+type RunResult struct {
+	// 命令的退出码，被信号终止时为 128 加信号值
+	ExitCode int
+	// 新提交的 ID ，没有提交时为空
+	Commit string
+	// 基础提交的 ID ，基于根节点时为空
+	Base string
+	// 指向新提交的分支本地名
+	Branch string
+}
+
+// CloneOptions 克隆工作空间的选项
+type CloneOptions struct {
+	// 新工作空间所处的分支
+	//
+	// 是全局分支时新工作空间跟踪该全局分支，否则在新工作空间中创建同名的本地分支。
+	// 没有指定 Revision 时从该分支指向的位置克隆。都没有指定时，
+	// 从工作空间克隆则与源工作空间位置和分支相同，从空间克隆则使用 DefaultCloneBranch
+	Branch string
+	// 克隆的起始位置，指定时如果没有指定 Branch 则新工作空间处于分离头指针状态
+	Revision string
+}
+
+// RemoveWorkspaceOptions 删除工作空间的选项
+type RemoveWorkspaceOptions struct {
+	// 丢弃尚未提交的变更
+	DiscardChanges bool
+	// 挂载仍被进程占用时延迟卸载，而不是失败
+	Force bool
+}
+
+// RemoveWorkspaceResult 删除工作空间的结果
+type RemoveWorkspaceResult struct {
+	// 被删除的工作空间路径
+	Path string
+	// 被删除的挂载 ID
+	MountID string
+	// 被删除的本地分支名
+	Branches []string
+	// 回收的空间大小（字节）
+	ReclaimedBytes int64
+}
+
+// CheckoutOptions 切换工作空间位置的选项
+type CheckoutOptions struct {
+	// 基于目标位置创建并切换到该名称的本地分支
+	NewBranch string
+	// 新分支已经存在时将其重置到目标位置，而不是失败
+	ForceNewBranch bool
+	// 以分离头指针状态切换，即使目标是一个分支
+	Detach bool
+	// 要求目标必须是一个本地分支
+	RequireBranch bool
+}
+
+// ResetMode reset 模式
+type ResetMode string
+
+// ResetMode 的合法值
+const (
+	// ResetSoft 移动分支头指针并基于新位置重新挂载，工作空间内容不变，与新位置的差异都成为尚未提交的变更
+	ResetSoft ResetMode = "Soft"
+	// ResetMixed 移动分支头指针并基于新位置重新挂载，保留尚未提交的变更
+	ResetMixed ResetMode = "Mixed"
+	// ResetHard 移动分支头指针并基于新位置重新挂载，丢弃尚未提交的变更
+	ResetHard ResetMode = "Hard"
+)
+
+// GCOptions 回收选项
+type GCOptions struct {
+	// 仅统计可以回收的内容，不实际删除
+	DryRun bool
+	// 失效的挂载仍被进程占用时延迟卸载，而不是跳过
+	Force bool
+}
+
+// GCResult 回收结果
+type GCResult struct {
+	// 被回收的挂载 ID
+	Mounts []string
+	// 被回收的层 ID
+	Layers []string
+	// 被回收的扁平化基础层对应的节点 ID
+	FlattenedLayers []string
+	// 新合并的扁平化基础层对应的节点 ID
+	Flattened []string
+	// 回收的空间大小（字节）
+	ReclaimedBytes int64
+}
+
+// PruneOptions 删除子树的选项
+type PruneOptions struct {
+	// 有分支、标签或工作空间指向子树时仍然删除
+	//
+	// 指向子树的分支和标签会被删除，使用子树的工作空间挂载会被延迟卸载并删除，即使仍被进程占用，
+	// 工作空间路径是软链或空目录时也会被删除
+	Force bool
+}
+
+// PruneResult 删除子树的结果
+type PruneResult struct {
+	// 被删除的节点 ID
+	Nodes []string
+	// 被删除的工作空间挂载 ID
+	Mounts []string
+	// 被删除的工作空间路径
+	Workspaces []string
+	// 回收的空间大小（字节）
+	ReclaimedBytes int64
+}
+
+// RemountResult 重新挂载的结果
+type RemountResult struct {
+	// 重新挂载的工作空间路径
+	Remounted []string
+	// 已经挂载，不需要重新挂载的工作空间路径
+	AlreadyMounted []string
+}
+
+// FlattenResult 合并层的结果
+type FlattenResult struct {
+	// 合并到的提交 ID
+	Commit string
+	// 合并的层数
+	Layers int
 }
 
 // Options 管理器选项
@@ -32,4 +505,10 @@ type Options struct {
 	ChownUID int
 	// 修改空间中存储文件所属用户组 ID ， -1 表示不修改
 	ChownGID int
+	// 等待其它进程释放空间和工作空间锁的最长时间
+	LockTimeout time.Duration
+	// 不获取空间和工作空间锁，仅用于不修改数据的操作
+	SkipLocks bool
+	// 挂载时最多叠加的已提交层数，超过时将深处的祖先层合并为一个扁平化的基础层， 0 表示不合并
+	FlattenThreshold int
 }
@@ -9,11 +9,14 @@ import (
 	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/go-logr/logr"
 
+	"github.com/yhlooo/stackcrisp/pkg/changes"
 	"github.com/yhlooo/stackcrisp/pkg/layers"
 	"github.com/yhlooo/stackcrisp/pkg/mounts"
+	"github.com/yhlooo/stackcrisp/pkg/reflogs"
 	"github.com/yhlooo/stackcrisp/pkg/spaces"
 	"github.com/yhlooo/stackcrisp/pkg/spaces/trees"
 	fsutil "github.com/yhlooo/stackcrisp/pkg/utils/fs"
@@ -27,6 +30,8 @@ const (
 	managerDataSubPathMounts = "mounts"
 
 	loggerName = "manager"
+
+	headRef = "HEAD"
 )
 
 // New 创建一个 Manager
@@ -40,6 +45,11 @@ func New(opts Options) (Manager, error) {
 		chownUID: opts.ChownUID,
 		chownGID: opts.ChownGID,
 
+		lockTimeout: opts.LockTimeout,
+		skipLocks:   opts.SkipLocks,
+
+		flattenThreshold: opts.FlattenThreshold,
+
 		layerManager: nil,
 	}, nil
 }
@@ -50,6 +60,17 @@ type defaultManager struct {
 	chownUID int
 	chownGID int
 
+	lockTimeout time.Duration
+	skipLocks   bool
+	locksLock   sync.Mutex
+	locks       map[string]*heldLock
+
+	// 本进程开始但尚未准备好的操作的新挂载 ID
+	startedIntentsLock sync.Mutex
+	startedIntents     map[string]bool
+
+	flattenThreshold int
+
 	prepareOnce  sync.Once
 	layerManager layers.LayerManager
 }
@@ -109,6 +130,16 @@ func (mgr *defaultManager) doPrepare(ctx context.Context) error {
 		}
 	}
 
+	// 确保 journal 目录
+	logger.V(1).Info("preparing journal dir")
+	journalDir := filepath.Join(mgr.dataRoot, managerDataSubPathJournal)
+	if !fsutil.IsDir(journalDir) {
+		logger.V(1).Info(fmt.Sprintf("madir %q", journalDir))
+		if err := os.Mkdir(journalDir, 0755); err != nil {
+			return fmt.Errorf("make directory for journal error: %w", err)
+		}
+	}
+
 	return nil
 }
 
@@ -120,32 +151,56 @@ type WorkspaceInfo struct {
 	SpaceID string `json:"sapceID"`
 	MountID string `json:"mountID"`
 	Branch  string `json:"branch"`
+	// 之前检出过的分支本地名或提交 ID ，最近的在前
+	CheckoutHistory []string `json:"checkoutHistory,omitempty"`
 }
 
 // CreateWorkspace 创建一个工作空间
-func (mgr *defaultManager) CreateWorkspace(ctx context.Context, path, branch string) (workspaces.Workspace, error) {
+func (mgr *defaultManager) CreateWorkspace(
+	ctx context.Context,
+	path string,
+	opts CreateWorkspaceOptions,
+) (workspaces.Workspace, error) {
 	logger := logr.FromContextOrDiscard(ctx).WithName(loggerName)
+	ctx = reflogs.NewContextWithMessage(ctx, fmt.Sprintf("created on branch %s", opts.Branch))
 
 	absPath, err := filepath.Abs(path)
 	if err != nil {
 		return nil, fmt.Errorf("get absolute path of %q error: %w", path, err)
 	}
+	wsID := uid.NewUID128()
+	if err := mgr.lockWorkspace(ctx, wsID.Base32()); err != nil {
+		return nil, err
+	}
+
+	// 检查空间名
+	if opts.SpaceName != "" {
+		if err := mgr.checkSpaceNameAvailable(ctx, opts.SpaceName); err != nil {
+			return nil, err
+		}
+	}
 
 	// 创建 space
 	space, err := mgr.createSpace(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("create space error: %w", err)
 	}
+	if err := space.SetName(opts.SpaceName); err != nil {
+		return nil, err
+	}
 
 	// 创建挂载
 	mount, head, err := mgr.createMount(ctx, space, space.Tree().Root().ID())
 	if err != nil {
 		return nil, fmt.Errorf("create mount error: %w", err)
 	}
+	if err := mgr.beginIntent(ctx, intentOperationInit, wsID, absPath, space, true, mount.ID(), nil); err != nil {
+		return nil, err
+	}
 
 	// 记录分支
-	ws := workspaces.New(uid.NewUID128(), absPath, space, mount, head, "")
-	if err := ws.SetBranch(branch); err != nil {
+	ws := workspaces.New(wsID, absPath, space, mount, head, "")
+	if err := ws.SetBranch(opts.Branch); err != nil {
 		return nil, fmt.Errorf("add branch to tree error: %w", err)
 	}
 
@@ -158,47 +213,45 @@ func (mgr *defaultManager) CreateWorkspace(ctx context.Context, path, branch str
 	if err := mgr.saveWorkspaceInfo(ctx, ws); err != nil {
 		return nil, fmt.Errorf("save workspace info error: %w", err)
 	}
+	if err := mgr.advanceIntent(ctx, mount.ID(), intentPhasePrepared); err != nil {
+		return nil, err
+	}
+	mgr.recordHeadMove(ctx, nil, ws)
 
 	return ws, nil
 }
 
 // GetWorkspaceFromPath 从指定目录获取对应工作空间
-func (mgr *defaultManager) GetWorkspaceFromPath(ctx context.Context, path string) (workspaces.Workspace, error) {
+func (mgr *defaultManager) GetWorkspaceFromPath(ctx context.Context, path string) (_ workspaces.Workspace, err error) {
 	logger := logr.FromContextOrDiscard(ctx).WithName(loggerName)
 
-	// 从挂载路径获取挂载 ID
 	absPath, err := filepath.Abs(path)
 	if err != nil {
 		return nil, fmt.Errorf("get absolute path of %q error: %w", path, err)
 	}
-	mountPath := absPath
-	if fsutil.IsSymlink(absPath) {
-		mountPath, err = os.Readlink(absPath)
-		if err != nil {
-			return nil, fmt.Errorf("get workspace mount path error: %w", err)
-		}
-	}
-	absMountPath, err := filepath.Abs(mountPath)
-	if err != nil {
-		return nil, fmt.Errorf("get absolute path of %q error: %w", mountPath, err)
-	}
-	relPath, err := filepath.Rel(filepath.Join(mgr.dataRoot, managerDataSubPathMounts), absMountPath)
+
+	// 读取路径所在工作空间信息
+	wsInfo, err := mgr.workspaceInfoFromPath(ctx, absPath)
 	if err != nil {
-		return nil, fmt.Errorf("get relative path of mount path %q error: %w", absMountPath, err)
+		return nil, err
 	}
-	divided := strings.Split(relPath, string(filepath.Separator))
-	if len(divided) == 0 {
-		return nil, fmt.Errorf("parse mount path error")
+
+	// 锁定工作空间，等待锁期间其它进程可能已经替换了工作空间挂载，需要重新读取
+	lockedID := wsInfo.ID
+	if err := mgr.lockWorkspace(ctx, lockedID); err != nil {
+		return nil, err
 	}
-	mountID, err := uid.DecodeUID128FromBase32(divided[0])
-	if err != nil {
-		return nil, fmt.Errorf("parse mount id %q error: %w", divided[0], err)
+	defer func() {
+		if err != nil {
+			_ = mgr.unlockWorkspace(ctx, lockedID)
+		}
+	}()
+	if wsInfo, err = mgr.workspaceInfoFromPath(ctx, absPath); err != nil {
+		return nil, err
 	}
-
-	// 读取挂载点对应工作空间信息
-	wsInfo, err := mgr.loadWorkspaceInfo(ctx, mountID)
+	mountID, err := uid.DecodeUID128FromBase32(wsInfo.MountID)
 	if err != nil {
-		return nil, fmt.Errorf("load workspace info error: %w", err)
+		return nil, fmt.Errorf("parse mount id %q error: %w", wsInfo.MountID, err)
 	}
 
 	// 解析 workspace ID
@@ -208,22 +261,22 @@ func (mgr *defaultManager) GetWorkspaceFromPath(ctx context.Context, path string
 	}
 
 	// 加载 space
-	logger.Info(fmt.Sprintf("loading space %q ...", wsInfo.SpaceID))
-	spaceID, err := uid.DecodeUID128FromBase32(wsInfo.SpaceID)
+	space, err := mgr.loadSpace(ctx, wsInfo.SpaceID)
 	if err != nil {
-		return nil, fmt.Errorf("parse space id %q error: %w", wsInfo.SpaceID, err)
+		return nil, err
 	}
-	space := spaces.New(
-		spaceID,
-		filepath.Join(mgr.dataRoot, managerDataSubPathSpaces, wsInfo.SpaceID),
-		mgr.layerManager,
-	)
-	if err := space.Load(ctx); err != nil {
-		return nil, fmt.Errorf("load space error: %w", err)
-	}
-	logger.Info(fmt.Sprintf("loaded space %s", space.ID()))
+	defer func() {
+		if err != nil {
+			_ = mgr.unlockSpace(ctx, space.ID().Base32())
+		}
+	}()
 
-	// 加载挂载
+	// 加载挂载，挂载不存在时（比如重启后）需要先重新挂载
+	if bound, err := mgr.isWorkspaceBound(wsInfo); err != nil {
+		return nil, err
+	} else if !bound {
+		return nil, newNotMountedError(wsInfo.Path)
+	}
 	mount := mounts.NewMountedMount(mountID, mounts.MountOptions{
 		MountDataRoot: filepath.Join(mgr.dataRoot, managerDataSubPathMounts, wsInfo.MountID),
 		ChownUID:      mgr.chownUID,
@@ -241,47 +294,115 @@ func (mgr *defaultManager) GetWorkspaceFromPath(ctx context.Context, path string
 		return nil, fmt.Errorf("head id %q not found", head)
 	}
 
-	return workspaces.New(wsID, absPath, space, mount, headNode, wsInfo.Branch), nil
+	ws := workspaces.New(wsID, absPath, space, mount, headNode, wsInfo.Branch)
+	ws.SetCheckoutHistory(wsInfo.CheckoutHistory)
+	return ws, nil
 }
 
-// RemoveWorkspaceMount 删除工作空间挂载
-func (mgr *defaultManager) RemoveWorkspaceMount(ctx context.Context, ws workspaces.Workspace) error {
-	logger := logr.FromContextOrDiscard(ctx).WithName(loggerName)
-
-	// 先移动到根目录
-	pwd, err := os.Getwd()
+// workspaceInfoFromPath 获取路径所在工作空间的信息
+func (mgr *defaultManager) workspaceInfoFromPath(ctx context.Context, absPath string) (*WorkspaceInfo, error) {
+	infos, err := mgr.listWorkspaceInfos(ctx)
 	if err != nil {
-		return fmt.Errorf("get pwd error: %w", err)
-	}
-	if err := os.Chdir("/"); err != nil {
-		return fmt.Errorf("change working directory to \"/\" error: %w", err)
+		return nil, err
 	}
 
-	// 卸载挂载
-	if err := ws.Mount().Umount(ctx); err != nil {
-		logger.Info(fmt.Sprintf("WARN umount %q error: %v", ws.Mount().MountPath(), err))
+	// 找到包含该路径的最内层工作空间，同一路径可能有多个工作空间信息，以实际绑定在路径上的挂载为准
+	var found, unmounted *WorkspaceInfo
+	for _, info := range infos {
+		if !isSubPath(info.Path, absPath) || (found != nil && len(found.Path) >= len(info.Path)) {
+			continue
+		}
+		bound, err := mgr.isWorkspaceBound(info)
+		if err != nil {
+			return nil, err
+		}
+		if bound {
+			found = info
+		} else if mgr.workspaceStaleReason(info) == "" {
+			unmounted = info
+		}
+	}
+	if found != nil {
+		return found, nil
+	}
+	if unmounted != nil && !fsutil.IsSymlink(unmounted.Path) {
+		return nil, newNotMountedError(unmounted.Path)
 	}
 
-	// 删除挂载数据
-	mountDataPath := filepath.Join(mgr.dataRoot, managerDataSubPathMounts, ws.Mount().ID().Base32())
-	logger.V(1).Info(fmt.Sprintf("rm %q", mountDataPath))
-	if err := os.RemoveAll(mountDataPath); err != nil {
-		return fmt.Errorf("remove mount data error: %w", err)
+	// 旧版本工作空间路径是链接到挂载点的软链，也可能直接位于挂载点中
+	mountID, err := mgr.mountIDFromMountPath(absPath)
+	if err != nil {
+		return nil, fmt.Errorf("%q is not in a workspace: %w", absPath, err)
+	}
+	wsInfo, err := mgr.loadWorkspaceInfo(ctx, mountID)
+	if err != nil {
+		return nil, fmt.Errorf("load workspace info error: %w", err)
 	}
+	return wsInfo, nil
+}
 
-	// 删除工作空间信息
-	wsInfoFile := filepath.Join(mgr.dataRoot, managerDataSubPathMounts, ws.Mount().ID().Base32()+".workspace")
-	logger.V(1).Info(fmt.Sprintf("rm %q", wsInfoFile))
-	if err := os.Remove(wsInfoFile); err != nil && !errors.Is(err, os.ErrNotExist) {
-		return fmt.Errorf("remove workspace info error: %w", err)
+// isWorkspaceBound 返回工作空间的挂载是否绑定在工作空间路径上
+func (mgr *defaultManager) isWorkspaceBound(info *WorkspaceInfo) (bool, error) {
+	mergedPath := filepath.Join(mgr.dataRoot, managerDataSubPathMounts, info.MountID, mountDataSubPathMerged)
+	// 挂载不存在时（比如重启后）工作空间路径和挂载点位于同一个文件系统中，但不是绑定
+	mounted, err := mounts.IsMounted(mergedPath)
+	if err != nil {
+		return false, fmt.Errorf("check mount point %q error: %w", mergedPath, err)
 	}
+	if !mounted {
+		return false, nil
+	}
+	bound, err := mounts.IsSameMount(info.Path, mergedPath)
+	if err != nil {
+		return false, fmt.Errorf("check mount point %q error: %w", info.Path, err)
+	}
+	return bound, nil
+}
 
-	// 移动回去
-	if err := os.Chdir(pwd); err != nil {
-		return fmt.Errorf("change working directory to %q error: %w", pwd, err)
+// mountIDFromMountPath 从挂载点路径（或其中的子路径，或者链接到挂载点的软链）获取挂载 ID
+func (mgr *defaultManager) mountIDFromMountPath(absPath string) (uid.UID, error) {
+	mountPath := absPath
+	if fsutil.IsSymlink(absPath) {
+		var err error
+		mountPath, err = os.Readlink(absPath)
+		if err != nil {
+			return nil, fmt.Errorf("get workspace mount path error: %w", err)
+		}
 	}
+	absMountPath, err := filepath.Abs(mountPath)
+	if err != nil {
+		return nil, fmt.Errorf("get absolute path of %q error: %w", mountPath, err)
+	}
+	relPath, err := filepath.Rel(filepath.Join(mgr.dataRoot, managerDataSubPathMounts), absMountPath)
+	if err != nil {
+		return nil, fmt.Errorf("get relative path of mount path %q error: %w", absMountPath, err)
+	}
+	divided := strings.Split(relPath, string(filepath.Separator))
+	if len(divided) == 0 {
+		return nil, fmt.Errorf("parse mount path error")
+	}
+	mountID, err := uid.DecodeUID128FromBase32(divided[0])
+	if err != nil {
+		return nil, fmt.Errorf("parse mount id %q error: %w", divided[0], err)
+	}
+	return mountID, nil
+}
 
-	return nil
+// newNotMountedError 返回工作空间没有挂载的错误
+func newNotMountedError(path string) error {
+	return fmt.Errorf("workspace %q is not mounted, run \"stackcrisp remount\" to mount it again", path)
+}
+
+// isSubPath 返回 path 是否是 parent 或者其中的子路径
+func isSubPath(parent, path string) bool {
+	rel, err := filepath.Rel(parent, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// RemoveWorkspaceMount 删除工作空间挂载
+func (mgr *defaultManager) RemoveWorkspaceMount(ctx context.Context, ws workspaces.Workspace, lazy bool) error {
+	_, err := mgr.reclaimMount(ctx, ws.Mount().ID().Base32(), reclaimOptions{Lazy: lazy})
+	return err
 }
 
 // Commit 提交工作空间变更
@@ -291,6 +412,7 @@ func (mgr *defaultManager) Commit(
 	info workspaces.CommitInfo,
 ) (workspaces.Workspace, error) {
 	logger := logr.FromContextOrDiscard(ctx).WithName(loggerName)
+	ctx = reflogs.NewContextWithMessage(ctx, commitSubject(info.Message()))
 
 	// 获取 space
 	space := ws.Space()
@@ -304,6 +426,9 @@ func (mgr *defaultManager) Commit(
 	if err != nil {
 		return nil, fmt.Errorf("create mount error: %w", err)
 	}
+	if err := mgr.beginIntent(ctx, intentOperationCommit, ws.ID(), ws.Path(), space, false, mount.ID(), ws); err != nil {
+		return nil, err
+	}
 	logger.Info(fmt.Sprintf("forward to new head %q", ws.Head().ID().Hex()))
 
 	// 更新分支头指针
@@ -314,6 +439,7 @@ func (mgr *defaultManager) Commit(
 	}
 
 	newWS := workspaces.New(ws.ID(), ws.Path(), space, mount, head, ws.Branch().LocalName())
+	newWS.SetCheckoutHistory(ws.CheckoutHistory())
 
 	// 记录空间信息
 	logger.Info(fmt.Sprintf("saving space %s ...", space.ID()))
@@ -324,6 +450,10 @@ func (mgr *defaultManager) Commit(
 	if err := mgr.saveWorkspaceInfo(ctx, newWS); err != nil {
 		return nil, fmt.Errorf("save workspace info error: %w", err)
 	}
+	if err := mgr.advanceIntent(ctx, mount.ID(), intentPhasePrepared); err != nil {
+		return nil, err
+	}
+	mgr.recordHeadMove(ctx, ws.Head().Parent(), newWS)
 
 	return newWS, nil
 }
@@ -333,20 +463,52 @@ func (mgr *defaultManager) Checkout(
 	ctx context.Context,
 	ws workspaces.Workspace,
 	key string,
+	opts CheckoutOptions,
 ) (workspaces.Workspace, error) {
 	logger := logr.FromContextOrDiscard(ctx).WithName(loggerName)
 
 	// 获取 space
 	space := ws.Space()
 
+	// 之前检出的位置
+	if n, ok := workspaces.ParsePreviousCheckout(key); ok {
+		history := ws.CheckoutHistory()
+		if n > len(history) {
+			return nil, fmt.Errorf("only %d previous checkouts recorded, can not checkout %q", len(history), key)
+		}
+		key = history[n-1]
+	}
+
 	// 查询目标
-	node, keyType, ok := ws.Search(key)
-	if !ok {
-		return nil, fmt.Errorf("key %q not found", key)
+	node, keyType, err := ws.Resolve(key)
+	if err != nil {
+		return nil, err
 	}
-	branch := ""
+	isLocalBranch := false
 	if keyType == trees.Branch {
+		// 本工作空间分支的完整名转换为本地名
+		if b, err := workspaces.ParseBranchFullName(key); err == nil &&
+			b.IsLocal() && b.WorkspaceID().Base32() == ws.ID().Base32() {
+			key = b.LocalName()
+		}
+		_, isLocalBranch = space.Tree().GetByBranch(workspaces.NewLocalBranch(ws.ID(), key).FullName())
+	}
+	ctx = reflogs.NewContextWithMessage(ctx, fmt.Sprintf("moving from %s to %s", currentPosition(ws), key))
+
+	// 确定切换后所处分支
+	branch := ""
+	switch {
+	case opts.NewBranch != "":
+		branch = opts.NewBranch
+		newBranch := workspaces.NewLocalBranch(ws.ID(), branch)
+		if existsNode, ok := space.Tree().GetByBranch(newBranch.FullName()); ok && !opts.ForceNewBranch {
+			return nil, fmt.Errorf("branch %q already exists at %q", branch, existsNode.ID().Hex())
+		}
+	case opts.Detach:
+	case isLocalBranch:
 		branch = key
+	case opts.RequireBranch:
+		return nil, fmt.Errorf("a branch is expected, got %s %q", strings.ToLower(string(keyType)), key)
 	}
 
 	// 基于指定 revision
@@ -354,9 +516,22 @@ func (mgr *defaultManager) Checkout(
 	if err != nil {
 		return nil, fmt.Errorf("create mount error: %w", err)
 	}
-	logger.Info(fmt.Sprintf("forward to new head %q", ws.Head().ID().Hex()))
+	if err := mgr.beginIntent(ctx, intentOperationCheckout, ws.ID(), ws.Path(), space, false, mount.ID(), ws); err != nil {
+		return nil, err
+	}
+	logger.Info(fmt.Sprintf("forward to new head %q", head.ID().Hex()))
+
+	// 创建分支，与切换一起保存
+	if opts.NewBranch != "" {
+		logger.Info(fmt.Sprintf("add branch %q to %q", opts.NewBranch, node.ID().Hex()))
+		if err := space.Tree().AddBranch(workspaces.NewLocalBranch(ws.ID(), opts.NewBranch).FullName(), node.ID()); err != nil {
+			return nil, fmt.Errorf("add branch %q error: %w", opts.NewBranch, err)
+		}
+	}
 
 	newWS := workspaces.New(ws.ID(), ws.Path(), space, mount, head, branch)
+	// 记录切换前的位置
+	newWS.SetCheckoutHistory(append([]string{currentPosition(ws)}, ws.CheckoutHistory()...))
 
 	// 记录空间信息
 	logger.Info(fmt.Sprintf("saving space %s ...", space.ID()))
@@ -367,32 +542,119 @@ func (mgr *defaultManager) Checkout(
 	if err := mgr.saveWorkspaceInfo(ctx, newWS); err != nil {
 		return nil, fmt.Errorf("save workspace info error: %w", err)
 	}
+	if err := mgr.advanceIntent(ctx, mount.ID(), intentPhasePrepared); err != nil {
+		return nil, err
+	}
+	mgr.recordHeadMove(ctx, ws.Head().Parent(), newWS)
 
 	return newWS, nil
 }
 
-// Clone 克隆工作空间
-func (mgr *defaultManager) Clone(
+// Reset 将工作空间当前分支头指针重置到指定位置
+func (mgr *defaultManager) Reset(
 	ctx context.Context,
-	sourceWS workspaces.Workspace,
-	targetPath string,
+	ws workspaces.Workspace,
+	revision string,
+	mode ResetMode,
 ) (workspaces.Workspace, error) {
 	logger := logr.FromContextOrDiscard(ctx).WithName(loggerName)
+	ctx = reflogs.NewContextWithMessage(ctx, fmt.Sprintf("moving to %s", revision))
 
 	// 获取 space
-	space := sourceWS.Space()
+	space := ws.Space()
 
-	// 获取头节点
-	headNode := sourceWS.Head()
+	// 查询目标
+	node, _, err := ws.Resolve(revision)
+	if err != nil {
+		return nil, err
+	}
+	branch := ws.Branch()
 
-	// 基于当前已经提交的最新层创建新挂载
-	mount, head, err := mgr.createMount(ctx, space, headNode.Parent().ID())
+	switch mode {
+	case ResetSoft, ResetMixed, ResetHard:
+	default:
+		return nil, fmt.Errorf("unknown reset mode: %q", mode)
+	}
+
+	// 保留变更时需要确认变更可以原样放到新位置上
+	var oldUpper layers.Layer
+	var workingTree changes.View
+	if mode != ResetHard {
+		if node.ID().Hex() == ws.Head().Parent().ID().Hex() {
+			// 位置没变，什么也不用做
+			return ws, nil
+		}
+		var err error
+		oldUpper, err = mgr.layerManager.Get(ctx, ws.Head().ID())
+		if err != nil {
+			return nil, fmt.Errorf("get upper layer error: %w", err)
+		}
+		oldBase, err := ws.GetView(ctx, headRef)
+		if err != nil {
+			return nil, err
+		}
+		if mode == ResetSoft {
+			// 保留工作空间中的全部内容，新位置与它的差异都成为尚未提交的变更
+			workingTree = changes.NewView(append(oldBase.Dirs(), oldUpper.DiffDir()))
+		} else {
+			newBase, err := ws.GetView(ctx, node.ID().Hex())
+			if err != nil {
+				return nil, err
+			}
+			conflicts, err := changes.Conflicts(oldUpper.DiffDir(), oldBase, newBase)
+			if err != nil {
+				return nil, fmt.Errorf("check uncommitted changes error: %w", err)
+			}
+			if len(conflicts) > 0 {
+				return nil, fmt.Errorf(
+					"uncommitted changes of %s can not be kept on %q, commit them or reset with hard mode",
+					strings.Join(conflicts, ", "), revision,
+				)
+			}
+		}
+	}
+
+	// 基于指定 revision 创建新挂载
+	mount, head, err := mgr.createMount(ctx, space, node.ID())
 	if err != nil {
 		return nil, fmt.Errorf("create mount error: %w", err)
 	}
-	logger.Info(fmt.Sprintf("forward to new head %q", headNode.Parent().ID().Hex()))
+	if err := mgr.beginIntent(ctx, intentOperationReset, ws.ID(), ws.Path(), space, false, mount.ID(), ws); err != nil {
+		return nil, err
+	}
+	logger.Info(fmt.Sprintf("forward to new head %q", head.ID().Hex()))
+
+	// 复制尚未提交的变更
+	if oldUpper != nil {
+		newUpper, err := mgr.layerManager.Get(ctx, head.ID())
+		if err != nil {
+			return nil, fmt.Errorf("get new upper layer error: %w", err)
+		}
+		if mode == ResetSoft {
+			logger.Info("copying working tree ...")
+			if err := changes.Flatten(workingTree, newUpper.DiffDir()); err != nil {
+				return nil, fmt.Errorf("copy working tree error: %w", err)
+			}
+			if err := mgr.makeDiffLayer(ctx, space, node, newUpper.DiffDir()); err != nil {
+				return nil, err
+			}
+		} else {
+			logger.Info("copying uncommitted changes ...")
+			if err := fsutil.CopyTree(oldUpper.DiffDir(), newUpper.DiffDir()); err != nil {
+				return nil, fmt.Errorf("copy uncommitted changes error: %w", err)
+			}
+		}
+	}
+
+	// 更新分支头指针
+	if branch.Name() != "" {
+		if err := space.Tree().UpdateBranch(branch.FullName(), node.ID(), true); err != nil {
+			return nil, fmt.Errorf("update branch HEAD error: %w", err)
+		}
+	}
 
-	newWS := workspaces.New(uid.NewUID128(), targetPath, space, mount, head, sourceWS.Branch().LocalName())
+	newWS := workspaces.New(ws.ID(), ws.Path(), space, mount, head, branch.LocalName())
+	newWS.SetCheckoutHistory(ws.CheckoutHistory())
 
 	// 记录空间信息
 	logger.Info(fmt.Sprintf("saving space %s ...", space.ID()))
@@ -403,10 +665,48 @@ func (mgr *defaultManager) Clone(
 	if err := mgr.saveWorkspaceInfo(ctx, newWS); err != nil {
 		return nil, fmt.Errorf("save workspace info error: %w", err)
 	}
+	if err := mgr.advanceIntent(ctx, mount.ID(), intentPhasePrepared); err != nil {
+		return nil, err
+	}
+	mgr.recordHeadMove(ctx, ws.Head().Parent(), newWS)
 
 	return newWS, nil
 }
 
+// recordHeadMove 记录工作空间头指针（已经提交的最新节点）的移动
+//
+// oldHead 为 nil 表示工作空间是新创建的
+func (mgr *defaultManager) recordHeadMove(ctx context.Context, oldHead trees.Node, newWS workspaces.Workspace) {
+	logger := logr.FromContextOrDiscard(ctx).WithName(loggerName)
+
+	oldID := ""
+	if oldHead != nil {
+		oldID = oldHead.ID().Hex()
+	}
+	newID := newWS.Head().Parent().ID().Hex()
+	if oldID == newID {
+		return
+	}
+	if err := newWS.Space().HeadReflog(newWS.ID()).Append(reflogs.NewEntry(ctx, oldID, newID)); err != nil {
+		// reflog 只是辅助信息，记录失败不影响操作
+		logger.Info(fmt.Sprintf("WARN append reflog of workspace %q error: %v", newWS.Path(), err))
+	}
+}
+
+// currentPosition 返回工作空间当前所在的分支本地名，分离头指针状态时返回提交 ID
+func currentPosition(ws workspaces.Workspace) string {
+	if ws.Branch().Name() != "" {
+		return ws.Branch().LocalName()
+	}
+	return ws.Head().Parent().ID().Hex()
+}
+
+// commitSubject 返回提交信息的第一行
+func commitSubject(message string) string {
+	subject, _, _ := strings.Cut(strings.TrimSpace(message), "\n")
+	return subject
+}
+
 // saveWorkspaceInfo 保存工作空间信息
 func (mgr *defaultManager) saveWorkspaceInfo(ctx context.Context, ws workspaces.Workspace) error {
 	logger := logr.FromContextOrDiscard(ctx).WithName(loggerName)
@@ -419,18 +719,20 @@ func (mgr *defaultManager) saveWorkspaceInfo(ctx context.Context, ws workspaces.
 		MountID: ws.Mount().ID().Base32(),
 		Head:    ws.Head().ID().Hex(),
 		Branch:  ws.Branch().LocalName(),
+
+		CheckoutHistory: ws.CheckoutHistory(),
 	})
 	if err != nil {
 		return fmt.Errorf("marshal workspace info to json error: %w", err)
 	}
 	wsInfoFile := filepath.Join(
 		mgr.dataRoot, managerDataSubPathMounts,
-		ws.Mount().ID().Base32()+".workspace",
+		ws.Mount().ID().Base32()+workspaceInfoFileSuffix,
 	)
 
 	// 写文件
 	logger.V(1).Info(fmt.Sprintf("write workspace info to file %q", wsInfoFile))
-	if err := os.WriteFile(wsInfoFile, wsInfoRaw, 0644); err != nil {
+	if err := fsutil.WriteFileAtomic(wsInfoFile, wsInfoRaw, 0644); err != nil {
 		return fmt.Errorf("write workspace info to file error: %w", err)
 	}
 
@@ -441,7 +743,7 @@ func (mgr *defaultManager) saveWorkspaceInfo(ctx context.Context, ws workspaces.
 func (mgr *defaultManager) loadWorkspaceInfo(ctx context.Context, mountID uid.UID) (*WorkspaceInfo, error) {
 	logger := logr.FromContextOrDiscard(ctx).WithName(loggerName)
 
-	wsInfoFile := filepath.Join(mgr.dataRoot, managerDataSubPathMounts, mountID.Base32()+".workspace")
+	wsInfoFile := filepath.Join(mgr.dataRoot, managerDataSubPathMounts, mountID.Base32()+workspaceInfoFileSuffix)
 	logger.V(1).Info(fmt.Sprintf("write workspace info to file %q", wsInfoFile))
 
 	// 读文件
@@ -459,18 +761,64 @@ func (mgr *defaultManager) loadWorkspaceInfo(ctx context.Context, mountID uid.UI
 	return &wsInfo, nil
 }
 
+// removeMountData 删除挂载数据和对应的工作空间信息
+func (mgr *defaultManager) removeMountData(ctx context.Context, mountID string) error {
+	logger := logr.FromContextOrDiscard(ctx).WithName(loggerName)
+
+	// 删除挂载数据
+	mountDataPath := filepath.Join(mgr.dataRoot, managerDataSubPathMounts, mountID)
+	logger.V(1).Info(fmt.Sprintf("rm %q", mountDataPath))
+	if err := os.RemoveAll(mountDataPath); err != nil {
+		return fmt.Errorf("remove mount data error: %w", err)
+	}
+
+	// 删除工作空间信息
+	wsInfoFile := filepath.Join(mgr.dataRoot, managerDataSubPathMounts, mountID+workspaceInfoFileSuffix)
+	logger.V(1).Info(fmt.Sprintf("rm %q", wsInfoFile))
+	if err := os.Remove(wsInfoFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("remove workspace info error: %w", err)
+	}
+
+	return nil
+}
+
+// loadSpace 加载指定 ID 的存储空间
+func (mgr *defaultManager) loadSpace(ctx context.Context, id string) (spaces.Space, error) {
+	logger := logr.FromContextOrDiscard(ctx).WithName(loggerName)
+
+	logger.Info(fmt.Sprintf("loading space %q ...", id))
+	spaceID, err := uid.DecodeUID128FromBase32(id)
+	if err != nil {
+		return nil, fmt.Errorf("parse space id %q error: %w", id, err)
+	}
+	if err := mgr.lockSpace(ctx, id); err != nil {
+		return nil, err
+	}
+	space := mgr.newSpace(spaceID)
+	if err := space.Load(ctx); err != nil {
+		_ = mgr.unlockSpace(ctx, id)
+		return nil, fmt.Errorf("load space error: %w", err)
+	}
+	logger.Info(fmt.Sprintf("loaded space %s", space.ID()))
+	return space, nil
+}
+
 // createSpace 创建一个存储空间
 func (mgr *defaultManager) createSpace(ctx context.Context) (spaces.Space, error) {
 	logger := logr.FromContextOrDiscard(ctx).WithName(loggerName)
 
 	spaceID := uid.NewUID128()
 	logger.Info(fmt.Sprintf("creating space %s ...", spaceID))
+	if err := mgr.lockSpace(ctx, spaceID.Base32()); err != nil {
+		return nil, err
+	}
 	spaceDataRoot := filepath.Join(mgr.dataRoot, managerDataSubPathSpaces, spaceID.Base32())
 	logger.V(1).Info(fmt.Sprintf("madir %q", spaceDataRoot))
 	if err := os.Mkdir(spaceDataRoot, 0755); err != nil {
+		_ = mgr.unlockSpace(ctx, spaceID.Base32())
 		return nil, fmt.Errorf("make directory %q for space data root error: %w", spaceDataRoot, err)
 	}
-	space := spaces.New(spaceID, spaceDataRoot, mgr.layerManager)
+	space := mgr.newSpace(spaceID)
 	logger.V(1).Info("initializing space ...")
 	if err := space.Init(ctx); err != nil {
 		return space, fmt.Errorf("init space error: %w", err)
@@ -478,6 +826,14 @@ func (mgr *defaultManager) createSpace(ctx context.Context) (spaces.Space, error
 	return space, nil
 }
 
+// newSpace 创建指定 ID 的存储空间对象
+func (mgr *defaultManager) newSpace(id uid.UID) spaces.Space {
+	return spaces.New(id, mgr.layerManager, spaces.SpaceOptions{
+		SpaceDataRoot:    filepath.Join(mgr.dataRoot, managerDataSubPathSpaces, id.Base32()),
+		FlattenThreshold: mgr.flattenThreshold,
+	})
+}
+
 // createMount 使用指定空间版本创建一个挂载
 func (mgr *defaultManager) createMount(
 	ctx context.Context,
@@ -0,0 +1,208 @@
+//go:build linux
+
+package manager
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yhlooo/stackcrisp/pkg/mounts"
+	"github.com/yhlooo/stackcrisp/pkg/workspaces"
+)
+
+// newTestManager 创建一个使用临时目录中数据根目录的管理器，返回管理器和临时目录
+//
+// 测试结束时卸载临时目录中的所有挂载
+func newTestManager(t *testing.T) (*defaultManager, string) {
+	if os.Geteuid() != 0 {
+		t.Skip("requires root")
+	}
+	root := t.TempDir()
+	t.Cleanup(func() {
+		umountAllUnder(t, root)
+	})
+	return openTestManager(t, filepath.Join(root, "data")), root
+}
+
+// openTestManager 创建一个使用 dataRoot 数据根目录的管理器，测试结束时关闭
+func openTestManager(t *testing.T, dataRoot string) *defaultManager {
+	ctx := context.Background()
+	mgr, err := New(Options{
+		DataRoot:    dataRoot,
+		ChownUID:    -1,
+		ChownGID:    -1,
+		LockTimeout: time.Second,
+	})
+	if err != nil {
+		t.Fatalf("new manager error: %v", err)
+	}
+	if err := mgr.Prepare(ctx); err != nil {
+		t.Fatalf("prepare manager error: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = mgr.Close(ctx)
+	})
+	return mgr.(*defaultManager)
+}
+
+// umountAllUnder 延迟卸载 root 中的所有挂载
+func umountAllUnder(t *testing.T, root string) {
+	mountPoints, err := mounts.ListMountPoints()
+	if err != nil {
+		t.Errorf("list mount points error: %v", err)
+		return
+	}
+	// 先卸载深处的挂载
+	sort.Slice(mountPoints, func(i, j int) bool { return len(mountPoints[i]) > len(mountPoints[j]) })
+	for _, p := range mountPoints {
+		if strings.HasPrefix(p, root+string(filepath.Separator)) {
+			_ = mounts.UmountPath(context.Background(), p, true)
+		}
+	}
+}
+
+// createTestWorkspace 在 path 创建并展开一个处于 main 分支的工作空间
+func createTestWorkspace(t *testing.T, mgr Manager, path string) workspaces.Workspace {
+	ctx := context.Background()
+	ws, err := mgr.CreateWorkspace(ctx, path, CreateWorkspaceOptions{Branch: "main"})
+	if err != nil {
+		t.Fatalf("create workspace error: %v", err)
+	}
+	if err := mgr.Apply(ctx, ws, nil); err != nil {
+		t.Fatalf("apply workspace error: %v", err)
+	}
+	return ws
+}
+
+// commitTestWorkspace 提交工作空间中的变更并展开新的工作空间
+func commitTestWorkspace(t *testing.T, mgr Manager, ws workspaces.Workspace, message string) workspaces.Workspace {
+	ctx := context.Background()
+	newWS, err := mgr.Commit(ctx, ws, workspaces.NewCommitInfo(message))
+	if err != nil {
+		t.Fatalf("commit error: %v", err)
+	}
+	if err := mgr.Apply(ctx, newWS, ws); err != nil {
+		t.Fatalf("apply workspace error: %v", err)
+	}
+	return newWS
+}
+
+// writeTestFiles 在工作空间中写入文件， files 是文件名到内容的映射
+func writeTestFiles(t *testing.T, ws workspaces.Workspace, files map[string]string) {
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(ws.Path(), name), []byte(content), 0644); err != nil {
+			t.Fatalf("write file %q error: %v", name, err)
+		}
+	}
+}
+
+// checkTestFiles 检查工作空间中的文件内容， files 是文件名到内容的映射
+func checkTestFiles(t *testing.T, ws workspaces.Workspace, files map[string]string) {
+	t.Helper()
+	for name, expected := range files {
+		content, err := os.ReadFile(filepath.Join(ws.Path(), name))
+		if err != nil {
+			t.Errorf("read file %q error: %v", name, err)
+			continue
+		}
+		if string(content) != expected {
+			t.Errorf("expected content of %q to be %q, got %q", name, expected, string(content))
+		}
+	}
+}
+
+// TestResetSoft 测试软重置后提交
+func TestResetSoft(t *testing.T) {
+	ctx := context.Background()
+	mgr, root := newTestManager(t)
+
+	ws := createTestWorkspace(t, mgr, filepath.Join(root, "ws"))
+	writeTestFiles(t, ws, map[string]string{"a": "1"})
+	ws = commitTestWorkspace(t, mgr, ws, "first")
+	first := ws.Head().Parent()
+	writeTestFiles(t, ws, map[string]string{"a": "2", "b": "2"})
+	ws = commitTestWorkspace(t, mgr, ws, "second")
+	writeTestFiles(t, ws, map[string]string{"c": "3"})
+
+	// 重置后头指针移动，工作空间内容不变
+	newWS, err := mgr.Reset(ctx, ws, first.ID().Hex(), ResetSoft)
+	if err != nil {
+		t.Fatalf("reset error: %v", err)
+	}
+	if err := mgr.Apply(ctx, newWS, ws); err != nil {
+		t.Fatalf("apply workspace error: %v", err)
+	}
+	ws = newWS
+	if ws.Head().Parent().ID().Hex() != first.ID().Hex() {
+		t.Fatalf("expected HEAD to be %s, got %s", first.ID().Hex(), ws.Head().Parent().ID().Hex())
+	}
+	checkTestFiles(t, ws, map[string]string{"a": "2", "b": "2", "c": "3"})
+
+	// 提交后新提交基于重置的位置，分支指向新提交
+	ws = commitTestWorkspace(t, mgr, ws, "squashed")
+	squashed := ws.Head().Parent()
+	if squashed.Parent().ID().Hex() != first.ID().Hex() {
+		t.Errorf("expected parent of the new commit to be %s, got %s", first.ID().Hex(), squashed.Parent().ID().Hex())
+	}
+	branchHead, ok := ws.Space().Tree().GetByBranch(ws.Branch().FullName())
+	if !ok || branchHead.ID().Hex() != squashed.ID().Hex() {
+		t.Errorf("expected branch main to point to %s, got %v", squashed.ID().Hex(), branchHead)
+	}
+	checkTestFiles(t, ws, map[string]string{"a": "2", "b": "2", "c": "3"})
+}
+
+// TestCheckout 测试 Checkout 方法
+func TestCheckout(t *testing.T) {
+	ctx := context.Background()
+	mgr, root := newTestManager(t)
+
+	ws := createTestWorkspace(t, mgr, filepath.Join(root, "ws"))
+	writeTestFiles(t, ws, map[string]string{"a": "1"})
+	ws = commitTestWorkspace(t, mgr, ws, "first")
+	first := ws.Head().Parent()
+	main := ws.Branch()
+
+	checkout := func(key string, opts CheckoutOptions) {
+		t.Helper()
+		newWS, err := mgr.Checkout(ctx, ws, key, opts)
+		if err != nil {
+			t.Fatalf("checkout %q error: %v", key, err)
+		}
+		if err := mgr.Apply(ctx, newWS, ws); err != nil {
+			t.Fatalf("apply workspace error: %v", err)
+		}
+		ws = newWS
+	}
+
+	// 创建并切换到新分支
+	checkout("HEAD", CheckoutOptions{NewBranch: "dev"})
+	if name := ws.Branch().LocalName(); name != "dev" {
+		t.Errorf("expected branch dev, got %q", name)
+	}
+	writeTestFiles(t, ws, map[string]string{"b": "2"})
+	ws = commitTestWorkspace(t, mgr, ws, "second")
+	second := ws.Head().Parent()
+
+	// 通过完整名切换分支
+	checkout(main.FullName(), CheckoutOptions{})
+	if name := ws.Branch().LocalName(); name != "main" {
+		t.Errorf("expected branch main, got %q", name)
+	}
+	if ws.Head().Parent().ID().Hex() != first.ID().Hex() {
+		t.Errorf("expected HEAD to be %s, got %s", first.ID().Hex(), ws.Head().Parent().ID().Hex())
+	}
+
+	// 分支已经存在时不切换也不修改分支
+	if _, err := mgr.Checkout(ctx, ws, "HEAD", CheckoutOptions{NewBranch: "dev"}); err == nil {
+		t.Errorf("expected an error when the branch already exists")
+	}
+	dev, ok := ws.Space().Tree().GetByBranch(workspaces.NewLocalBranch(ws.ID(), "dev").FullName())
+	if !ok || dev.ID().Hex() != second.ID().Hex() {
+		t.Errorf("expected branch dev to point to %s, got %v", second.ID().Hex(), dev)
+	}
+}
@@ -0,0 +1,233 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"runtime"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	"github.com/yhlooo/stackcrisp/pkg/changes"
+	"github.com/yhlooo/stackcrisp/pkg/oci"
+	"github.com/yhlooo/stackcrisp/pkg/reflogs"
+	"github.com/yhlooo/stackcrisp/pkg/spaces"
+	"github.com/yhlooo/stackcrisp/pkg/spaces/trees"
+	"github.com/yhlooo/stackcrisp/pkg/utils/compress"
+	fsutil "github.com/yhlooo/stackcrisp/pkg/utils/fs"
+	"github.com/yhlooo/stackcrisp/pkg/workspaces"
+)
+
+// ociCreatedBy 导出的镜像历史中记录的创建方式
+const ociCreatedBy = "stackcrisp commit"
+
+// ExportOCI 将从根节点到指定提交的各层导出为 OCI 镜像布局，每个提交对应一个镜像层
+func (mgr *defaultManager) ExportOCI(
+	ctx context.Context,
+	ws workspaces.Workspace,
+	revision string,
+	dir string,
+	opts ExportOCIOptions,
+) (*ExportOCIResult, error) {
+	logger := logr.FromContextOrDiscard(ctx).WithName(loggerName)
+
+	// 查询目标
+	var space spaces.Space
+	var node trees.Node
+	var err error
+	if opts.Space != "" {
+		if space, err = mgr.readSpace(ctx, opts.Space); err != nil {
+			return nil, err
+		}
+		node, _, err = workspaces.ResolveInSpace(space, revision)
+	} else {
+		space = ws.Space()
+		node, _, err = ws.Resolve(revision)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !workspaces.IsCommitted(node) {
+		return nil, fmt.Errorf("%q is not a commit", revision)
+	}
+	layerSet, err := space.GetLayers(ctx, node.ID())
+	if err != nil {
+		return nil, fmt.Errorf("get layers of %q error: %w", node.ID().Hex(), err)
+	}
+	var nodes []trees.Node
+	for cur := node; cur != nil; cur = cur.Parent() {
+		nodes = append([]trees.Node{cur}, nodes...)
+	}
+
+	layout := oci.NewLayout(dir)
+	if err := layout.Init(); err != nil {
+		return nil, err
+	}
+
+	// 写入各层
+	config := oci.ImageConfig{
+		Created:      workspaces.CommitDate(node),
+		Architecture: runtime.GOARCH,
+		OS:           "linux",
+		RootFS:       oci.RootFS{Type: "layers"},
+	}
+	manifest := oci.Manifest{
+		SchemaVersion: 2,
+		MediaType:     oci.MediaTypeImageManifest,
+	}
+	for i, l := range layerSet {
+		n := nodes[i]
+		if n.IsRoot() && fsutil.IsEmptyDir(l.DiffDir()) {
+			// 根节点对应层通常是空的
+			continue
+		}
+		logger.Info(fmt.Sprintf("exporting layer of %s ...", n.ID().Hex()))
+		desc, diffID, err := layout.WriteLayer(func(w io.Writer) error {
+			return changes.WriteLayerTar(l.DiffDir(), w)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("export layer of %s error: %w", n.ID().Hex(), err)
+		}
+		manifest.Layers = append(manifest.Layers, desc)
+		config.RootFS.DiffIDs = append(config.RootFS.DiffIDs, diffID)
+		config.History = append(config.History, oci.History{
+			Created:   workspaces.CommitDate(n),
+			CreatedBy: ociCreatedBy,
+			Comment:   workspaces.CommitMessage(n),
+		})
+	}
+
+	// 写入配置、清单和索引
+	if manifest.Config, err = layout.WriteJSON(oci.MediaTypeImageConfig, config); err != nil {
+		return nil, err
+	}
+	manifestDesc, err := layout.WriteJSON(oci.MediaTypeImageManifest, manifest)
+	if err != nil {
+		return nil, err
+	}
+	manifestDesc.Platform = &oci.Platform{Architecture: config.Architecture, OS: config.OS}
+	if opts.Tag != "" {
+		manifestDesc.Annotations = map[string]string{oci.AnnotationRefName: opts.Tag}
+	}
+	if err := layout.WriteIndex(&oci.Index{
+		SchemaVersion: 2,
+		MediaType:     oci.MediaTypeImageIndex,
+		Manifests:     []oci.Descriptor{manifestDesc},
+	}); err != nil {
+		return nil, err
+	}
+
+	return &ExportOCIResult{
+		Commit: node.ID().Hex(),
+		Digest: manifestDesc.Digest,
+		Layers: len(manifest.Layers),
+	}, nil
+}
+
+// ImportOCI 将 OCI 镜像布局中的镜像导入为空间中的一串提交，每个镜像层对应一个提交
+func (mgr *defaultManager) ImportOCI(
+	ctx context.Context,
+	ws workspaces.Workspace,
+	dir string,
+	ref string,
+	opts ImportOptions,
+) (*ImportResult, error) {
+	logger := logr.FromContextOrDiscard(ctx).WithName(loggerName)
+	ctx = reflogs.NewContextWithMessage(ctx, fmt.Sprintf("import-oci %s", dir))
+
+	// 读取镜像
+	layout := oci.NewLayout(dir)
+	manifestDesc, manifest, err := layout.GetManifest(ref)
+	if err != nil {
+		return nil, err
+	}
+	if len(manifest.Layers) == 0 {
+		return nil, fmt.Errorf("image %s has no layers", manifestDesc.Digest)
+	}
+	for _, desc := range manifest.Layers {
+		if !oci.IsLayerMediaType(desc.MediaType) {
+			return nil, fmt.Errorf("unsupported media type %q of layer %s", desc.MediaType, desc.Digest)
+		}
+	}
+	config := &oci.ImageConfig{}
+	if err := layout.ReadJSON(manifest.Config, config); err != nil {
+		return nil, err
+	}
+	// 不产生层的历史记录不对应镜像层
+	var history []oci.History
+	for _, h := range config.History {
+		if !h.EmptyLayer {
+			history = append(history, h)
+		}
+	}
+	if len(history) != len(manifest.Layers) {
+		history = nil
+	}
+
+	target, err := mgr.resolveImportTarget(ctx, ws, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// 逐层导入
+	var nodes []trees.Node
+	parent := target.base
+	for i, desc := range manifest.Layers {
+		logger.Info(fmt.Sprintf("importing layer %s ...", desc.Digest))
+		node, err := mgr.createImportedLayer(ctx, target.space, parent, func(diffDir string) error {
+			return extractOCILayer(layout, desc, diffDir)
+		})
+		if err != nil {
+			mgr.discardImported(ctx, target.space, nodes)
+			return nil, fmt.Errorf("import layer %s error: %w", desc.Digest, err)
+		}
+		ociCommitInfo(history, i, desc).SetToNode(node)
+		nodes = append(nodes, node)
+		parent = node
+	}
+	logger.Info(fmt.Sprintf("imported %d layers of %s", len(nodes), manifestDesc.Digest))
+
+	return mgr.finishImport(ctx, target, nodes)
+}
+
+// extractOCILayer 将镜像层解压到层的 diff 目录
+func extractOCILayer(layout oci.Layout, desc oci.Descriptor, diffDir string) error {
+	blob, err := layout.OpenBlob(desc)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = blob.Close() }()
+	r, err := compress.NewReader(blob)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = r.Close() }()
+	if err := changes.ExtractLayerTar(r, diffDir); err != nil {
+		return err
+	}
+	// 读完剩余的内容以校验摘要
+	if _, err := io.Copy(io.Discard, blob); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ociCommitInfo 返回第 i 个镜像层对应提交的提交信息
+func ociCommitInfo(history []oci.History, i int, desc oci.Descriptor) workspaces.CommitInfo {
+	message := fmt.Sprintf("import layer %s", desc.Digest)
+	date := time.Now()
+	if history != nil {
+		h := history[i]
+		switch {
+		case h.Comment != "":
+			message = h.Comment
+		case h.CreatedBy != "":
+			message = h.CreatedBy
+		}
+		if h.Created != nil {
+			date = *h.Created
+		}
+	}
+	return workspaces.NewCommitInfoWithDate(message, date)
+}
@@ -0,0 +1,132 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-logr/logr"
+
+	"github.com/yhlooo/stackcrisp/pkg/layers"
+	"github.com/yhlooo/stackcrisp/pkg/reflogs"
+	"github.com/yhlooo/stackcrisp/pkg/spaces/trees"
+	"github.com/yhlooo/stackcrisp/pkg/workspaces"
+)
+
+// Prune 删除以指定提交为根的子树及其对应的层
+func (mgr *defaultManager) Prune(
+	ctx context.Context,
+	ws workspaces.Workspace,
+	revision string,
+	opts PruneOptions,
+) (*PruneResult, error) {
+	logger := logr.FromContextOrDiscard(ctx).WithName(loggerName)
+	ctx = reflogs.NewContextWithMessage(ctx, fmt.Sprintf("pruned %s", revision))
+
+	// 获取 space
+	space := ws.Space()
+
+	// 查询目标
+	node, _, err := ws.Resolve(revision)
+	if err != nil {
+		return nil, err
+	}
+	if node.IsRoot() {
+		return nil, fmt.Errorf("the root commit can not be pruned")
+	}
+	inSubtree := map[string]bool{}
+	trees.Walk(node, func(n trees.Node) {
+		inSubtree[n.ID().Hex()] = true
+	})
+
+	// 检查使用子树的工作空间
+	wsInfos, err := mgr.listWorkspaceInfos(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list workspaces error: %w", err)
+	}
+	var usingInfos []*WorkspaceInfo
+	for _, info := range wsInfos {
+		if info.SpaceID != space.ID().Base32() || !inSubtree[info.Head] {
+			continue
+		}
+		if mgr.workspaceStaleReason(info) != "" {
+			// 失效的工作空间可以直接回收
+			usingInfos = append(usingInfos, info)
+			continue
+		}
+		if !opts.Force {
+			return nil, fmt.Errorf("subtree of %q is used by workspace %q, use force to prune it anyway", revision, info.Path)
+		}
+		logger.Info(fmt.Sprintf("WARN workspace %q will be removed", info.Path))
+		usingInfos = append(usingInfos, info)
+	}
+
+	// 挂载仍被进程占用时在删除任何数据前失败
+	if !opts.Force {
+		for _, info := range usingInfos {
+			if err := mgr.checkMountNotBusy(ctx, info.MountID); err != nil {
+				return nil, fmt.Errorf("check mount of workspace %q error: %w", info.Path, err)
+			}
+		}
+	}
+
+	// 从树上删除
+	deleted, err := space.Tree().DeleteSubtree(node.ID(), opts.Force)
+	if err != nil {
+		return nil, fmt.Errorf("%w, use force to prune it anyway", err)
+	}
+	ret := &PruneResult{}
+
+	// 卸载并删除使用子树的工作空间挂载
+	for _, info := range usingInfos {
+		size, err := mgr.reclaimMount(ctx, info.MountID, reclaimOptions{Lazy: opts.Force})
+		if err != nil {
+			return ret, fmt.Errorf("remove mount of workspace %q error: %w", info.Path, err)
+		}
+		ret.Mounts = append(ret.Mounts, info.MountID)
+		ret.ReclaimedBytes += size
+		if mgr.removeWorkspacePath(ctx, info.Path) {
+			ret.Workspaces = append(ret.Workspaces, info.Path)
+		}
+	}
+
+	// 记录空间信息
+	logger.Info(fmt.Sprintf("saving space %s ...", space.ID()))
+	if err := space.Save(ctx); err != nil {
+		return ret, fmt.Errorf("save space error: %w", err)
+	}
+
+	// 删除层和以子树中的节点为顶的扁平化基础层
+	flattened := map[string]layers.Layer{}
+	if ls, err := space.ListFlattened(ctx); err != nil {
+		logger.Info(fmt.Sprintf("WARN list flattened layers error: %v, run gc to remove them", err))
+	} else {
+		for _, l := range ls {
+			flattened[l.ID().Hex()] = l
+		}
+	}
+	var failed []string
+	for _, n := range deleted {
+		ret.Nodes = append(ret.Nodes, n.ID().Hex())
+		if l, ok := flattened[n.ID().Hex()]; ok {
+			size, err := mgr.reclaimFlattenedLayer(ctx, space, l, false)
+			if err != nil {
+				// 不影响删除原来的层，之后由 gc 回收
+				logger.Info(fmt.Sprintf("WARN remove flattened layer %s error: %v", n.ID().Hex(), err))
+			}
+			ret.ReclaimedBytes += size
+		}
+		size, err := mgr.reclaimLayer(ctx, n.ID(), false)
+		if err != nil {
+			logger.Info(fmt.Sprintf("WARN remove layer %s error: %v", n.ID().Hex(), err))
+			failed = append(failed, n.ID().Hex())
+			continue
+		}
+		ret.ReclaimedBytes += size
+	}
+	if len(failed) > 0 {
+		return ret, fmt.Errorf("remove layers %s error, run gc to retry", strings.Join(failed, ", "))
+	}
+
+	return ret, nil
+}
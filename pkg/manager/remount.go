@@ -0,0 +1,92 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/go-logr/logr"
+)
+
+// Remount 重新挂载没有挂载的工作空间（比如重启后）
+func (mgr *defaultManager) Remount(ctx context.Context, path string) (*RemountResult, error) {
+	logger := logr.FromContextOrDiscard(ctx).WithName(loggerName)
+
+	infos, err := mgr.listWorkspaceInfos(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list workspaces error: %w", err)
+	}
+
+	// 找到需要重新挂载的工作空间
+	var targets []*WorkspaceInfo
+	for _, info := range infos {
+		if reason := mgr.workspaceStaleReason(info); reason != "" {
+			logger.V(1).Info(fmt.Sprintf("skip stale workspace %q: %s", info.Path, reason))
+			continue
+		}
+		targets = append(targets, info)
+	}
+	if path != "" {
+		absPath, err := filepath.Abs(path)
+		if err != nil {
+			return nil, fmt.Errorf("get absolute path of %q error: %w", path, err)
+		}
+		// 包含该路径的最内层工作空间
+		var found *WorkspaceInfo
+		for _, info := range targets {
+			if isSubPath(info.Path, absPath) && (found == nil || len(info.Path) > len(found.Path)) {
+				found = info
+			}
+		}
+		if found == nil {
+			return nil, fmt.Errorf("%q is not in a workspace", absPath)
+		}
+		targets = []*WorkspaceInfo{found}
+	}
+
+	// 逐个重新挂载，失败时继续处理其它工作空间
+	result := &RemountResult{}
+	var errs []error
+	for _, info := range targets {
+		remounted, err := mgr.remountWorkspace(ctx, info)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("remount workspace %q error: %w", info.Path, err))
+			continue
+		}
+		if remounted {
+			result.Remounted = append(result.Remounted, info.Path)
+		} else {
+			result.AlreadyMounted = append(result.AlreadyMounted, info.Path)
+		}
+	}
+	return result, errors.Join(errs...)
+}
+
+// remountWorkspace 重新挂载工作空间，已经挂载时返回 false
+func (mgr *defaultManager) remountWorkspace(ctx context.Context, info *WorkspaceInfo) (bool, error) {
+	logger := logr.FromContextOrDiscard(ctx).WithName(loggerName)
+
+	if err := mgr.lockWorkspace(ctx, info.ID); err != nil {
+		return false, err
+	}
+	// 逐个处理，每个工作空间处理完就释放锁
+	defer func() {
+		_ = mgr.unlockSpace(ctx, info.SpaceID)
+		_ = mgr.unlockWorkspace(ctx, info.ID)
+	}()
+	bound, err := mgr.isWorkspaceBound(info)
+	if err != nil {
+		return false, err
+	}
+	if bound {
+		logger.V(1).Info(fmt.Sprintf("workspace %q is already mounted", info.Path))
+		return false, nil
+	}
+
+	logger.Info(fmt.Sprintf("remounting workspace %q ...", info.Path))
+	if err := mgr.expandWorkspace(ctx, info.MountID); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,200 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/go-logr/logr"
+
+	"github.com/yhlooo/stackcrisp/pkg/changes"
+	"github.com/yhlooo/stackcrisp/pkg/spaces"
+	fsutil "github.com/yhlooo/stackcrisp/pkg/utils/fs"
+	"github.com/yhlooo/stackcrisp/pkg/utils/uid"
+	"github.com/yhlooo/stackcrisp/pkg/workspaces"
+)
+
+// RemoveWorkspace 删除包含指定路径的工作空间
+func (mgr *defaultManager) RemoveWorkspace(
+	ctx context.Context,
+	path string,
+	opts RemoveWorkspaceOptions,
+) (_ *RemoveWorkspaceResult, err error) {
+	logger := logr.FromContextOrDiscard(ctx).WithName(loggerName)
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("get absolute path of %q error: %w", path, err)
+	}
+	info, err := mgr.findWorkspaceInfo(ctx, absPath)
+	if err != nil {
+		return nil, err
+	}
+	lockedID := info.ID
+	if err := mgr.lockWorkspace(ctx, lockedID); err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = mgr.unlockWorkspace(ctx, lockedID)
+		}
+	}()
+	// 等待锁期间工作空间可能已经被替换或删除，需要重新读取
+	if info, err = mgr.findWorkspaceInfo(ctx, absPath); err != nil {
+		return nil, err
+	}
+	ret := &RemoveWorkspaceResult{Path: info.Path, MountID: info.MountID}
+
+	// 加载空间，空间已经被删除时只需要删除挂载
+	var space spaces.Space
+	if fsutil.IsDir(filepath.Join(mgr.dataRoot, managerDataSubPathSpaces, info.SpaceID)) {
+		if space, err = mgr.loadSpace(ctx, info.SpaceID); err != nil {
+			return nil, err
+		}
+		defer func() {
+			if err != nil {
+				_ = mgr.unlockSpace(ctx, space.ID().Base32())
+			}
+		}()
+	}
+	var upper uid.UID
+	if space != nil {
+		if upper, err = uid.DecodeUID128FromHex(info.Head); err != nil {
+			return nil, fmt.Errorf("parse workspace head id %q error: %w", info.Head, err)
+		}
+	}
+
+	// 检查尚未提交的变更
+	if space != nil && !opts.DiscardChanges {
+		n, err := mgr.countUncommittedChanges(ctx, space, upper)
+		if err != nil {
+			return nil, err
+		}
+		if n > 0 {
+			return nil, fmt.Errorf(
+				"workspace %q has %d uncommitted changes, commit them or discard them to remove it anyway",
+				info.Path, n,
+			)
+		}
+	}
+
+	// 挂载仍被进程占用时在删除任何数据前失败
+	if !opts.Force {
+		if err := mgr.checkMountNotBusy(ctx, info.MountID); err != nil {
+			return nil, err
+		}
+	}
+
+	// 卸载并删除挂载
+	size, err := mgr.reclaimMount(ctx, info.MountID, reclaimOptions{Lazy: opts.Force})
+	if err != nil {
+		return nil, fmt.Errorf("remove mount error: %w", err)
+	}
+	ret.ReclaimedBytes += size
+	mgr.removeWorkspacePath(ctx, info.Path)
+	if space == nil {
+		return ret, nil
+	}
+
+	// 删除工作空间的本地分支和 upper 层
+	for name := range space.Tree().Branches() {
+		b, err := workspaces.ParseBranchFullName(name)
+		if err != nil || !b.IsLocal() || b.WorkspaceID().Base32() != info.ID {
+			continue
+		}
+		logger.V(1).Info(fmt.Sprintf("delete branch %q", name))
+		space.Tree().DeleteBranch(name)
+		ret.Branches = append(ret.Branches, b.LocalName())
+	}
+	upperNode, ok := space.Tree().Get(upper)
+	deleteUpper := ok && upperNode.IsLeaf() && !workspaces.IsCommitted(upperNode)
+	if deleteUpper {
+		space.Tree().DeleteNode(upper)
+	}
+	logger.Info(fmt.Sprintf("saving space %s ...", space.ID()))
+	if err := space.Save(ctx); err != nil {
+		return ret, fmt.Errorf("save space error: %w", err)
+	}
+	if deleteUpper {
+		size, err := mgr.reclaimLayer(ctx, upper, false)
+		if err != nil {
+			logger.Info(fmt.Sprintf("WARN remove layer %s error: %v, run gc to retry", info.Head, err))
+		}
+		ret.ReclaimedBytes += size
+	}
+
+	return ret, nil
+}
+
+// removeWorkspacePath 删除挂载已经被删除的工作空间路径，返回是否删除成功
+//
+// 仅删除软链和空目录，路径中仍有内容时保留
+func (mgr *defaultManager) removeWorkspacePath(ctx context.Context, path string) bool {
+	logger := logr.FromContextOrDiscard(ctx).WithName(loggerName)
+	if _, err := os.Lstat(path); errors.Is(err, os.ErrNotExist) {
+		return true
+	}
+	if !fsutil.IsSymlink(path) && !fsutil.IsEmptyDir(path) {
+		logger.Info(fmt.Sprintf("WARN %q is not empty, keep it", path))
+		return false
+	}
+	logger.V(1).Info(fmt.Sprintf("rm %q", path))
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		logger.Info(fmt.Sprintf("WARN remove %q error: %v", path, err))
+		return false
+	}
+	return true
+}
+
+// findWorkspaceInfo 找到包含路径的最内层工作空间的信息，包括没有挂载和已经失效的工作空间
+//
+// 同一路径有多个工作空间信息时，依次优先选择绑定在路径上的、没有失效的
+func (mgr *defaultManager) findWorkspaceInfo(ctx context.Context, absPath string) (*WorkspaceInfo, error) {
+	infos, err := mgr.listWorkspaceInfos(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list workspaces error: %w", err)
+	}
+
+	var found *WorkspaceInfo
+	foundRank := 0
+	for _, info := range infos {
+		if !isSubPath(info.Path, absPath) || (found != nil && len(found.Path) > len(info.Path)) {
+			continue
+		}
+		rank := 1
+		if mgr.workspaceStaleReason(info) == "" {
+			rank = 2
+			if bound, err := mgr.isWorkspaceBound(info); err != nil {
+				return nil, err
+			} else if bound {
+				rank = 3
+			}
+		}
+		if found == nil || len(info.Path) > len(found.Path) || rank > foundRank {
+			found, foundRank = info, rank
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("%q is not in a workspace", absPath)
+	}
+	return found, nil
+}
+
+// countUncommittedChanges 返回 upper 层中尚未提交的变更数量
+func (mgr *defaultManager) countUncommittedChanges(ctx context.Context, space spaces.Space, upper uid.UID) (int, error) {
+	layerSet, err := space.GetLayers(ctx, upper)
+	if err != nil {
+		return 0, fmt.Errorf("get layers of %q error: %w", upper.Hex(), err)
+	}
+	dirs := make([]string, len(layerSet))
+	for i, l := range layerSet {
+		dirs[i] = l.DiffDir()
+	}
+	changeList, err := changes.Diff(changes.NewView(dirs[:len(dirs)-1]), dirs[len(dirs)-1])
+	if err != nil {
+		return 0, fmt.Errorf("diff uncommitted changes error: %w", err)
+	}
+	return len(changeList), nil
+}
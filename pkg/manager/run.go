@@ -0,0 +1,191 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"syscall"
+
+	"github.com/go-logr/logr"
+
+	"github.com/yhlooo/stackcrisp/pkg/mounts"
+	"github.com/yhlooo/stackcrisp/pkg/reflogs"
+	"github.com/yhlooo/stackcrisp/pkg/spaces"
+	"github.com/yhlooo/stackcrisp/pkg/spaces/trees"
+	"github.com/yhlooo/stackcrisp/pkg/workspaces"
+)
+
+// Run 在私有的挂载命名空间中挂载基础提交和一个临时的上层，并在其中执行命令
+func (mgr *defaultManager) Run(ctx context.Context, ws workspaces.Workspace, opts RunOptions) (*RunResult, error) {
+	logger := logr.FromContextOrDiscard(ctx).WithName(loggerName)
+	if len(opts.Command) == 0 {
+		return nil, fmt.Errorf("command is required")
+	}
+	if !opts.Commit && opts.Branch != "" {
+		return nil, fmt.Errorf("branch can only be specified when committing changes")
+	}
+	command := strings.Join(opts.Command, " ")
+	ctx = reflogs.NewContextWithMessage(ctx, fmt.Sprintf("run %s", command))
+
+	// 确定空间、分支和基础提交
+	target, err := mgr.resolveImportTarget(ctx, ws, ImportOptions{
+		Space:  opts.Space,
+		Base:   opts.Revision,
+		Branch: opts.Branch,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if opts.Revision == "" && opts.Space == "" && target.branchHead == nil {
+		// 默认基于工作空间头指针
+		if target.base, _, err = ws.Resolve("HEAD"); err != nil {
+			return nil, err
+		}
+	}
+
+	// 运行命令不修改工作空间，不阻塞工作空间中的其它操作
+	if ws != nil {
+		if err := mgr.unlockWorkspace(ctx, ws.ID().Base32()); err != nil {
+			return nil, fmt.Errorf("unlock workspace error: %w", err)
+		}
+	}
+
+	// 运行命令
+	node, exitCode, err := mgr.runOnNode(ctx, target.space, target.base, opts)
+	if err != nil {
+		return nil, err
+	}
+	if exitCode != 0 {
+		return &RunResult{ExitCode: exitCode}, nil
+	}
+	if !opts.Commit {
+		logger.Info(fmt.Sprintf("discarding changes in %s ...", node.ID().Hex()))
+		mgr.discardImported(ctx, target.space, []trees.Node{node})
+		return &RunResult{}, nil
+	}
+
+	// 提交
+	message := opts.Message
+	if message == "" {
+		message = command
+	}
+	workspaces.NewCommitInfo(message).SetToNode(node)
+	logger.Info(fmt.Sprintf("committed changes of %q as %s", command, node.ID().Hex()))
+	imported, err := mgr.finishImport(ctx, target, []trees.Node{node})
+	if err != nil {
+		return nil, err
+	}
+	return &RunResult{
+		Commit: imported.Commit,
+		Base:   imported.Base,
+		Branch: imported.Branch,
+	}, nil
+}
+
+// runOnNode 基于 base 创建临时的上层，在私有的挂载命名空间中挂载并运行命令
+//
+// 命令成功退出时返回保存了变更的上层节点，由调用方提交或丢弃，否则丢弃上层，返回的节点为 nil 。
+// 运行命令期间不持有空间锁，返回前重新获取锁并重新加载空间，调用方此前从空间中取得的节点都不再有效
+func (mgr *defaultManager) runOnNode(
+	ctx context.Context,
+	space spaces.Space,
+	base trees.Node,
+	opts RunOptions,
+) (trees.Node, int, error) {
+	logger := logr.FromContextOrDiscard(ctx).WithName(loggerName)
+
+	// 创建临时的上层和挂载
+	mount, node, err := mgr.createMount(ctx, space, base.ID())
+	if mount != nil {
+		defer func() {
+			if err := mgr.removeMountData(ctx, mount.ID().Base32()); err != nil {
+				logger.Info(fmt.Sprintf("WARN remove data of mount %s error: %v", mount.ID(), err))
+			}
+		}()
+	}
+	if err != nil {
+		if node != nil {
+			mgr.discardImported(ctx, space, []trees.Node{node})
+		}
+		return nil, 0, fmt.Errorf("create mount error: %w", err)
+	}
+
+	// 运行命令可能需要很长时间，期间释放空间锁，不阻塞空间中的其它操作。
+	// 上层还没有记录到空间中，仍然持有的 gc 锁避免它被当作垃圾回收
+	if err := mgr.unlockSpace(ctx, space.ID().Base32()); err != nil {
+		mgr.discardImported(ctx, space, []trees.Node{node})
+		return nil, 0, fmt.Errorf("unlock space error: %w", err)
+	}
+	exitCode, err := mgr.runInMount(ctx, mount, opts)
+	if relockErr := mgr.reloadSpace(ctx, space); relockErr != nil && err == nil {
+		err = relockErr
+	}
+	if err == nil && exitCode == 0 {
+		// 重新加载的空间中没有上层节点，重新插入
+		upper := trees.NewNode(node.ID())
+		if err = space.Tree().AddNode(base.ID(), upper); err != nil {
+			err = fmt.Errorf("add layer %s to tree error: %w", node.ID().Hex(), err)
+		}
+		node = upper
+	}
+	if err != nil || exitCode != 0 {
+		logger.Info(fmt.Sprintf("discarding changes in %s ...", node.ID().Hex()))
+		mgr.discardImported(ctx, space, []trees.Node{node})
+		return nil, exitCode, err
+	}
+	return node, 0, nil
+}
+
+// runUser 返回运行命令的用户 ID 和用户组 ID ， -1 表示当前用户（ root ）
+func (mgr *defaultManager) runUser(asRoot bool) (int, int) {
+	if asRoot {
+		return -1, -1
+	}
+	return mgr.chownUID, mgr.chownGID
+}
+
+// runInMount 在私有的挂载命名空间中挂载 mount 并在其中运行命令，返回命令的退出码
+//
+// 除非 opts.AsRoot 为 true ，命令以执行 stackcrisp 的原始用户运行
+func (mgr *defaultManager) runInMount(ctx context.Context, mount mounts.Mount, opts RunOptions) (int, error) {
+	logger := logr.FromContextOrDiscard(ctx).WithName(loggerName)
+
+	exitCode := 0
+	err := mounts.RunInPrivateNamespace(ctx, func() error {
+		if err := mount.Mount(ctx); err != nil {
+			return fmt.Errorf("mount error: %w", err)
+		}
+		defer func() {
+			if err := mount.Umount(ctx); err != nil {
+				// 命令留下的后台进程仍在使用挂载，延迟卸载，它们此后的写入仍然会进入上层
+				logger.Info(fmt.Sprintf("WARN umount %q error: %v, detach it", mount.MountPath(), err))
+				if err := mount.Detach(ctx); err != nil {
+					logger.Info(fmt.Sprintf("WARN detach %q error: %v", mount.MountPath(), err))
+				}
+			}
+		}()
+
+		uid, gid := mgr.runUser(opts.AsRoot)
+		cmd, err := newRunCommand(mount.MountPath(), opts, uid, gid)
+		if err != nil {
+			return err
+		}
+		logger.Info(fmt.Sprintf("running %q ...", opts.Command))
+		err = cmd.Run()
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			if err != nil {
+				return fmt.Errorf("run command error: %w", err)
+			}
+			return nil
+		}
+		exitCode = exitErr.ExitCode()
+		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+			exitCode = 128 + int(status.Signal()) // 128+n 被信号终止的退出码
+		}
+		return nil
+	})
+	return exitCode, err
+}
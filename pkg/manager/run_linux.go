@@ -0,0 +1,65 @@
+//go:build linux
+
+package manager
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	fsutil "github.com/yhlooo/stackcrisp/pkg/utils/fs"
+)
+
+// defaultSearchPath 环境变量中没有 PATH 时 chroot 后查找命令的目录
+const defaultSearchPath = "/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin"
+
+// newRunCommand 创建在挂载点 root 中运行的命令，命令以 uid 和 gid 指定的用户运行， -1 表示当前用户
+func newRunCommand(root string, opts RunOptions, uid, gid int) (*exec.Cmd, error) {
+	env := opts.Env
+	if env == nil {
+		env = os.Environ()
+	}
+	cmd := &exec.Cmd{
+		Args:   opts.Command,
+		Env:    env,
+		Stdin:  opts.Stdin,
+		Stdout: opts.Stdout,
+		Stderr: opts.Stderr,
+
+		SysProcAttr: &syscall.SysProcAttr{},
+	}
+	if uid >= 0 && gid >= 0 {
+		cmd.SysProcAttr.Credential = &syscall.Credential{Uid: uint32(uid), Gid: uint32(gid)}
+	}
+	dir := path.Join("/", opts.Dir)
+
+	if !opts.Chroot {
+		p, err := exec.LookPath(opts.Command[0])
+		if err != nil {
+			return nil, err
+		}
+		cmd.Path = p
+		cmd.Dir = filepath.Join(root, dir)
+		return cmd, nil
+	}
+
+	// chroot 后命令在挂载中查找
+	searchPath := defaultSearchPath
+	for _, kv := range env {
+		if v, ok := strings.CutPrefix(kv, "PATH="); ok {
+			searchPath = v
+		}
+	}
+	p, err := fsutil.LookPathInRoot(root, opts.Command[0], searchPath)
+	if err != nil {
+		return nil, fmt.Errorf("look up command in %q error: %w", root, err)
+	}
+	cmd.Path = p
+	cmd.Dir = dir
+	cmd.SysProcAttr.Chroot = root
+	return cmd, nil
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+
+package manager
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+)
+
+// newRunCommand 创建在挂载点 root 中运行的命令
+func newRunCommand(string, RunOptions, int, int) (*exec.Cmd, error) {
+	return nil, fmt.Errorf("running commands in a mount is not supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,342 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/go-logr/logr"
+
+	"github.com/yhlooo/stackcrisp/pkg/changes"
+	"github.com/yhlooo/stackcrisp/pkg/spaces"
+	"github.com/yhlooo/stackcrisp/pkg/spaces/trees"
+	fsutil "github.com/yhlooo/stackcrisp/pkg/utils/fs"
+	"github.com/yhlooo/stackcrisp/pkg/utils/uid"
+	"github.com/yhlooo/stackcrisp/pkg/workspaces"
+)
+
+const (
+	// spaceNamesLockID 修改空间名时持有的锁，保证空间名不重复
+	spaceNamesLockID = "_names"
+)
+
+// ListSpaces 列出数据目录中的所有空间，按空间名和 ID 排序
+func (mgr *defaultManager) ListSpaces(ctx context.Context) ([]SpaceStatus, error) {
+	logger := logr.FromContextOrDiscard(ctx).WithName(loggerName)
+
+	spaceIDs, err := mgr.listSpaceIDs(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list spaces error: %w", err)
+	}
+	wsInfos, err := mgr.listWorkspaceInfos(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list workspaces error: %w", err)
+	}
+
+	ret := make([]SpaceStatus, 0, len(spaceIDs))
+	for _, id := range spaceIDs {
+		status, err := mgr.spaceStatus(ctx, id, wsInfos)
+		if err != nil {
+			logger.Info(fmt.Sprintf("WARN get status of space %s error: %v", id, err))
+			continue
+		}
+		ret = append(ret, *status)
+	}
+
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Name != ret[j].Name {
+			// 有名字的在前
+			return ret[j].Name == "" || (ret[i].Name != "" && ret[i].Name < ret[j].Name)
+		}
+		return ret[i].ID < ret[j].ID
+	})
+	return ret, nil
+}
+
+// DescribeSpace 返回指定空间的状态
+func (mgr *defaultManager) DescribeSpace(ctx context.Context, spaceRef string) (*SpaceStatus, error) {
+	spaceID, err := mgr.resolveSpaceID(ctx, spaceRef)
+	if err != nil {
+		return nil, err
+	}
+	wsInfos, err := mgr.listWorkspaceInfos(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list workspaces error: %w", err)
+	}
+	return mgr.spaceStatus(ctx, spaceID, wsInfos)
+}
+
+// RenameSpace 修改空间名
+func (mgr *defaultManager) RenameSpace(ctx context.Context, spaceRef string, name string) error {
+	logger := logr.FromContextOrDiscard(ctx).WithName(loggerName)
+
+	spaceID, err := mgr.resolveSpaceID(ctx, spaceRef)
+	if err != nil {
+		return err
+	}
+	space, err := mgr.loadSpace(ctx, spaceID)
+	if err != nil {
+		return err
+	}
+	if space.Meta().Name == name {
+		return nil
+	}
+	if name != "" {
+		if err := mgr.checkSpaceNameAvailable(ctx, name); err != nil {
+			return err
+		}
+	}
+	if err := space.SetName(name); err != nil {
+		return err
+	}
+	logger.Info(fmt.Sprintf("saving space %s ...", space.ID()))
+	if err := space.Save(ctx); err != nil {
+		return fmt.Errorf("save space error: %w", err)
+	}
+	return nil
+}
+
+// DeleteSpace 删除空间及其所有层
+func (mgr *defaultManager) DeleteSpace(ctx context.Context, spaceRef string) (*DeleteSpaceResult, error) {
+	logger := logr.FromContextOrDiscard(ctx).WithName(loggerName)
+
+	spaceID, err := mgr.resolveSpaceID(ctx, spaceRef)
+	if err != nil {
+		return nil, err
+	}
+	space, err := mgr.loadSpace(ctx, spaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 检查是否仍有工作空间在使用
+	wsInfos, err := mgr.listWorkspaceInfos(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list workspaces error: %w", err)
+	}
+	if paths := mgr.spaceWorkspacePaths(spaceID, wsInfos); len(paths) > 0 {
+		return nil, fmt.Errorf(
+			"space %s is still used by workspaces: %s, remove them first", spaceID, strings.Join(paths, ", "),
+		)
+	}
+
+	// 先删除空间数据，之后即使删除层失败也可以被 gc 回收
+	ret := &DeleteSpaceResult{ID: spaceID}
+	var nodes []trees.Node
+	trees.Walk(space.Tree().Root(), func(node trees.Node) {
+		nodes = append(nodes, node)
+	})
+	flattened, err := space.ListFlattened(ctx)
+	if err != nil {
+		logger.Info(fmt.Sprintf("WARN list flattened layers of space %s error: %v", spaceID, err))
+	}
+	for _, l := range flattened {
+		if size, err := fsutil.DiskUsage(l.DiffDir()); err == nil {
+			ret.ReclaimedBytes += size
+		}
+	}
+	spaceDataRoot := filepath.Join(mgr.dataRoot, managerDataSubPathSpaces, spaceID)
+	logger.V(1).Info(fmt.Sprintf("rm -rf %q", spaceDataRoot))
+	if err := os.RemoveAll(spaceDataRoot); err != nil {
+		return nil, fmt.Errorf("remove space data root %q error: %w", spaceDataRoot, err)
+	}
+	logger.Info(fmt.Sprintf("removed space %s", spaceID))
+
+	for _, node := range nodes {
+		size, err := mgr.reclaimLayer(ctx, node.ID(), false)
+		if err != nil {
+			logger.Info(fmt.Sprintf("WARN remove layer %s error: %v, run gc to retry", node.ID().Hex(), err))
+			continue
+		}
+		ret.Layers = append(ret.Layers, node.ID().Hex())
+		ret.ReclaimedBytes += size
+	}
+	return ret, nil
+}
+
+// SpaceView 返回空间中指定 revision 的文件视图
+func (mgr *defaultManager) SpaceView(ctx context.Context, spaceRef string, revision string) (changes.View, error) {
+	space, err := mgr.readSpace(ctx, spaceRef)
+	if err != nil {
+		return nil, err
+	}
+	node, _, err := workspaces.ResolveInSpace(space, revision)
+	if err != nil {
+		return nil, err
+	}
+	layerSet, err := space.GetLayers(ctx, node.ID())
+	if err != nil {
+		return nil, fmt.Errorf("get layers of %q error: %w", node.ID().Hex(), err)
+	}
+	return changes.NewLayersView(layerSet), nil
+}
+
+// readSpace 不加锁读取空间， spaceRef 是空间 ID 或空间名
+//
+// 空间数据总是原子地写入，只读取不需要锁定
+func (mgr *defaultManager) readSpace(ctx context.Context, spaceRef string) (spaces.Space, error) {
+	spaceID, err := mgr.resolveSpaceID(ctx, spaceRef)
+	if err != nil {
+		return nil, err
+	}
+	id, err := uid.DecodeUID128FromBase32(spaceID)
+	if err != nil {
+		return nil, fmt.Errorf("parse space id %q error: %w", spaceID, err)
+	}
+	space := mgr.newSpace(id)
+	if err := space.Load(ctx); err != nil {
+		return nil, fmt.Errorf("load space error: %w", err)
+	}
+	return space, nil
+}
+
+// spaceStatus 不加锁读取空间并返回其状态
+func (mgr *defaultManager) spaceStatus(ctx context.Context, spaceID string, wsInfos []*WorkspaceInfo) (*SpaceStatus, error) {
+	logger := logr.FromContextOrDiscard(ctx).WithName(loggerName)
+
+	id, err := uid.DecodeUID128FromBase32(spaceID)
+	if err != nil {
+		return nil, fmt.Errorf("parse space id %q error: %w", spaceID, err)
+	}
+	// 空间数据总是原子地写入，只读取不需要锁定
+	space := mgr.newSpace(id)
+	if err := space.Load(ctx); err != nil {
+		return nil, fmt.Errorf("load space error: %w", err)
+	}
+	meta := space.Meta()
+	status := &SpaceStatus{
+		ID:           spaceID,
+		Name:         meta.Name,
+		CreationTime: meta.CreationTime,
+		Workspaces:   mgr.spaceWorkspacePaths(spaceID, wsInfos),
+	}
+
+	// 提交和层占用的空间
+	trees.Walk(space.Tree().Root(), func(node trees.Node) {
+		if !node.IsRoot() && workspaces.IsCommitted(node) {
+			status.Commits++
+		}
+		usage, err := mgr.layerUsage(ctx, node)
+		if err != nil {
+			logger.Info(fmt.Sprintf("WARN get disk usage of layer %s error: %v", node.ID().Hex(), err))
+		}
+		status.DiskUsageBytes += usage.Bytes
+	})
+	flattened, err := space.ListFlattened(ctx)
+	if err != nil {
+		logger.Info(fmt.Sprintf("WARN list flattened layers of space %s error: %v", spaceID, err))
+	}
+	for _, l := range flattened {
+		size, err := fsutil.DiskUsage(l.DiffDir())
+		if err != nil && !os.IsNotExist(err) {
+			logger.Info(fmt.Sprintf("WARN get disk usage of flattened layer %s error: %v", l.ID().Hex(), err))
+		}
+		status.DiskUsageBytes += size
+	}
+
+	// 分支
+	wsPaths := map[string]string{}
+	for _, info := range wsInfos {
+		wsPaths[info.ID] = info.Path
+	}
+	for fullName, node := range space.Tree().Branches() {
+		b, err := workspaces.ParseBranchFullName(fullName)
+		if err != nil {
+			continue
+		}
+		branch := SpaceBranch{Name: b.LocalName(), Commit: node.ID().Hex()}
+		if b.IsLocal() {
+			branch.Workspace = wsPaths[b.WorkspaceID().Base32()]
+		}
+		status.Branches = append(status.Branches, branch)
+	}
+	sort.Slice(status.Branches, func(i, j int) bool {
+		if status.Branches[i].Name != status.Branches[j].Name {
+			return status.Branches[i].Name < status.Branches[j].Name
+		}
+		return status.Branches[i].Workspace < status.Branches[j].Workspace
+	})
+
+	return status, nil
+}
+
+// spaceWorkspacePaths 返回使用指定空间且没有失效的工作空间路径
+func (mgr *defaultManager) spaceWorkspacePaths(spaceID string, wsInfos []*WorkspaceInfo) []string {
+	var ret []string
+	for _, info := range wsInfos {
+		if info.SpaceID == spaceID && mgr.workspaceStaleReason(info) == "" {
+			ret = append(ret, info.Path)
+		}
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// resolveSpaceID 将空间 ID 或空间名解析为 base32 形式的空间 ID ，空间不存在时返回错误
+func (mgr *defaultManager) resolveSpaceID(ctx context.Context, ref string) (string, error) {
+	// 空间 ID
+	id, err := uid.DecodeUID128FromBase32(ref)
+	if err != nil {
+		id, err = uid.DecodeUID128FromHex(ref)
+	}
+	if err == nil {
+		if !fsutil.IsDir(filepath.Join(mgr.dataRoot, managerDataSubPathSpaces, id.Base32())) {
+			return "", fmt.Errorf("space %q not found", ref)
+		}
+		return id.Base32(), nil
+	}
+
+	// 空间名
+	spaceID, err := mgr.findSpaceByName(ctx, ref)
+	if err != nil {
+		return "", err
+	}
+	if spaceID == "" {
+		return "", fmt.Errorf("space %q not found", ref)
+	}
+	return spaceID, nil
+}
+
+// findSpaceByName 返回指定名字的空间 ID ，不存在时返回空字符串
+func (mgr *defaultManager) findSpaceByName(ctx context.Context, name string) (string, error) {
+	logger := logr.FromContextOrDiscard(ctx).WithName(loggerName)
+
+	spaceIDs, err := mgr.listSpaceIDs(ctx)
+	if err != nil {
+		return "", fmt.Errorf("list spaces error: %w", err)
+	}
+	for _, id := range spaceIDs {
+		meta, err := spaces.ReadMeta(filepath.Join(mgr.dataRoot, managerDataSubPathSpaces, id))
+		if err != nil {
+			logger.Info(fmt.Sprintf("WARN read meta of space %s error: %v", id, err))
+			continue
+		}
+		if meta.Name == name {
+			return id, nil
+		}
+	}
+	return "", nil
+}
+
+// checkSpaceNameAvailable 检查空间名是否合法并且没有被使用
+//
+// 会获取空间名锁并在 Close 前一直持有，避免其它进程同时使用相同的名字
+func (mgr *defaultManager) checkSpaceNameAvailable(ctx context.Context, name string) error {
+	if err := spaces.ValidateName(name); err != nil {
+		return err
+	}
+	if err := mgr.lock(ctx, "space names", locksSubPathSpaces, spaceNamesLockID, false); err != nil {
+		return err
+	}
+	spaceID, err := mgr.findSpaceByName(ctx, name)
+	if err != nil {
+		return err
+	}
+	if spaceID != "" {
+		return fmt.Errorf("space name %q is already used by space %s", name, spaceID)
+	}
+	return nil
+}
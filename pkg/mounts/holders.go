@@ -0,0 +1,55 @@
+package mounts
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Holder 占用挂载的进程
+type Holder struct {
+	// 进程 ID
+	PID int
+	// 进程名
+	Command string
+	// 占用方式，如 cwd 、 root 、 fd 3 、 maps
+	Refs []string
+}
+
+// String 返回进程描述
+func (h Holder) String() string {
+	return fmt.Sprintf("pid %d (%s: %s)", h.PID, h.Command, strings.Join(h.Refs, ", "))
+}
+
+// BusyError 挂载被进程占用的错误
+type BusyError struct {
+	// 挂载点
+	Path string
+	// 占用挂载的进程
+	Holders []Holder
+}
+
+// Error 返回错误描述
+func (err *BusyError) Error() string {
+	holders := make([]string, len(err.Holders))
+	for i, h := range err.Holders {
+		holders[i] = h.String()
+	}
+	return fmt.Sprintf("%q is busy, used by %s", err.Path, strings.Join(holders, ", "))
+}
+
+// isUnderAny 返回 path 是否是 parents 中任意一个路径或者其中的子路径
+func isUnderAny(path string, parents []string) bool {
+	// 已经被删除的文件会带有后缀
+	path = strings.TrimSuffix(path, " (deleted)")
+	if !filepath.IsAbs(path) {
+		return false
+	}
+	for _, parent := range parents {
+		rel, err := filepath.Rel(parent, path)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,98 @@
+//go:build linux
+
+package mounts
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const procPath = "/proc"
+
+// FindHolders 找到工作目录、根目录、打开的文件或者映射的文件位于指定路径中的进程，不包括当前进程
+func FindHolders(paths ...string) ([]Holder, error) {
+	cleanPaths := make([]string, len(paths))
+	for i, p := range paths {
+		cleanPaths[i] = filepath.Clean(p)
+	}
+
+	entries, err := os.ReadDir(procPath)
+	if err != nil {
+		return nil, fmt.Errorf("read dir %q error: %w", procPath, err)
+	}
+	var ret []Holder
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil || pid == os.Getpid() {
+			continue
+		}
+		// 进程可能已经退出或者无权查看，忽略错误
+		refs := processRefs(pid, cleanPaths)
+		if len(refs) == 0 {
+			continue
+		}
+		comm, _ := os.ReadFile(filepath.Join(procPath, entry.Name(), "comm"))
+		ret = append(ret, Holder{
+			PID:     pid,
+			Command: strings.TrimSpace(string(comm)),
+			Refs:    refs,
+		})
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].PID < ret[j].PID
+	})
+	return ret, nil
+}
+
+// processRefs 返回进程占用指定路径的方式
+func processRefs(pid int, paths []string) []string {
+	procDir := filepath.Join(procPath, strconv.Itoa(pid))
+
+	var refs []string
+	for _, name := range []string{"cwd", "root"} {
+		if target, err := os.Readlink(filepath.Join(procDir, name)); err == nil && isUnderAny(target, paths) {
+			refs = append(refs, name)
+		}
+	}
+
+	fdDir := filepath.Join(procDir, "fd")
+	fds, _ := os.ReadDir(fdDir)
+	for _, fd := range fds {
+		if target, err := os.Readlink(filepath.Join(fdDir, fd.Name())); err == nil && isUnderAny(target, paths) {
+			refs = append(refs, "fd "+fd.Name())
+		}
+	}
+
+	if mapsReferTo(filepath.Join(procDir, "maps"), paths) {
+		refs = append(refs, "maps")
+	}
+	return refs
+}
+
+// mapsReferTo 返回进程内存映射中是否有指定路径中的文件
+func mapsReferTo(mapsFile string, paths []string) bool {
+	f, err := os.Open(mapsFile)
+	if err != nil {
+		return false
+	}
+	defer func() { _ = f.Close() }()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// 格式参考 https://man7.org/linux/man-pages/man5/proc_pid_maps.5.html
+		// 第 6 个字段起是映射的文件路径
+		fields := strings.SplitN(scanner.Text(), " ", 6)
+		if len(fields) < 6 {
+			continue
+		}
+		if isUnderAny(strings.TrimSpace(fields[5]), paths) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,77 @@
+//go:build linux
+
+package mounts
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// TestFindHolders 测试 FindHolders 方法
+func TestFindHolders(t *testing.T) {
+	dir := t.TempDir()
+	sub := filepath.Join(dir, "sub")
+	if err := os.Mkdir(sub, 0755); err != nil {
+		t.Fatalf("mkdir error: %v", err)
+	}
+
+	// 工作目录位于子目录中的进程
+	cmd := exec.Command("sleep", "10")
+	cmd.Dir = sub
+	if err := cmd.Start(); err != nil {
+		t.Skipf("start sleep error: %v", err)
+	}
+	defer func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	}()
+
+	holders, err := FindHolders(dir)
+	if err != nil {
+		t.Fatalf("find holders error: %v", err)
+	}
+	found := false
+	for _, h := range holders {
+		if h.PID == cmd.Process.Pid {
+			found = true
+			if len(h.Refs) == 0 || h.Refs[0] != "cwd" || h.Command != "sleep" {
+				t.Errorf("unexpected holder: %#v", h)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("process %d not found in holders: %v", cmd.Process.Pid, holders)
+	}
+
+	// 其它路径中没有
+	holders, err = FindHolders(filepath.Join(dir, "other"))
+	if err != nil {
+		t.Fatalf("find holders error: %v", err)
+	}
+	for _, h := range holders {
+		if h.PID == cmd.Process.Pid {
+			t.Errorf("unexpected holder: %#v", h)
+		}
+	}
+}
+
+// TestIsUnderAny 测试 isUnderAny 方法
+func TestIsUnderAny(t *testing.T) {
+	parents := []string{"/a/b", "/c"}
+	cases := map[string]bool{
+		"/a/b":               true,
+		"/a/b/c":             true,
+		"/a/bc":              false,
+		"/c/d (deleted)":     true,
+		"/a":                 false,
+		"socket:[12345]":     false,
+		"anon_inode:[event]": false,
+	}
+	for path, expected := range cases {
+		if got := isUnderAny(path, parents); got != expected {
+			t.Errorf("isUnderAny(%q): expected %t, got %t", path, expected, got)
+		}
+	}
+}
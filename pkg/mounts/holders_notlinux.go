@@ -0,0 +1,13 @@
+//go:build !linux
+
+package mounts
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// FindHolders 找到工作目录、根目录、打开的文件或者映射的文件位于指定路径中的进程，不包括当前进程
+func FindHolders(...string) ([]Holder, error) {
+	return nil, fmt.Errorf("finding processes is not supported on %s", runtime.GOOS)
+}
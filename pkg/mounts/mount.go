@@ -2,6 +2,7 @@ package mounts
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -9,12 +10,14 @@ import (
 	"github.com/go-logr/logr"
 
 	"github.com/yhlooo/stackcrisp/pkg/layers"
+	fsutil "github.com/yhlooo/stackcrisp/pkg/utils/fs"
 	"github.com/yhlooo/stackcrisp/pkg/utils/uid"
 )
 
 const (
 	mountDataSubPathMountPath = "merged"
 	mountDataSubPathWorkDir   = "work"
+	mountDataSubPathLowerDirs = "lowerdirs.json"
 
 	loggerName = "mounts"
 )
@@ -54,6 +57,7 @@ func New(ctx context.Context, id uid.UID, layers []layers.Layer, opts MountOptio
 		UpperDir:  upperDir,
 		WorkDir:   workDir,
 		ReadOnly:  false,
+		Backend:   DetectOverlayBackend(),
 	}
 
 	logger.V(1).Info(fmt.Sprintf("overlay mount options: %#v", ovlOpts))
@@ -79,6 +83,20 @@ func NewMountedMount(id uid.UID, opts MountOptions) Mount {
 	}
 }
 
+// ReadLowerDirs 读取挂载时记录的 lower 层目录，第 0 个元素是最顶层
+func ReadLowerDirs(mountDataRoot string) ([]string, error) {
+	p := filepath.Join(mountDataRoot, mountDataSubPathLowerDirs)
+	raw, err := os.ReadFile(p)
+	if err != nil {
+		return nil, fmt.Errorf("read lower dirs from %q error: %w", p, err)
+	}
+	var ret []string
+	if err := json.Unmarshal(raw, &ret); err != nil {
+		return nil, fmt.Errorf("unmarshal lower dirs from %q error: %w", p, err)
+	}
+	return ret, nil
+}
+
 // Mount 挂载
 type Mount interface {
 	// ID 返回挂载 ID
@@ -87,10 +105,16 @@ type Mount interface {
 	MountPath() string
 	// Mount 挂载
 	Mount(ctx context.Context) error
-	// Umount 卸载
+	// Umount 卸载，挂载被占用时失败
 	Umount(ctx context.Context) error
-	// CreateSymlink 在指定路径创建访问挂载点的软链
-	CreateSymlink(ctx context.Context, path string) error
+	// Detach 延迟卸载
+	//
+	// 仍在使用挂载的进程可以继续使用，直到它们离开后才真正卸载。挂载不会变为只读
+	Detach(ctx context.Context) error
+	// BindTo 将挂载点绑定挂载到指定路径
+	//
+	// 路径上已经有挂载时原子地替换它，并延迟卸载原挂载，路径中仍在使用原挂载的进程不受影响
+	BindTo(ctx context.Context, path string) error
 }
 
 // defaultMount 是 Mount 的一个默认实现
@@ -126,14 +150,26 @@ func (m *mountedMount) MountPath() string {
 	return m.mountPath
 }
 
-// CreateSymlink 在指定路径创建访问挂载点的软链
-func (m *mountedMount) CreateSymlink(ctx context.Context, path string) error {
+// prepareMountPoint 确保路径可以作为挂载点
+//
+// 路径不存在时创建目录，是旧版本链接到挂载点的软链时将其替换为目录
+func (m *mountedMount) prepareMountPoint(ctx context.Context, path string) error {
 	logger := logr.FromContextOrDiscard(ctx).WithName(loggerName)
-	logger.V(1).Info(fmt.Sprintf("ln -s %q %q", m.MountPath(), path))
-	if err := os.Symlink(m.MountPath(), path); err != nil {
-		return err
+
+	if fsutil.IsSymlink(path) {
+		logger.V(1).Info(fmt.Sprintf("rm %q", path))
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("remove symlink %q error: %w", path, err)
+		}
+	}
+	if fsutil.IsExists(path) {
+		return nil
+	}
+	logger.V(1).Info(fmt.Sprintf("mkdir %q", path))
+	if err := os.Mkdir(path, 0755); err != nil {
+		return fmt.Errorf("mkdir %q error: %w", path, err)
 	}
-	if err := os.Lchown(path, m.chownUID, m.chownGID); err != nil {
+	if err := os.Chown(path, m.chownUID, m.chownGID); err != nil {
 		return fmt.Errorf("chown %q to \"%d:%d\" error: %w", path, m.chownUID, m.chownGID, err)
 	}
 	return nil
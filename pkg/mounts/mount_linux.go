@@ -4,15 +4,22 @@ package mounts
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"syscall"
 
 	"github.com/go-logr/logr"
+	"golang.org/x/sys/unix"
 
 	fsutil "github.com/yhlooo/stackcrisp/pkg/utils/fs"
 )
 
+// moveMountBeneath move_mount(2) 的 MOVE_MOUNT_BENEATH 标记，自 Linux 6.5 起支持
+const moveMountBeneath = 0x00000200
+
 // Mount 挂载
 func (m *defaultMount) Mount(ctx context.Context) error {
 	logger := logr.FromContextOrDiscard(ctx).WithName(loggerName)
@@ -34,6 +41,16 @@ func (m *defaultMount) Mount(ctx context.Context) error {
 		}
 	}
 
+	// 记录叠加的层，供 gc 判断哪些扁平化基础层仍在被使用
+	raw, err := json.Marshal(m.ovlOpts.LowerDir)
+	if err != nil {
+		return fmt.Errorf("marshal lower dirs to json error: %w", err)
+	}
+	lowerDirsFile := filepath.Join(filepath.Dir(mountPath), mountDataSubPathLowerDirs)
+	if err := fsutil.WriteFileAtomic(lowerDirsFile, raw, 0644); err != nil {
+		return fmt.Errorf("write lower dirs to %q error: %w", lowerDirsFile, err)
+	}
+
 	if err := CreateOverlayMount(ctx, m.ovlOpts); err != nil {
 		return err
 	}
@@ -43,9 +60,82 @@ func (m *defaultMount) Mount(ctx context.Context) error {
 	return nil
 }
 
-// Umount 卸载挂载
+// Umount 卸载挂载，挂载被占用时失败
 func (m *mountedMount) Umount(ctx context.Context) error {
+	return UmountPath(ctx, m.MountPath(), false)
+}
+
+// Detach 延迟卸载
+//
+// 仍在使用挂载的进程可以继续使用，直到它们离开后才真正卸载。
+// 挂载不会变为只读，这些进程的写入仍然会进入 upper 层，即使 upper 层已经被提交
+func (m *mountedMount) Detach(ctx context.Context) error {
+	return UmountPath(ctx, m.MountPath(), true)
+}
+
+// BindTo 将挂载点绑定挂载到指定路径
+//
+// 路径上已经有挂载时原子地替换它，并延迟卸载原挂载，路径中仍在使用原挂载的进程不受影响
+func (m *mountedMount) BindTo(ctx context.Context, path string) error {
+	logger := logr.FromContextOrDiscard(ctx).WithName(loggerName)
+
+	if err := m.prepareMountPoint(ctx, path); err != nil {
+		return err
+	}
+	mounted, err := IsMounted(path)
+	if err != nil {
+		return fmt.Errorf("check mount point %q error: %w", path, err)
+	}
+
+	if !mounted {
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return fmt.Errorf("read dir %q error: %w", path, err)
+		}
+		if len(entries) > 0 {
+			return fmt.Errorf("%q is not an empty directory", path)
+		}
+		logger.V(1).Info(fmt.Sprintf("mount --bind %q %q", m.MountPath(), path))
+		return syscall.Mount(m.MountPath(), path, "", syscall.MS_BIND, "")
+	}
+
+	// 在原挂载下方挂载，再卸载上方的原挂载，路径上始终有挂载
+	logger.V(1).Info(fmt.Sprintf("mount --bind --beneath %q %q", m.MountPath(), path))
+	if err := bindBeneath(m.MountPath(), path); err != nil {
+		if !errors.Is(err, unix.EINVAL) && !errors.Is(err, unix.ENOSYS) {
+			return fmt.Errorf("bind %q beneath %q error: %w", m.MountPath(), path, err)
+		}
+		// 内核不支持（ 6.5 之前），先卸载原挂载再绑定，期间路径短暂地为空
+		logger.V(1).Info(fmt.Sprintf("bind beneath is not supported (%v), umount before bind", err))
+		if err := UmountPath(ctx, path, true); err != nil {
+			return err
+		}
+		logger.V(1).Info(fmt.Sprintf("mount --bind %q %q", m.MountPath(), path))
+		return syscall.Mount(m.MountPath(), path, "", syscall.MS_BIND, "")
+	}
+	return UmountPath(ctx, path, true)
+}
+
+// UmountPath 卸载指定路径上最上方的挂载
+//
+// lazy 为 true 时延迟卸载，挂载立即从路径上移除，仍在使用挂载的进程可以继续使用，直到它们离开后才真正释放，
+// 否则挂载被占用时失败
+func UmountPath(ctx context.Context, path string, lazy bool) error {
 	logger := logr.FromContextOrDiscard(ctx).WithName(loggerName)
-	logger.V(1).Info(fmt.Sprintf("umount %q", m.MountPath()))
-	return syscall.Unmount(m.MountPath(), 0)
+	if lazy {
+		logger.V(1).Info(fmt.Sprintf("umount --lazy %q", path))
+		return syscall.Unmount(path, syscall.MNT_DETACH)
+	}
+	logger.V(1).Info(fmt.Sprintf("umount %q", path))
+	return syscall.Unmount(path, 0)
+}
+
+// bindBeneath 将 src 绑定挂载到 dst 上已有挂载的下方
+func bindBeneath(src, dst string) error {
+	fd, err := unix.OpenTree(unix.AT_FDCWD, src, unix.OPEN_TREE_CLONE|unix.OPEN_TREE_CLOEXEC)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = unix.Close(fd) }()
+	return unix.MoveMount(fd, "", unix.AT_FDCWD, dst, unix.MOVE_MOUNT_F_EMPTY_PATH|moveMountBeneath)
 }
@@ -13,7 +13,22 @@ func (m *defaultMount) Mount(context.Context) error {
 	return fmt.Errorf("mount is not supported on %s", runtime.GOOS)
 }
 
-// Umount 卸载挂载
+// Umount 卸载挂载，挂载被占用时失败
 func (m *mountedMount) Umount(context.Context) error {
 	return fmt.Errorf("umount is not supported on %s", runtime.GOOS)
 }
+
+// Detach 延迟卸载
+func (m *mountedMount) Detach(context.Context) error {
+	return fmt.Errorf("umount is not supported on %s", runtime.GOOS)
+}
+
+// BindTo 将挂载点绑定挂载到指定路径
+func (m *mountedMount) BindTo(context.Context, string) error {
+	return fmt.Errorf("bind mount is not supported on %s", runtime.GOOS)
+}
+
+// UmountPath 卸载指定路径上最上方的挂载
+func UmountPath(context.Context, string, bool) error {
+	return fmt.Errorf("umount is not supported on %s", runtime.GOOS)
+}
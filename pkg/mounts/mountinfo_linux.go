@@ -0,0 +1,126 @@
+//go:build linux
+
+package mounts
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const mountInfoPath = "/proc/self/mountinfo"
+
+// IsMounted 返回指定路径是否挂载点
+func IsMounted(path string) (bool, error) {
+	mountPoints, err := ListMountPoints()
+	if err != nil {
+		return false, err
+	}
+	path = filepath.Clean(path)
+	for _, p := range mountPoints {
+		if p == path {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ListMountPoints 列出当前挂载命名空间中的所有挂载点
+func ListMountPoints() ([]string, error) {
+	entries, err := listMountInfo()
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]string, len(entries))
+	for i, entry := range entries {
+		ret[i] = entry.mountPoint
+	}
+	return ret, nil
+}
+
+// IsSameMount 返回两个路径是否都是挂载点，且（最上方的）挂载是同一文件系统的同一目录
+//
+// 一个挂载和它的绑定挂载是同一文件系统的同一目录
+func IsSameMount(a, b string) (bool, error) {
+	entries, err := listMountInfo()
+	if err != nil {
+		return false, err
+	}
+	a = filepath.Clean(a)
+	b = filepath.Clean(b)
+	var entryA, entryB *mountInfoEntry
+	for i := range entries {
+		// 同一挂载点上后面的挂载在上方
+		switch entries[i].mountPoint {
+		case a:
+			entryA = &entries[i]
+		case b:
+			entryB = &entries[i]
+		}
+	}
+	if entryA == nil || entryB == nil {
+		return false, nil
+	}
+	return entryA.device == entryB.device && entryA.root == entryB.root, nil
+}
+
+// mountInfoEntry mountinfo 中的一条挂载信息
+type mountInfoEntry struct {
+	// 文件系统的设备号 major:minor
+	device string
+	// 挂载的文件系统中的目录
+	root string
+	// 挂载点
+	mountPoint string
+}
+
+// listMountInfo 列出当前挂载命名空间中的所有挂载信息
+func listMountInfo() ([]mountInfoEntry, error) {
+	f, err := os.Open(mountInfoPath)
+	if err != nil {
+		return nil, fmt.Errorf("open %q error: %w", mountInfoPath, err)
+	}
+	defer func() { _ = f.Close() }()
+
+	var ret []mountInfoEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// 格式参考 https://man7.org/linux/man-pages/man5/proc_pid_mountinfo.5.html
+		// 第 3 、 4 、 5 个字段分别是设备号、文件系统中的目录和挂载点
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 5 {
+			continue
+		}
+		ret = append(ret, mountInfoEntry{
+			device:     fields[2],
+			root:       unescapeMountInfo(fields[3]),
+			mountPoint: unescapeMountInfo(fields[4]),
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read %q error: %w", mountInfoPath, err)
+	}
+	return ret, nil
+}
+
+// unescapeMountInfo 还原 mountinfo 中以 \ooo 八进制转义的字符
+func unescapeMountInfo(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+4 <= len(s) {
+			if v, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(v))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
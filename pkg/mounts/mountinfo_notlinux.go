@@ -0,0 +1,23 @@
+//go:build !linux
+
+package mounts
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// IsMounted 返回指定路径是否挂载点
+func IsMounted(string) (bool, error) {
+	return false, fmt.Errorf("mountinfo is not supported on %s", runtime.GOOS)
+}
+
+// ListMountPoints 列出当前挂载命名空间中的所有挂载点
+func ListMountPoints() ([]string, error) {
+	return nil, fmt.Errorf("mountinfo is not supported on %s", runtime.GOOS)
+}
+
+// IsSameMount 返回两个路径是否都是挂载点，且（最上方的）挂载是同一文件系统的同一目录
+func IsSameMount(string, string) (bool, error) {
+	return false, fmt.Errorf("mountinfo is not supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,38 @@
+//go:build linux
+
+package mounts
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+
+	"github.com/go-logr/logr"
+	"golang.org/x/sys/unix"
+)
+
+// RunInPrivateNamespace 在一个私有的挂载命名空间中执行 fn
+//
+// fn 在一个独占的线程中执行，该线程脱离进程的挂载命名空间，其中的挂载和卸载不会传播到其它挂载命名空间，
+// 在 fn 中启动的子进程也处于该命名空间中。 fn 返回后线程随之退出，其中的挂载随命名空间一起释放
+func RunInPrivateNamespace(ctx context.Context, fn func() error) error {
+	logger := logr.FromContextOrDiscard(ctx).WithName(loggerName)
+
+	errCh := make(chan error, 1)
+	go func() {
+		// 不解除锁定，使线程在 goroutine 结束后退出，而不是带着私有的命名空间被其它 goroutine 使用
+		runtime.LockOSThread()
+
+		logger.V(1).Info("unshare --mount --propagation private")
+		if err := unix.Unshare(unix.CLONE_NEWNS); err != nil {
+			errCh <- fmt.Errorf("unshare mount namespace error: %w", err)
+			return
+		}
+		if err := unix.Mount("", "/", "", unix.MS_REC|unix.MS_PRIVATE, ""); err != nil {
+			errCh <- fmt.Errorf("make mounts private error: %w", err)
+			return
+		}
+		errCh <- fn()
+	}()
+	return <-errCh
+}
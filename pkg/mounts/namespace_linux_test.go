@@ -0,0 +1,38 @@
+//go:build linux
+
+package mounts
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+// TestRunInPrivateNamespace 测试 RunInPrivateNamespace 方法
+func TestRunInPrivateNamespace(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("requires root")
+	}
+
+	dir := t.TempDir()
+	err := RunInPrivateNamespace(context.Background(), func() error {
+		if err := syscall.Mount("tmpfs", dir, "tmpfs", 0, ""); err != nil {
+			return err
+		}
+		return os.WriteFile(filepath.Join(dir, "a"), []byte("a"), 0644)
+	})
+	if err != nil {
+		t.Fatalf("run in private namespace error: %v", err)
+	}
+
+	// 挂载不可见，文件写入了命名空间中的 tmpfs
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("read dir %q error: %v", dir, err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("expected %q to be empty outside the namespace, got %d entries", dir, len(entries))
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+
+package mounts
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+)
+
+// RunInPrivateNamespace 在一个私有的挂载命名空间中执行 fn
+func RunInPrivateNamespace(context.Context, func() error) error {
+	return fmt.Errorf("mount namespace is not supported on %s", runtime.GOOS)
+}
@@ -15,4 +15,19 @@ type OverlayMountOptions struct {
 	WorkDir string
 	// 是否只读挂载
 	ReadOnly bool
+	// 创建挂载的方式，为空时自动选择当前内核支持的方式
+	Backend OverlayBackend
 }
+
+// OverlayBackend 创建 OverlayFS 挂载的方式
+type OverlayBackend string
+
+// OverlayBackend 的合法值
+const (
+	// OverlayBackendFSConfig 使用新的挂载 API （ fsopen / fsconfig ）逐层添加 lowerdir+ ，
+	// 层数不受挂载参数长度限制，需要 Linux 6.8 及以上
+	OverlayBackendFSConfig OverlayBackend = "FSConfig"
+	// OverlayBackendRelativePath 使用 mount(2) ，切换到 lower 层的公共父目录后以相对路径指定各层，
+	// 缩短挂载参数，但挂载参数仍然不能超过一页
+	OverlayBackendRelativePath OverlayBackend = "RelativePath"
+)
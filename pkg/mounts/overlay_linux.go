@@ -5,14 +5,117 @@ package mounts
 import (
 	"context"
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
+	"sync"
 	"syscall"
+	"unsafe"
 
 	"github.com/go-logr/logr"
+	"golang.org/x/sys/unix"
 )
 
+// fsconfig(2) 的命令，见 linux/mount.h
+const (
+	fsconfigSetString = 1
+	fsconfigCmdCreate = 6
+)
+
+// 挂载参数的最大长度，即一页
+var maxMountDataLength = os.Getpagesize() - 1
+
+var (
+	detectOverlayBackendOnce sync.Once
+	detectedOverlayBackend   OverlayBackend
+)
+
+// DetectOverlayBackend 返回当前内核支持的最好的创建 OverlayFS 挂载的方式
+func DetectOverlayBackend() OverlayBackend {
+	detectOverlayBackendOnce.Do(func() {
+		detectedOverlayBackend = OverlayBackendRelativePath
+		fd, err := unix.Fsopen("overlay", unix.FSOPEN_CLOEXEC)
+		if err != nil {
+			return
+		}
+		defer func() { _ = unix.Close(fd) }()
+		// 不支持 lowerdir+ 的内核会返回 EINVAL
+		if err := fsconfig(fd, fsconfigSetString, "lowerdir+", "/"); err != nil {
+			return
+		}
+		detectedOverlayBackend = OverlayBackendFSConfig
+	})
+	return detectedOverlayBackend
+}
+
 // CreateOverlayMount 创建一个 Overlay 挂载
 func CreateOverlayMount(ctx context.Context, opts OverlayMountOptions) error {
+	// 挂载名
+	if opts.Source == "" {
+		opts.Source = "overlay"
+	}
+
+	backend := opts.Backend
+	if backend == "" {
+		backend = DetectOverlayBackend()
+	}
+	switch backend {
+	case OverlayBackendFSConfig:
+		return createOverlayMountWithFSConfig(ctx, opts)
+	case OverlayBackendRelativePath:
+		return createOverlayMountWithRelativePath(ctx, opts)
+	default:
+		return fmt.Errorf("unknown overlay backend %q", backend)
+	}
+}
+
+// createOverlayMountWithFSConfig 使用新的挂载 API 创建 Overlay 挂载，逐层添加 lower 层
+func createOverlayMountWithFSConfig(ctx context.Context, opts OverlayMountOptions) error {
+	logger := logr.FromContextOrDiscard(ctx).WithName(loggerName)
+	logger.V(1).Info(fmt.Sprintf(
+		"fsopen overlay %q with %d lower layers at %q", opts.Source, len(opts.LowerDir), opts.MountPath,
+	))
+
+	fd, err := unix.Fsopen("overlay", unix.FSOPEN_CLOEXEC)
+	if err != nil {
+		return fmt.Errorf("fsopen overlay error: %w", err)
+	}
+	defer func() { _ = unix.Close(fd) }()
+
+	// 设置参数
+	params := [][2]string{{"source", opts.Source}}
+	for _, dir := range opts.LowerDir {
+		params = append(params, [2]string{"lowerdir+", dir})
+	}
+	params = append(params, [2]string{"upperdir", opts.UpperDir}, [2]string{"workdir", opts.WorkDir})
+	for _, param := range params {
+		if err := fsconfig(fd, fsconfigSetString, param[0], param[1]); err != nil {
+			return fmt.Errorf("set overlay option %s=%q error: %w", param[0], param[1], err)
+		}
+	}
+	if err := fsconfig(fd, fsconfigCmdCreate, "", ""); err != nil {
+		return fmt.Errorf("create overlay error: %w", err)
+	}
+
+	// 挂载
+	var attrs int
+	if opts.ReadOnly {
+		attrs |= unix.MOUNT_ATTR_RDONLY
+	}
+	mfd, err := unix.Fsmount(fd, unix.FSMOUNT_CLOEXEC, attrs)
+	if err != nil {
+		return fmt.Errorf("fsmount overlay error: %w", err)
+	}
+	defer func() { _ = unix.Close(mfd) }()
+	if err := unix.MoveMount(mfd, "", unix.AT_FDCWD, opts.MountPath, unix.MOVE_MOUNT_F_EMPTY_PATH); err != nil {
+		return fmt.Errorf("move overlay mount to %q error: %w", opts.MountPath, err)
+	}
+	return nil
+}
+
+// createOverlayMountWithRelativePath 使用 mount(2) 创建 Overlay 挂载，
+// 挂载时切换到 lower 层的公共父目录中，以相对路径指定各层
+func createOverlayMountWithRelativePath(ctx context.Context, opts OverlayMountOptions) error {
 	logger := logr.FromContextOrDiscard(ctx).WithName(loggerName)
 
 	// 挂载标记
@@ -22,20 +125,91 @@ func CreateOverlayMount(ctx context.Context, opts OverlayMountOptions) error {
 		flags |= syscall.MS_RDONLY
 		showOpts = "ro,"
 	}
-	// 挂载名
-	source := opts.Source
-	if source == "" {
-		source = "overlay"
+
+	// 以 lower 层的公共父目录为基准的相对路径
+	base := commonParent(opts.LowerDir)
+	lowerDir := make([]string, len(opts.LowerDir))
+	for i, dir := range opts.LowerDir {
+		lowerDir[i] = relativeTo(base, dir)
 	}
-	// 挂载参数
 	data := fmt.Sprintf(
 		"lowerdir=%s,upperdir=%s,workdir=%s",
-		strings.Join(opts.LowerDir, ":"),
-		opts.UpperDir,
-		opts.WorkDir,
+		strings.Join(lowerDir, ":"),
+		relativeTo(base, opts.UpperDir),
+		relativeTo(base, opts.WorkDir),
 	)
+	if len(data) > maxMountDataLength {
+		return fmt.Errorf(
+			"overlay options for %d lower layers are too long (%d > %d bytes), "+
+				"Linux 6.8 or later is required to mount so many layers",
+			len(opts.LowerDir), len(data), maxMountDataLength,
+		)
+	}
+
+	// 切换到基准目录挂载，完成后切换回来
+	pwd, err := os.Open(".")
+	if err != nil {
+		return fmt.Errorf("open working directory error: %w", err)
+	}
+	defer func() { _ = pwd.Close() }()
+	if err := os.Chdir(base); err != nil {
+		return fmt.Errorf("change working directory to %q error: %w", base, err)
+	}
+	defer func() {
+		if err := pwd.Chdir(); err != nil {
+			logger.Info(fmt.Sprintf("WARN change working directory back error: %v", err))
+		}
+	}()
 
 	showOpts += data
-	logger.V(1).Info(fmt.Sprintf("mount -t overlay %q -o %q %q", source, showOpts, opts.MountPath))
-	return syscall.Mount(source, opts.MountPath, "overlay", flags, data)
+	logger.V(1).Info(fmt.Sprintf("cd %q && mount -t overlay %q -o %q %q", base, opts.Source, showOpts, opts.MountPath))
+	return syscall.Mount(opts.Source, opts.MountPath, "overlay", flags, data)
+}
+
+// commonParent 返回所有路径的公共父目录
+func commonParent(paths []string) string {
+	if len(paths) == 0 {
+		return "/"
+	}
+	parent := filepath.Dir(filepath.Clean(paths[0]))
+	for _, p := range paths[1:] {
+		p = filepath.Clean(p)
+		for parent != "/" && !strings.HasPrefix(p, parent+string(filepath.Separator)) {
+			parent = filepath.Dir(parent)
+		}
+	}
+	return parent
+}
+
+// relativeTo 返回 path 相对 base 的路径，无法表示时返回原路径
+func relativeTo(base, path string) string {
+	rel, err := filepath.Rel(base, path)
+	if err != nil || len(rel) >= len(path) {
+		return path
+	}
+	return rel
+}
+
+// fsconfig 调用 fsconfig(2) ， key 和 value 为空时传空指针
+func fsconfig(fd int, cmd uint, key, value string) error {
+	var keyPtr, valuePtr *byte
+	var err error
+	if key != "" {
+		if keyPtr, err = unix.BytePtrFromString(key); err != nil {
+			return err
+		}
+	}
+	if value != "" {
+		if valuePtr, err = unix.BytePtrFromString(value); err != nil {
+			return err
+		}
+	}
+	_, _, errno := unix.Syscall6(
+		unix.SYS_FSCONFIG,
+		uintptr(fd), uintptr(cmd), uintptr(unsafe.Pointer(keyPtr)), uintptr(unsafe.Pointer(valuePtr)), 0, 0,
+	)
+	if errno != 0 {
+		return errno
+	}
+	return nil
 }
@@ -0,0 +1,40 @@
+//go:build linux
+
+package mounts
+
+import "testing"
+
+// TestCommonParent 测试 commonParent 方法
+func TestCommonParent(t *testing.T) {
+	cases := []struct {
+		paths    []string
+		expected string
+	}{
+		{nil, "/"},
+		{[]string{"/data/layers/a/diff"}, "/data/layers/a"},
+		{[]string{"/data/layers/a/diff", "/data/layers/b/diff"}, "/data/layers"},
+		{[]string{"/data/layers/a", "/data/layersx/b"}, "/data"},
+		{[]string{"/a/b", "/c/d"}, "/"},
+	}
+	for _, c := range cases {
+		if got := commonParent(c.paths); got != c.expected {
+			t.Errorf("commonParent(%q): expected %q, got %q", c.paths, c.expected, got)
+		}
+	}
+}
+
+// TestRelativeTo 测试 relativeTo 方法
+func TestRelativeTo(t *testing.T) {
+	cases := []struct {
+		base, path, expected string
+	}{
+		{"/data/layers", "/data/layers/a/diff", "a/diff"},
+		{"/data/layers", "/data/mounts/m/upper", "../mounts/m/upper"},
+		{"/data/layers/a/b/c", "/x", "/x"},
+	}
+	for _, c := range cases {
+		if got := relativeTo(c.base, c.path); got != c.expected {
+			t.Errorf("relativeTo(%q, %q): expected %q, got %q", c.base, c.path, c.expected, got)
+		}
+	}
+}
@@ -3,11 +3,17 @@
 package mounts
 
 import (
+	"context"
 	"fmt"
 	"runtime"
 )
 
+// DetectOverlayBackend 返回当前内核支持的最好的创建 OverlayFS 挂载的方式
+func DetectOverlayBackend() OverlayBackend {
+	return ""
+}
+
 // CreateOverlayMount 创建一个 Overlay 挂载
-func CreateOverlayMount(OverlayMountOptions) error {
-	return fmt.Errorf("overla is not supported on %s", runtime.GOOS)
+func CreateOverlayMount(context.Context, OverlayMountOptions) error {
+	return fmt.Errorf("overlay is not supported on %s", runtime.GOOS)
 }
@@ -0,0 +1,286 @@
+package oci
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	fsutil "github.com/yhlooo/stackcrisp/pkg/utils/fs"
+)
+
+const (
+	layoutFileName = "oci-layout"
+	indexFileName  = "index.json"
+	blobsDirName   = "blobs"
+	digestPrefix   = "sha256:"
+)
+
+// Layout OCI 镜像布局目录
+type Layout interface {
+	// Dir 返回镜像布局目录路径
+	Dir() string
+	// Init 在空目录（或者不存在的目录）中初始化镜像布局
+	Init() error
+	// WriteBlob 写入内容，返回其描述符
+	WriteBlob(mediaType string, r io.Reader) (Descriptor, error)
+	// WriteJSON 将 v 以 JSON 格式写入，返回其描述符
+	WriteJSON(mediaType string, v any) (Descriptor, error)
+	// WriteLayer 将 write 写入的 tar 以 gzip 压缩后作为镜像层写入，返回其描述符和未压缩的 tar 的摘要
+	WriteLayer(write func(w io.Writer) error) (Descriptor, string, error)
+	// OpenBlob 打开内容，读取到结尾时校验大小和摘要，不一致时返回错误
+	OpenBlob(desc Descriptor) (io.ReadCloser, error)
+	// ReadJSON 读取 JSON 格式的内容到 v
+	ReadJSON(desc Descriptor, v any) error
+	// ReadIndex 读取 index.json
+	ReadIndex() (*Index, error)
+	// WriteIndex 写入 index.json
+	WriteIndex(index *Index) error
+	// GetManifest 获取引用名为 ref 的镜像清单， ref 为空时要求布局中只有一个镜像
+	GetManifest(ref string) (Descriptor, *Manifest, error)
+}
+
+// NewLayout 创建一个 Layout
+func NewLayout(dir string) Layout {
+	return &defaultLayout{dir: dir}
+}
+
+// defaultLayout 是 Layout 的默认实现
+type defaultLayout struct {
+	dir string
+}
+
+var _ Layout = &defaultLayout{}
+
+// Dir 返回镜像布局目录路径
+func (l *defaultLayout) Dir() string {
+	return l.dir
+}
+
+// Init 在空目录（或者不存在的目录）中初始化镜像布局
+func (l *defaultLayout) Init() error {
+	if fsutil.IsExists(l.dir) && !fsutil.IsEmptyDir(l.dir) {
+		return fmt.Errorf("path %q is not an empty dir", l.dir)
+	}
+	if err := os.MkdirAll(filepath.Join(l.dir, blobsDirName, "sha256"), 0755); err != nil {
+		return fmt.Errorf("make blobs dir in %q error: %w", l.dir, err)
+	}
+	data, err := json.Marshal(ImageLayout{Version: ImageLayoutVersion})
+	if err != nil {
+		return fmt.Errorf("marshal %s error: %w", layoutFileName, err)
+	}
+	if err := os.WriteFile(filepath.Join(l.dir, layoutFileName), data, 0644); err != nil {
+		return fmt.Errorf("write %s error: %w", layoutFileName, err)
+	}
+	return nil
+}
+
+// WriteBlob 写入内容，返回其描述符
+func (l *defaultLayout) WriteBlob(mediaType string, r io.Reader) (Descriptor, error) {
+	f, err := os.CreateTemp(filepath.Join(l.dir, blobsDirName, "sha256"), ".tmp-")
+	if err != nil {
+		return Descriptor{}, fmt.Errorf("create temp blob error: %w", err)
+	}
+	h := sha256.New()
+	size, err := io.Copy(io.MultiWriter(f, h), r)
+	if err == nil {
+		err = f.Close()
+	} else {
+		_ = f.Close()
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+		return Descriptor{}, fmt.Errorf("write blob error: %w", err)
+	}
+
+	desc := Descriptor{MediaType: mediaType, Digest: digestOf(h), Size: size}
+	if err := os.Rename(f.Name(), l.blobPath(desc.Digest)); err != nil {
+		_ = os.Remove(f.Name())
+		return Descriptor{}, fmt.Errorf("rename blob error: %w", err)
+	}
+	return desc, nil
+}
+
+// WriteJSON 将 v 以 JSON 格式写入，返回其描述符
+func (l *defaultLayout) WriteJSON(mediaType string, v any) (Descriptor, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return Descriptor{}, fmt.Errorf("marshal %s error: %w", mediaType, err)
+	}
+	return l.WriteBlob(mediaType, bytes.NewReader(data))
+}
+
+// WriteLayer 将 write 写入的 tar 以 gzip 压缩后作为镜像层写入，返回其描述符和未压缩的 tar 的摘要
+func (l *defaultLayout) WriteLayer(write func(w io.Writer) error) (Descriptor, string, error) {
+	pr, pw := io.Pipe()
+	diffID := sha256.New()
+	go func() {
+		gw := gzip.NewWriter(pw)
+		err := write(io.MultiWriter(gw, diffID))
+		if err == nil {
+			err = gw.Close()
+		}
+		_ = pw.CloseWithError(err)
+	}()
+	desc, err := l.WriteBlob(MediaTypeLayerGzip, pr)
+	if err != nil {
+		// 让写入的协程退出
+		_ = pr.CloseWithError(err)
+		return Descriptor{}, "", err
+	}
+	return desc, digestOf(diffID), nil
+}
+
+// OpenBlob 打开内容，读取到结尾时校验大小和摘要，不一致时返回错误
+func (l *defaultLayout) OpenBlob(desc Descriptor) (io.ReadCloser, error) {
+	if !strings.HasPrefix(desc.Digest, digestPrefix) {
+		return nil, fmt.Errorf("unsupported digest %q", desc.Digest)
+	}
+	if _, err := hex.DecodeString(strings.TrimPrefix(desc.Digest, digestPrefix)); err != nil {
+		return nil, fmt.Errorf("invalid digest %q: %w", desc.Digest, err)
+	}
+	f, err := os.Open(l.blobPath(desc.Digest))
+	if err != nil {
+		return nil, fmt.Errorf("open blob %q error: %w", desc.Digest, err)
+	}
+	return &verifiedReader{file: f, desc: desc, hash: sha256.New()}, nil
+}
+
+// ReadJSON 读取 JSON 格式的内容到 v
+func (l *defaultLayout) ReadJSON(desc Descriptor, v any) error {
+	r, err := l.OpenBlob(desc)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = r.Close() }()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("unmarshal %s %q error: %w", desc.MediaType, desc.Digest, err)
+	}
+	return nil
+}
+
+// ReadIndex 读取 index.json
+func (l *defaultLayout) ReadIndex() (*Index, error) {
+	data, err := os.ReadFile(filepath.Join(l.dir, layoutFileName))
+	if err != nil {
+		return nil, fmt.Errorf("read %s error (is %q an OCI image layout?): %w", layoutFileName, l.dir, err)
+	}
+	layout := &ImageLayout{}
+	if err := json.Unmarshal(data, layout); err != nil {
+		return nil, fmt.Errorf("unmarshal %s error: %w", layoutFileName, err)
+	}
+	if layout.Version != ImageLayoutVersion {
+		return nil, fmt.Errorf("unsupported image layout version %q", layout.Version)
+	}
+
+	data, err = os.ReadFile(filepath.Join(l.dir, indexFileName))
+	if err != nil {
+		return nil, fmt.Errorf("read %s error: %w", indexFileName, err)
+	}
+	index := &Index{}
+	if err := json.Unmarshal(data, index); err != nil {
+		return nil, fmt.Errorf("unmarshal %s error: %w", indexFileName, err)
+	}
+	return index, nil
+}
+
+// WriteIndex 写入 index.json
+func (l *defaultLayout) WriteIndex(index *Index) error {
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal %s error: %w", indexFileName, err)
+	}
+	if err := fsutil.WriteFileAtomic(filepath.Join(l.dir, indexFileName), data, 0644); err != nil {
+		return fmt.Errorf("write %s error: %w", indexFileName, err)
+	}
+	return nil
+}
+
+// GetManifest 获取引用名为 ref 的镜像清单， ref 为空时要求布局中只有一个镜像
+func (l *defaultLayout) GetManifest(ref string) (Descriptor, *Manifest, error) {
+	index, err := l.ReadIndex()
+	if err != nil {
+		return Descriptor{}, nil, err
+	}
+
+	var found []Descriptor
+	var refs []string
+	for _, desc := range index.Manifests {
+		name := desc.Annotations[AnnotationRefName]
+		if name != "" {
+			refs = append(refs, name)
+		}
+		if ref == "" || name == ref {
+			found = append(found, desc)
+		}
+	}
+	switch {
+	case len(found) == 0 && ref == "":
+		return Descriptor{}, nil, fmt.Errorf("no image found in %q", l.dir)
+	case len(found) == 0:
+		return Descriptor{}, nil, fmt.Errorf("image %q not found in %q, available: %v", ref, l.dir, refs)
+	case len(found) > 1:
+		return Descriptor{}, nil, fmt.Errorf("%d images found in %q, specify one of %v", len(found), l.dir, refs)
+	}
+
+	desc := found[0]
+	if desc.MediaType != MediaTypeImageManifest && desc.MediaType != MediaTypeDockerManifest {
+		return Descriptor{}, nil, fmt.Errorf("unsupported media type %q of image %q", desc.MediaType, desc.Digest)
+	}
+	manifest := &Manifest{}
+	if err := l.ReadJSON(desc, manifest); err != nil {
+		return Descriptor{}, nil, err
+	}
+	return desc, manifest, nil
+}
+
+// blobPath 返回内容的存储路径
+func (l *defaultLayout) blobPath(digest string) string {
+	return filepath.Join(l.dir, blobsDirName, "sha256", strings.TrimPrefix(digest, digestPrefix))
+}
+
+// digestOf 返回 h 中已写入内容的摘要
+func digestOf(h hash.Hash) string {
+	return digestPrefix + hex.EncodeToString(h.Sum(nil))
+}
+
+// verifiedReader 读取到结尾时校验大小和摘要的 io.ReadCloser
+type verifiedReader struct {
+	file *os.File
+	desc Descriptor
+	hash hash.Hash
+	size int64
+}
+
+// Read 读取内容
+func (r *verifiedReader) Read(p []byte) (int, error) {
+	n, err := r.file.Read(p)
+	r.hash.Write(p[:n])
+	r.size += int64(n)
+	if errors.Is(err, io.EOF) {
+		if r.size != r.desc.Size {
+			return n, fmt.Errorf("size of blob %q mismatch, expected %d, got %d", r.desc.Digest, r.desc.Size, r.size)
+		}
+		if digest := digestOf(r.hash); digest != r.desc.Digest {
+			return n, fmt.Errorf("digest of blob %q mismatch, got %q", r.desc.Digest, digest)
+		}
+	}
+	return n, err
+}
+
+// Close 关闭文件
+func (r *verifiedReader) Close() error {
+	return r.file.Close()
+}
@@ -0,0 +1,69 @@
+package oci
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestLayout 测试 defaultLayout 的读写
+func TestLayout(t *testing.T) {
+	l := NewLayout(filepath.Join(t.TempDir(), "image"))
+	if err := l.Init(); err != nil {
+		t.Fatalf("init error: %v", err)
+	}
+
+	layer, diffID, err := l.WriteLayer(func(w io.Writer) error {
+		_, err := w.Write([]byte("not really a tar"))
+		return err
+	})
+	if err != nil {
+		t.Fatalf("write layer error: %v", err)
+	}
+	if diffID == layer.Digest {
+		t.Errorf("expected diff ID to differ from the digest of the compressed layer")
+	}
+	config, err := l.WriteJSON(MediaTypeImageConfig, ImageConfig{
+		OS:     "linux",
+		RootFS: RootFS{Type: "layers", DiffIDs: []string{diffID}},
+	})
+	if err != nil {
+		t.Fatalf("write config error: %v", err)
+	}
+	manifest, err := l.WriteJSON(MediaTypeImageManifest, Manifest{
+		SchemaVersion: 2,
+		MediaType:     MediaTypeImageManifest,
+		Config:        config,
+		Layers:        []Descriptor{layer},
+	})
+	if err != nil {
+		t.Fatalf("write manifest error: %v", err)
+	}
+	manifest.Annotations = map[string]string{AnnotationRefName: "latest"}
+	if err := l.WriteIndex(&Index{SchemaVersion: 2, Manifests: []Descriptor{manifest}}); err != nil {
+		t.Fatalf("write index error: %v", err)
+	}
+
+	for _, ref := range []string{"", "latest"} {
+		desc, m, err := l.GetManifest(ref)
+		if err != nil {
+			t.Fatalf("get manifest %q error: %v", ref, err)
+		}
+		if desc.Digest != manifest.Digest || len(m.Layers) != 1 || m.Layers[0].Digest != layer.Digest {
+			t.Errorf("unexpected manifest of %q: %+v", ref, m)
+		}
+	}
+	if _, _, err := l.GetManifest("v1"); err == nil {
+		t.Errorf("expected an error for missing ref")
+	}
+
+	// 内容被篡改时读取失败
+	blob := filepath.Join(l.Dir(), "blobs", "sha256", config.Digest[len("sha256:"):])
+	if err := os.WriteFile(blob, []byte("{}"), 0644); err != nil {
+		t.Fatalf("write blob error: %v", err)
+	}
+	if err := l.ReadJSON(config, &ImageConfig{}); err == nil {
+		t.Errorf("expected an error for tampered blob")
+	}
+}
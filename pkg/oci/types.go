@@ -0,0 +1,98 @@
+package oci
+
+import "time"
+
+// 媒体类型
+const (
+	MediaTypeImageIndex    = "application/vnd.oci.image.index.v1+json"
+	MediaTypeImageManifest = "application/vnd.oci.image.manifest.v1+json"
+	MediaTypeImageConfig   = "application/vnd.oci.image.config.v1+json"
+	MediaTypeLayer         = "application/vnd.oci.image.layer.v1.tar"
+	MediaTypeLayerGzip     = "application/vnd.oci.image.layer.v1.tar+gzip"
+	MediaTypeLayerZstd     = "application/vnd.oci.image.layer.v1.tar+zstd"
+
+	// Docker 镜像的媒体类型，一些工具导出的 OCI 布局中仍然使用
+	MediaTypeDockerManifest  = "application/vnd.docker.distribution.manifest.v2+json"
+	MediaTypeDockerLayerGzip = "application/vnd.docker.image.rootfs.diff.tar.gzip"
+)
+
+// 注解
+const (
+	// AnnotationRefName 镜像布局 index.json 中镜像的引用名
+	AnnotationRefName = "org.opencontainers.image.ref.name"
+	// AnnotationCreated 镜像创建时间
+	AnnotationCreated = "org.opencontainers.image.created"
+)
+
+// Descriptor 内容描述符
+type Descriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	Platform    *Platform         `json:"platform,omitempty"`
+}
+
+// Platform 镜像适用的平台
+type Platform struct {
+	Architecture string `json:"architecture"`
+	OS           string `json:"os"`
+}
+
+// Index 镜像索引，即镜像布局中的 index.json
+type Index struct {
+	SchemaVersion int               `json:"schemaVersion"`
+	MediaType     string            `json:"mediaType,omitempty"`
+	Manifests     []Descriptor      `json:"manifests"`
+	Annotations   map[string]string `json:"annotations,omitempty"`
+}
+
+// Manifest 镜像清单
+type Manifest struct {
+	SchemaVersion int               `json:"schemaVersion"`
+	MediaType     string            `json:"mediaType,omitempty"`
+	Config        Descriptor        `json:"config"`
+	Layers        []Descriptor      `json:"layers"`
+	Annotations   map[string]string `json:"annotations,omitempty"`
+}
+
+// ImageConfig 镜像配置，仅包含 stackcrisp 使用的字段
+type ImageConfig struct {
+	Created      *time.Time `json:"created,omitempty"`
+	Architecture string     `json:"architecture"`
+	OS           string     `json:"os"`
+	RootFS       RootFS     `json:"rootfs"`
+	History      []History  `json:"history,omitempty"`
+}
+
+// RootFS 镜像的层
+type RootFS struct {
+	Type string `json:"type"`
+	// 各层未压缩 tar 的摘要，第 0 个元素是最底层
+	DiffIDs []string `json:"diff_ids"`
+}
+
+// History 镜像每一层的构建历史
+type History struct {
+	Created    *time.Time `json:"created,omitempty"`
+	CreatedBy  string     `json:"created_by,omitempty"`
+	Comment    string     `json:"comment,omitempty"`
+	EmptyLayer bool       `json:"empty_layer,omitempty"`
+}
+
+// ImageLayout 镜像布局目录中的 oci-layout 文件
+type ImageLayout struct {
+	Version string `json:"imageLayoutVersion"`
+}
+
+// ImageLayoutVersion 支持的镜像布局版本
+const ImageLayoutVersion = "1.0.0"
+
+// IsLayerMediaType 返回媒体类型是否支持导入的镜像层
+func IsLayerMediaType(mediaType string) bool {
+	switch mediaType {
+	case MediaTypeLayer, MediaTypeLayerGzip, MediaTypeLayerZstd, MediaTypeDockerLayerGzip:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,28 @@
+package reflogs
+
+import "context"
+
+type contextKeyCommand struct{}
+type contextKeyMessage struct{}
+
+// NewContextWithCommand 将导致引用移动的命令注入到上下文中
+func NewContextWithCommand(parent context.Context, command string) context.Context {
+	return context.WithValue(parent, contextKeyCommand{}, command)
+}
+
+// CommandFromContext 从上下文获取导致引用移动的命令
+func CommandFromContext(ctx context.Context) string {
+	command, _ := ctx.Value(contextKeyCommand{}).(string)
+	return command
+}
+
+// NewContextWithMessage 将引用移动的说明注入到上下文中
+func NewContextWithMessage(parent context.Context, message string) context.Context {
+	return context.WithValue(parent, contextKeyMessage{}, message)
+}
+
+// MessageFromContext 从上下文获取引用移动的说明
+func MessageFromContext(ctx context.Context) string {
+	message, _ := ctx.Value(contextKeyMessage{}).(string)
+	return message
+}
@@ -0,0 +1,112 @@
+package reflogs
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Entry reflog 条目
+type Entry struct {
+	// 移动前指向的节点 ID ，新建时为空
+	Old string `json:"old,omitempty"`
+	// 移动后指向的节点 ID ，删除时为空
+	New string `json:"new,omitempty"`
+	// 移动发生的时间
+	Time time.Time `json:"time"`
+	// 导致移动的命令
+	Command string `json:"command,omitempty"`
+	// 说明
+	Message string `json:"message,omitempty"`
+}
+
+// NewEntry 创建一个 reflog 条目，命令和说明从上下文中获取
+func NewEntry(ctx context.Context, oldID, newID string) Entry {
+	return Entry{
+		Old:     oldID,
+		New:     newID,
+		Time:    time.Now(),
+		Command: CommandFromContext(ctx),
+		Message: MessageFromContext(ctx),
+	}
+}
+
+// Reflog 记录一个引用所有移动的只追加日志
+type Reflog interface {
+	// Append 追加条目
+	Append(entry Entry) error
+	// Entries 返回所有条目，最新的在前
+	Entries() ([]Entry, error)
+}
+
+// New 创建一个存储在指定文件的 Reflog
+func New(path string) Reflog {
+	return &fileReflog{path: path}
+}
+
+// fileReflog 是 Reflog 的一个实现，以每行一个 json 对象的形式存储在文件中
+type fileReflog struct {
+	path string
+}
+
+var _ Reflog = &fileReflog{}
+
+// Append 追加条目
+func (l *fileReflog) Append(entry Entry) error {
+	raw, err := json.Marshal(&entry)
+	if err != nil {
+		return fmt.Errorf("marshal reflog entry to json error: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+		return fmt.Errorf("make directory for reflog %q error: %w", l.path, err)
+	}
+	f, err := os.OpenFile(l.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("open reflog %q error: %w", l.path, err)
+	}
+	if _, err := f.Write(append(raw, '\n')); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("write reflog %q error: %w", l.path, err)
+	}
+	return f.Close()
+}
+
+// Entries 返回所有条目，最新的在前
+func (l *fileReflog) Entries() ([]Entry, error) {
+	f, err := os.Open(l.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("open reflog %q error: %w", l.path, err)
+	}
+	defer func() { _ = f.Close() }()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			// 跳过写了一半的行
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read reflog %q error: %w", l.path, err)
+	}
+
+	// 最新的在前
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+	return entries, nil
+}
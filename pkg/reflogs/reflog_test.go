@@ -0,0 +1,52 @@
+package reflogs
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+// TestFileReflog 测试 fileReflog
+func TestFileReflog(t *testing.T) {
+	reflog := New(filepath.Join(t.TempDir(), "logs", "HEAD"))
+
+	// 不存在时没有条目
+	entries, err := reflog.Entries()
+	if err != nil {
+		t.Fatalf("get entries error: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("expected no entries, got %d", len(entries))
+	}
+
+	ctx := NewContextWithCommand(context.Background(), "commit")
+	for _, item := range []struct{ old, new, message string }{
+		{"", "a", "first"},
+		{"a", "b", "second"},
+		{"b", "", "third"},
+	} {
+		if err := reflog.Append(NewEntry(NewContextWithMessage(ctx, item.message), item.old, item.new)); err != nil {
+			t.Fatalf("append entry error: %v", err)
+		}
+	}
+
+	// 最新的在前
+	entries, err = reflog.Entries()
+	if err != nil {
+		t.Fatalf("get entries error: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 entries, got %d", len(entries))
+	}
+	for i, expected := range []string{"third", "second", "first"} {
+		if entries[i].Message != expected {
+			t.Errorf("entries[%d]: expected message %q, got %q", i, expected, entries[i].Message)
+		}
+		if entries[i].Command != "commit" {
+			t.Errorf("entries[%d]: expected command \"commit\", got %q", i, entries[i].Command)
+		}
+	}
+	if entries[0].Old != "b" || entries[0].New != "" {
+		t.Errorf("unexpected entries[0]: %#v", entries[0])
+	}
+}
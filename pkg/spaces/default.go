@@ -5,42 +5,57 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
 	"slices"
+	"time"
 
 	"github.com/go-logr/logr"
 
 	"github.com/yhlooo/stackcrisp/pkg/layers"
 	"github.com/yhlooo/stackcrisp/pkg/mounts"
+	"github.com/yhlooo/stackcrisp/pkg/reflogs"
 	"github.com/yhlooo/stackcrisp/pkg/spaces/trees"
+	fsutil "github.com/yhlooo/stackcrisp/pkg/utils/fs"
 	"github.com/yhlooo/stackcrisp/pkg/utils/uid"
 )
 
 const (
-	spaceDataSubPathTree = "tree.json"
-	loggerName           = "spaces"
+	spaceDataSubPathTree          = "tree.json"
+	spaceDataSubPathHeadReflogs   = "logs/heads"
+	spaceDataSubPathBranchReflogs = "logs/branches"
+	spaceDataSubPathFlattened     = "flattened"
+	loggerName                    = "spaces"
 
 	// RootTag 根节点标签
 	RootTag = "ROOT"
 )
 
 // New 创建一个 Space
-func New(id uid.UID, spaceDataRoot string, layerManager layers.LayerManager) Space {
+func New(id uid.UID, layerManager layers.LayerManager, opts SpaceOptions) Space {
 	return &defaultSpace{
-		id:            id,
-		spaceDataRoot: spaceDataRoot,
-		layerManger:   layerManager,
+		id:               id,
+		spaceDataRoot:    opts.SpaceDataRoot,
+		flattenThreshold: opts.FlattenThreshold,
+		layerManger:      layerManager,
 	}
 }
 
 // defaultSpace 是 Space 的一个默认实现
 type defaultSpace struct {
-	id            uid.UID
-	spaceDataRoot string
+	id               uid.UID
+	spaceDataRoot    string
+	flattenThreshold int
 
 	layerTree   trees.Tree
 	layerManger layers.LayerManager
+
+	meta        Meta
+	metaChanged bool
+
+	// 上次加载或保存时各分支头指针的 ID ，用于在保存时记录分支移动
+	savedBranches map[string]string
 }
 
 var _ Space = &defaultSpace{}
@@ -75,6 +90,9 @@ func (space *defaultSpace) Init(ctx context.Context) error {
 	if err := space.layerTree.AddTag(RootTag, rootLayer.ID()); err != nil {
 		return fmt.Errorf("add root tag error: %w", err)
 	}
+	space.savedBranches = nil
+	space.meta = Meta{CreationTime: time.Now()}
+	space.metaChanged = true
 	return nil
 }
 
@@ -98,6 +116,12 @@ func (space *defaultSpace) Load(ctx context.Context) error {
 	if err != nil {
 		return fmt.Errorf("load tree from dump error: %w", err)
 	}
+	space.savedBranches = branchHeads(space.layerTree)
+
+	// 读取元信息
+	if err := space.loadMeta(ctx); err != nil {
+		return err
+	}
 
 	return nil
 }
@@ -116,13 +140,84 @@ func (space *defaultSpace) Save(ctx context.Context) error {
 	}
 	// 写文件
 	logger.V(1).Info(fmt.Sprintf("writing tree dump to %q ...", space.treeDumpSavePath()))
-	if err := os.WriteFile(space.treeDumpSavePath(), raw, 0644); err != nil {
+	if err := fsutil.WriteFileAtomic(space.treeDumpSavePath(), raw, 0644); err != nil {
 		return fmt.Errorf("write tree dump error: %w", err)
 	}
+	// 写元信息
+	if err := space.saveMeta(ctx); err != nil {
+		return err
+	}
+
+	// 记录分支移动
+	branches := branchHeads(space.layerTree)
+	for name, oldID := range space.savedBranches {
+		if _, ok := branches[name]; !ok {
+			space.appendBranchReflog(ctx, name, oldID, "")
+		}
+	}
+	for name, newID := range branches {
+		if oldID := space.savedBranches[name]; oldID != newID {
+			space.appendBranchReflog(ctx, name, oldID, newID)
+		}
+	}
+	space.savedBranches = branches
 
 	return nil
 }
 
+// Snapshot 返回已经持久化的数据的快照
+func (space *defaultSpace) Snapshot() ([]byte, error) {
+	raw, err := os.ReadFile(space.treeDumpSavePath())
+	if err != nil {
+		return nil, fmt.Errorf("read tree dump error: %w", err)
+	}
+	return raw, nil
+}
+
+// RestoreSnapshot 将持久化的数据恢复到快照时的状态，并重新加载
+func (space *defaultSpace) RestoreSnapshot(ctx context.Context, snapshot []byte) error {
+	logger := logr.FromContextOrDiscard(ctx).WithName(loggerName)
+
+	logger.V(1).Info(fmt.Sprintf("restoring tree dump %q ...", space.treeDumpSavePath()))
+	if err := fsutil.WriteFileAtomic(space.treeDumpSavePath(), snapshot, 0644); err != nil {
+		return fmt.Errorf("write tree dump error: %w", err)
+	}
+	return space.Load(ctx)
+}
+
+// HeadReflog 返回指定工作空间头指针的 reflog
+func (space *defaultSpace) HeadReflog(wsID uid.UID) reflogs.Reflog {
+	return reflogs.New(filepath.Join(space.spaceDataRoot, spaceDataSubPathHeadReflogs, wsID.Base32()))
+}
+
+// BranchReflog 返回指定分支的 reflog
+//
+// 分支头指针的移动在 Save 时自动记录
+func (space *defaultSpace) BranchReflog(fullName string) reflogs.Reflog {
+	return reflogs.New(filepath.Join(
+		space.spaceDataRoot, spaceDataSubPathBranchReflogs,
+		url.PathEscape(fullName),
+	))
+}
+
+// appendBranchReflog 记录分支移动
+func (space *defaultSpace) appendBranchReflog(ctx context.Context, name, oldID, newID string) {
+	logger := logr.FromContextOrDiscard(ctx).WithName(loggerName)
+	if err := space.BranchReflog(name).Append(reflogs.NewEntry(ctx, oldID, newID)); err != nil {
+		// reflog 只是辅助信息，记录失败不影响操作
+		logger.Info(fmt.Sprintf("WARN append reflog of branch %q error: %v", name, err))
+	}
+}
+
+// branchHeads 返回树上各分支头指针的 ID
+func branchHeads(tree trees.Tree) map[string]string {
+	ret := map[string]string{}
+	for name, node := range tree.Branches() {
+		ret[name] = node.ID().Hex()
+	}
+	return ret
+}
+
 // CreateLayer 创建层
 func (space *defaultSpace) CreateLayer(ctx context.Context, base uid.UID) (trees.Node, error) {
 	logger := logr.FromContextOrDiscard(ctx).WithName(loggerName)
@@ -149,6 +244,30 @@ func (space *defaultSpace) CreateLayer(ctx context.Context, base uid.UID) (trees
 	return nextNode, nil
 }
 
+// GetLayers 获取从根节点到指定节点的所有层
+//
+// 返回结果中第 0 个元素是根节点对应层，第 n-1 个元素是指定节点对应层
+func (space *defaultSpace) GetLayers(ctx context.Context, nodeID uid.UID) ([]layers.Layer, error) {
+	node, ok := space.layerTree.Get(nodeID)
+	if !ok {
+		return nil, fmt.Errorf("layer %q not found", nodeID.Hex())
+	}
+
+	var layerSet []layers.Layer
+	cur := node
+	for cur != nil {
+		layer, err := space.layerManger.Get(ctx, cur.ID())
+		if err != nil {
+			return nil, fmt.Errorf("get layer %q error: %w", cur.ID(), err)
+		}
+		layerSet = append(layerSet, layer)
+		cur = cur.Parent()
+	}
+	slices.Reverse(layerSet)
+
+	return layerSet, nil
+}
+
 // CreateMount 创建一个该空间的挂载
 func (space *defaultSpace) CreateMount(
 	ctx context.Context,
@@ -164,18 +283,11 @@ func (space *defaultSpace) CreateMount(
 		return nil, nil, err
 	}
 
-	// 找到所有层
-	var layerSet []layers.Layer
-	cur := upperNode
-	for cur != nil {
-		layer, err := space.layerManger.Get(ctx, cur.ID())
-		if err != nil {
-			return nil, nil, fmt.Errorf("get layer %q error: %w", cur.ID(), err)
-		}
-		layerSet = append(layerSet, layer)
-		cur = cur.Parent()
+	// 找到挂载的层
+	layerSet, err := space.GetMountLayers(ctx, upperNode.ID())
+	if err != nil {
+		return nil, nil, err
 	}
-	slices.Reverse(layerSet)
 
 	logger.V(1).Info(fmt.Sprintf("mount layers: %v", layerSet))
 	mount, err := mounts.New(ctx, mountID, layerSet, mountOpts)
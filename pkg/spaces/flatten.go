@@ -0,0 +1,185 @@
+package spaces
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-logr/logr"
+
+	"github.com/yhlooo/stackcrisp/pkg/changes"
+	"github.com/yhlooo/stackcrisp/pkg/layers"
+	"github.com/yhlooo/stackcrisp/pkg/utils/uid"
+)
+
+// 扁平化基础层写入完成前的临时目录后缀
+const flattenedTempSuffix = ".tmp"
+
+// GetMountLayers 获取挂载指定节点时叠加的层，第 0 个元素是最底层
+//
+// 指定节点是挂载的 upper 层，其余是已提交的层。最深的已缓存的扁平化祖先层作为基础层，替换其对应的所有层。
+// 基础层之上的已提交层数超过阈值时，先将所有已提交层合并为新的基础层。
+// 合并需要复制整个文件系统， gc 会提前合并工作空间的头指针，通常挂载时不需要合并
+func (space *defaultSpace) GetMountLayers(ctx context.Context, nodeID uid.UID) ([]layers.Layer, error) {
+	layerSet, err := space.GetLayers(ctx, nodeID)
+	if err != nil {
+		return nil, err
+	}
+	if len(layerSet) < 2 {
+		return layerSet, nil
+	}
+
+	// 不合并时层数太多可能无法挂载（ Linux 6.8 之前受挂载参数长度限制）
+	parent := layerSet[len(layerSet)-2].ID()
+	needed, err := space.NeedsFlatten(ctx, parent)
+	if err != nil {
+		return nil, err
+	}
+	if needed {
+		base, err := space.Flatten(ctx, parent)
+		if err != nil {
+			return nil, err
+		}
+		return []layers.Layer{base, layerSet[len(layerSet)-1]}, nil
+	}
+
+	for i := len(layerSet) - 2; i >= 0; i-- {
+		if l, ok := space.getFlattened(layerSet[i].ID()); ok {
+			return append([]layers.Layer{l}, layerSet[i+1:]...), nil
+		}
+	}
+	return layerSet, nil
+}
+
+// NeedsFlatten 返回挂载指定节点的子节点时，基础层之上叠加的已提交层数是否超过阈值
+func (space *defaultSpace) NeedsFlatten(ctx context.Context, nodeID uid.UID) (bool, error) {
+	if space.flattenThreshold <= 0 {
+		return false, nil
+	}
+	layerSet, err := space.GetLayers(ctx, nodeID)
+	if err != nil {
+		return false, err
+	}
+	base := -1
+	for i := len(layerSet) - 1; i >= 0; i-- {
+		if _, ok := space.getFlattened(layerSet[i].ID()); ok {
+			base = i
+			break
+		}
+	}
+	return len(layerSet)-1-base > space.flattenThreshold, nil
+}
+
+// Flatten 将从根节点到指定节点的所有层合并为一个扁平化的基础层并缓存
+func (space *defaultSpace) Flatten(ctx context.Context, nodeID uid.UID) (layers.Layer, error) {
+	logger := logr.FromContextOrDiscard(ctx).WithName(loggerName)
+
+	if l, ok := space.getFlattened(nodeID); ok {
+		return l, nil
+	}
+	layerSet, err := space.GetLayers(ctx, nodeID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 从最深的已缓存的祖先基础层开始合并
+	var dirs []string
+	for i := len(layerSet) - 1; i >= 0; i-- {
+		if l, ok := space.getFlattened(layerSet[i].ID()); ok {
+			dirs = append(dirs, l.DiffDir())
+			layerSet = layerSet[i+1:]
+			break
+		}
+	}
+	for _, l := range layerSet {
+		dirs = append(dirs, l.DiffDir())
+	}
+
+	// 先写入临时目录，完成后再重命名，避免中断时留下不完整的基础层
+	logger.Info(fmt.Sprintf("flattening %d layers up to %s ...", len(dirs), nodeID.Hex()))
+	dataRoot := space.flattenedDataRoot(nodeID)
+	tmpDataRoot := dataRoot + flattenedTempSuffix
+	if err := os.RemoveAll(tmpDataRoot); err != nil {
+		return nil, fmt.Errorf("remove %q error: %w", tmpDataRoot, err)
+	}
+	if err := os.MkdirAll(tmpDataRoot, 0755); err != nil {
+		return nil, fmt.Errorf("make dir %q error: %w", tmpDataRoot, err)
+	}
+	tmp := layers.NewLayer(nodeID, tmpDataRoot)
+	if err := tmp.Save(); err != nil {
+		return nil, err
+	}
+	if err := changes.Flatten(changes.NewView(dirs), tmp.DiffDir()); err != nil {
+		_ = os.RemoveAll(tmpDataRoot)
+		return nil, fmt.Errorf("flatten layers error: %w", err)
+	}
+	if err := os.Rename(tmpDataRoot, dataRoot); err != nil {
+		_ = os.RemoveAll(tmpDataRoot)
+		return nil, fmt.Errorf("rename %q to %q error: %w", tmpDataRoot, dataRoot, err)
+	}
+
+	return layers.NewLayer(nodeID, dataRoot), nil
+}
+
+// ListFlattened 列出缓存的所有扁平化基础层，层 ID 即其对应的节点 ID
+func (space *defaultSpace) ListFlattened(ctx context.Context) ([]layers.Layer, error) {
+	logger := logr.FromContextOrDiscard(ctx).WithName(loggerName)
+
+	root := filepath.Join(space.spaceDataRoot, spaceDataSubPathFlattened)
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read dir %q error: %w", root, err)
+	}
+
+	var ret []layers.Layer
+	seen := map[string]bool{}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		// 被中断的合并留下的临时目录也算作对应节点的基础层，删除时一起删除
+		name := strings.TrimSuffix(entry.Name(), flattenedTempSuffix)
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		id, err := uid.DecodeUID128FromBase32(name)
+		if err != nil {
+			logger.Info(fmt.Sprintf("WARN unexpected dir in flattened layers data root: %q", filepath.Join(root, entry.Name())))
+			continue
+		}
+		ret = append(ret, layers.NewLayer(id, space.flattenedDataRoot(id)))
+	}
+	return ret, nil
+}
+
+// DeleteFlattened 删除指定节点对应的扁平化基础层
+func (space *defaultSpace) DeleteFlattened(_ context.Context, nodeID uid.UID) error {
+	dataRoot := space.flattenedDataRoot(nodeID)
+	for _, p := range []string{dataRoot, dataRoot + flattenedTempSuffix} {
+		if err := os.RemoveAll(p); err != nil {
+			return fmt.Errorf("remove flattened layer data %q error: %w", p, err)
+		}
+	}
+	return nil
+}
+
+// getFlattened 获取指定节点对应的已缓存的扁平化基础层
+func (space *defaultSpace) getFlattened(nodeID uid.UID) (layers.Layer, bool) {
+	l := layers.NewLayer(nodeID, space.flattenedDataRoot(nodeID))
+	if _, err := os.Stat(l.DiffDir()); err != nil {
+		return nil, false
+	}
+	return l, true
+}
+
+// flattenedDataRoot 返回指定节点对应的扁平化基础层数据目录
+func (space *defaultSpace) flattenedDataRoot(nodeID uid.UID) string {
+	return filepath.Join(space.spaceDataRoot, spaceDataSubPathFlattened, nodeID.Base32())
+}
@@ -0,0 +1,124 @@
+package spaces
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	fsutil "github.com/yhlooo/stackcrisp/pkg/utils/fs"
+	"github.com/yhlooo/stackcrisp/pkg/utils/uid"
+)
+
+const (
+	spaceDataSubPathMeta = "meta.json"
+)
+
+var (
+	nameRegexp = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]{0,62}$`)
+)
+
+// Meta 空间元信息
+type Meta struct {
+	// 空间名
+	Name string `json:"name,omitempty"`
+	// 创建时间
+	CreationTime time.Time `json:"creationTime,omitempty"`
+}
+
+// ValidateName 检查空间名是否合法
+//
+// 空间名由字母、数字、 . 、 _ 、 - 组成，以字母或数字开头，并且不能与空间 ID 的形式相同
+func ValidateName(name string) error {
+	if !nameRegexp.MatchString(name) {
+		return fmt.Errorf("invalid space name %q (not match %q)", name, nameRegexp.String())
+	}
+	if _, err := uid.DecodeUID128FromBase32(name); err == nil {
+		return fmt.Errorf("invalid space name %q: looks like a space id", name)
+	}
+	if _, err := uid.DecodeUID128FromHex(name); err == nil {
+		return fmt.Errorf("invalid space name %q: looks like a space id", name)
+	}
+	return nil
+}
+
+// ReadMeta 不加载空间，直接从空间数据存储根目录读取空间元信息
+//
+// 旧版本创建的空间没有元信息，此时返回空的元信息
+func ReadMeta(spaceDataRoot string) (Meta, error) {
+	var meta Meta
+	raw, err := os.ReadFile(filepath.Join(spaceDataRoot, spaceDataSubPathMeta))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return meta, nil
+		}
+		return meta, fmt.Errorf("read meta error: %w", err)
+	}
+	if err := json.Unmarshal(raw, &meta); err != nil {
+		return meta, fmt.Errorf("unmarshal meta from json error: %w", err)
+	}
+	return meta, nil
+}
+
+// Meta 返回空间元信息
+func (space *defaultSpace) Meta() Meta {
+	return space.meta
+}
+
+// SetName 设置空间名，在 Save 时持久化
+//
+// name 为空表示删除空间名
+func (space *defaultSpace) SetName(name string) error {
+	if name != "" {
+		if err := ValidateName(name); err != nil {
+			return err
+		}
+	}
+	space.meta.Name = name
+	space.metaChanged = true
+	return nil
+}
+
+// loadMeta 读取空间元信息，旧版本创建的空间没有元信息
+func (space *defaultSpace) loadMeta(ctx context.Context) error {
+	logger := logr.FromContextOrDiscard(ctx).WithName(loggerName)
+
+	logger.V(1).Info(fmt.Sprintf("reading meta from %q ...", space.metaSavePath()))
+	meta, err := ReadMeta(space.spaceDataRoot)
+	if err != nil {
+		return err
+	}
+	space.meta = meta
+	space.metaChanged = false
+	return nil
+}
+
+// saveMeta 在元信息被修改时将其持久化
+func (space *defaultSpace) saveMeta(ctx context.Context) error {
+	if !space.metaChanged {
+		return nil
+	}
+	logger := logr.FromContextOrDiscard(ctx).WithName(loggerName)
+
+	raw, err := json.Marshal(&space.meta)
+	if err != nil {
+		return fmt.Errorf("marshal meta to json error: %w", err)
+	}
+	logger.V(1).Info(fmt.Sprintf("writing meta to %q ...", space.metaSavePath()))
+	if err := fsutil.WriteFileAtomic(space.metaSavePath(), raw, 0644); err != nil {
+		return fmt.Errorf("write meta error: %w", err)
+	}
+	space.metaChanged = false
+	return nil
+}
+
+// metaSavePath 返回元信息存储路径
+func (space *defaultSpace) metaSavePath() string {
+	return filepath.Join(space.spaceDataRoot, spaceDataSubPathMeta)
+}
@@ -0,0 +1,20 @@
+package spaces
+
+import "testing"
+
+// TestValidateName 测试 ValidateName 方法
+func TestValidateName(t *testing.T) {
+	for _, name := range []string{"base", "ubuntu-22.04", "go_1.21", "A"} {
+		if err := ValidateName(name); err != nil {
+			t.Errorf("validate %q: unexpected error: %v", name, err)
+		}
+	}
+	for _, name := range []string{
+		"", "-base", ".hidden", "a/b", "with space",
+		"26NPW3NJFH5EAJ2ERXMFIXV3YE", "d79afb6da929fa4027448dd8545ebbc1",
+	} {
+		if err := ValidateName(name); err == nil {
+			t.Errorf("validate %q: expected an error", name)
+		}
+	}
+}
@@ -3,7 +3,9 @@ package spaces
 import (
 	"context"
 
+	"github.com/yhlooo/stackcrisp/pkg/layers"
 	"github.com/yhlooo/stackcrisp/pkg/mounts"
+	"github.com/yhlooo/stackcrisp/pkg/reflogs"
 	"github.com/yhlooo/stackcrisp/pkg/spaces/trees"
 	"github.com/yhlooo/stackcrisp/pkg/utils/uid"
 )
@@ -12,6 +14,8 @@ import (
 type SpaceOptions struct {
 	// 空间数据存储根目录
 	SpaceDataRoot string
+	// 挂载时最多叠加的已提交层数，超过时将深处的祖先层合并为一个扁平化的基础层， 0 表示不合并
+	FlattenThreshold int
 }
 
 // Space 存储空间
@@ -22,12 +26,44 @@ type Space interface {
 	ID() uid.UID
 	// Tree 返回记录层的树
 	Tree() trees.Tree
+	// Meta 返回空间元信息
+	Meta() Meta
+	// SetName 设置空间名，在 Save 时持久化，为空表示删除空间名
+	SetName(name string) error
 	// Init 初始化
 	Init(ctx context.Context) error
 	// Load 加载数据
 	Load(ctx context.Context) error
 	// Save 将数据持久化
 	Save(ctx context.Context) error
+	// Snapshot 返回已经持久化的数据的快照
+	Snapshot() ([]byte, error)
+	// RestoreSnapshot 将持久化的数据恢复到快照时的状态，并重新加载
+	RestoreSnapshot(ctx context.Context, snapshot []byte) error
+	// CreateLayer 基于指定节点创建一个新的层，并将其作为该节点的子节点加入树
+	CreateLayer(ctx context.Context, base uid.UID) (trees.Node, error)
+	// GetLayers 获取从根节点到指定节点的所有层，第 0 个元素是根节点对应层
+	GetLayers(ctx context.Context, nodeID uid.UID) ([]layers.Layer, error)
+	// HeadReflog 返回指定工作空间头指针的 reflog
+	HeadReflog(wsID uid.UID) reflogs.Reflog
+	// BranchReflog 返回指定分支的 reflog
+	//
+	// 分支头指针的移动在 Save 时自动记录
+	BranchReflog(fullName string) reflogs.Reflog
+	// GetMountLayers 获取挂载指定节点时叠加的层，第 0 个元素是最底层
+	//
+	// 深处的祖先层被替换为已缓存的扁平化基础层，已提交层数超过阈值时先合并
+	GetMountLayers(ctx context.Context, nodeID uid.UID) ([]layers.Layer, error)
+	// NeedsFlatten 返回挂载指定节点的子节点时，基础层之上叠加的已提交层数是否超过阈值
+	NeedsFlatten(ctx context.Context, nodeID uid.UID) (bool, error)
+	// Flatten 将从根节点到指定节点的所有层合并为一个扁平化的基础层并缓存
+	//
+	// 返回的层 ID 与指定节点 ID 相同。原来的各层不受影响，仍然用于历史和差异比较
+	Flatten(ctx context.Context, nodeID uid.UID) (layers.Layer, error)
+	// ListFlattened 列出缓存的所有扁平化基础层，层 ID 即其对应的节点 ID
+	ListFlattened(ctx context.Context) ([]layers.Layer, error)
+	// DeleteFlattened 删除指定节点对应的扁平化基础层
+	DeleteFlattened(ctx context.Context, nodeID uid.UID) error
 	// CreateMount 创建一个该空间的挂载
 	CreateMount(ctx context.Context, commit uid.UID, mountID uid.UID, mountOpts mounts.MountOptions) (mount mounts.Mount, head trees.Node, err error)
 }
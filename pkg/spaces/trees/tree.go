@@ -2,6 +2,8 @@ package trees
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 	"sync"
 
 	"github.com/yhlooo/stackcrisp/pkg/utils/uid"
@@ -25,6 +27,8 @@ type Tree interface {
 	GetByBranch(name string) (Node, bool)
 	// GetByTag 通过标签名获取节点
 	GetByTag(name string) (Node, bool)
+	// FindByIDPrefix 获取 ID 十六进制形式以 prefix 开头的所有节点
+	FindByIDPrefix(prefix string) []Node
 	// Root 获取根节点
 	Root() Node
 	// Tags 获取标签与节点的映射关系的一个只读副本
@@ -41,7 +45,15 @@ type Tree interface {
 	// 如果 parentID 为 nil 就是插入根节点
 	AddNode(parentID uid.UID, node Node) error
 
-	// TODO: DeleteNode(id uid.UID) bool
+	// DeleteNode 删除叶子节点，同时删除指向该节点的分支和标签
+	//
+	// 删除成功则返回 true 、节点不存在、是根节点或者不是叶子节点则返回 false
+	DeleteNode(id uid.UID) bool
+	// DeleteSubtree 删除以指定节点为根的子树
+	//
+	// 有分支或标签指向子树中的节点时，如果 force = false 则返回错误，否则同时删除这些分支和标签。
+	// 删除成功则返回被删除的所有节点
+	DeleteSubtree(id uid.UID, force bool) ([]Node, error)
 
 	// AddTag 添加标签
 	//
@@ -66,6 +78,22 @@ type Tree interface {
 	DeleteBranch(name string) bool
 }
 
+// Walk 广度优先遍历以 node 为根的子树中的所有节点
+func Walk(node Node, fn func(node Node)) {
+	if node == nil {
+		return
+	}
+	queue := []Node{node}
+	for len(queue) > 0 {
+		item := queue[0]
+		queue = queue[1:]
+		fn(item)
+		for _, child := range item.Children() {
+			queue = append(queue, child)
+		}
+	}
+}
+
 // NewTree 创建一个 Tree
 func NewTree() Tree {
 	return &defaultTree{}
@@ -111,6 +139,24 @@ func (tree *defaultTree) GetByTag(name string) (Node, bool) {
 	return node, ok
 }
 
+// FindByIDPrefix 获取 ID 十六进制形式以 prefix 开头的所有节点
+func (tree *defaultTree) FindByIDPrefix(prefix string) []Node {
+	tree.nodesLock.RLock()
+	defer tree.nodesLock.RUnlock()
+
+	prefix = strings.ToLower(prefix)
+	var ret []Node
+	for id, node := range tree.nodes {
+		if strings.HasPrefix(id, prefix) {
+			ret = append(ret, node)
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].ID().Hex() < ret[j].ID().Hex()
+	})
+	return ret
+}
+
 // Root 获取根节点
 func (tree *defaultTree) Root() Node {
 	tree.nodesLock.RLock()
@@ -216,6 +262,110 @@ func (tree *defaultTree) AddNode(parentID uid.UID, node Node) error {
 	return nil
 }
 
+// DeleteNode 删除叶子节点，同时删除指向该节点的分支和标签
+//
+// 删除成功则返回 true 、节点不存在、是根节点或者不是叶子节点则返回 false
+func (tree *defaultTree) DeleteNode(id uid.UID) bool {
+	tree.branchesLock.Lock()
+	defer tree.branchesLock.Unlock()
+	tree.tagsLock.Lock()
+	defer tree.tagsLock.Unlock()
+	tree.nodesLock.Lock()
+	defer tree.nodesLock.Unlock()
+
+	// 找到节点
+	node, ok := tree.nodes[id.Hex()]
+	if !ok || node.IsRoot() || !node.IsLeaf() {
+		return false
+	}
+
+	tree.deleteSubtree(node)
+	return true
+}
+
+// DeleteSubtree 删除以指定节点为根的子树
+//
+// 有分支或标签指向子树中的节点时，如果 force = false 则返回错误，否则同时删除这些分支和标签。
+// 删除成功则返回被删除的所有节点
+func (tree *defaultTree) DeleteSubtree(id uid.UID, force bool) ([]Node, error) {
+	tree.branchesLock.Lock()
+	defer tree.branchesLock.Unlock()
+	tree.tagsLock.Lock()
+	defer tree.tagsLock.Unlock()
+	tree.nodesLock.Lock()
+	defer tree.nodesLock.Unlock()
+
+	// 找到节点
+	node, ok := tree.nodes[id.Hex()]
+	if !ok {
+		return nil, fmt.Errorf("node %q not found", id.Hex())
+	}
+	if node.IsRoot() {
+		return nil, fmt.Errorf("root node %q can not be deleted", id.Hex())
+	}
+
+	// 检查指向子树的分支和标签
+	if !force {
+		inSubtree := map[string]bool{}
+		Walk(node, func(n Node) {
+			inSubtree[n.ID().Hex()] = true
+		})
+		var refs []string
+		for name, head := range tree.branches {
+			if inSubtree[head.ID().Hex()] {
+				refs = append(refs, fmt.Sprintf("branch %q", name))
+			}
+		}
+		for name, n := range tree.tags {
+			if inSubtree[n.ID().Hex()] {
+				refs = append(refs, fmt.Sprintf("tag %q", name))
+			}
+		}
+		if len(refs) > 0 {
+			sort.Strings(refs)
+			return nil, fmt.Errorf("subtree of %q is referenced by %s", id.Hex(), strings.Join(refs, ", "))
+		}
+	}
+
+	return tree.deleteSubtree(node), nil
+}
+
+// deleteSubtree 删除以指定节点为根的子树，同时删除指向子树中节点的分支和标签
+//
+// 调用者需要持有所有锁。返回被删除的所有节点
+func (tree *defaultTree) deleteSubtree(node Node) []Node {
+	var deleted []Node
+	Walk(node, func(n Node) {
+		deleted = append(deleted, n)
+	})
+	inSubtree := make(map[string]bool, len(deleted))
+	for _, n := range deleted {
+		inSubtree[n.ID().Hex()] = true
+	}
+
+	// 删除指向子树的分支和标签
+	for name, head := range tree.branches {
+		if inSubtree[head.ID().Hex()] {
+			delete(tree.branches, name)
+		}
+	}
+	for name, n := range tree.tags {
+		if inSubtree[n.ID().Hex()] {
+			delete(tree.tags, name)
+		}
+	}
+
+	// 解除与父节点的关系
+	node.Parent().DeleteChild(node.ID())
+	node.SetParent(nil)
+	// 更新索引
+	for _, n := range deleted {
+		delete(tree.nodes, n.ID().Hex())
+	}
+
+	return deleted
+}
+
 // AddTag 添加标签
 //
 // 可以覆盖同名标签
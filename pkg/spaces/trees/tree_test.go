@@ -0,0 +1,108 @@
+package trees
+
+import (
+	"testing"
+
+	"github.com/yhlooo/stackcrisp/pkg/utils/uid"
+)
+
+// newTestTree 创建一个用于测试的树
+//
+//	root - a - b - c
+//	         \
+//	          d
+func newTestTree(t *testing.T) (Tree, map[string]Node) {
+	tree := NewTree()
+	nodes := map[string]Node{}
+	for _, item := range []struct{ name, parent string }{
+		{"root", ""}, {"a", "root"}, {"b", "a"}, {"c", "b"}, {"d", "a"},
+	} {
+		node := NewNode(uid.NewUID128())
+		var parentID uid.UID
+		if item.parent != "" {
+			parentID = nodes[item.parent].ID()
+		}
+		if err := tree.AddNode(parentID, node); err != nil {
+			t.Fatalf("add node %q error: %v", item.name, err)
+		}
+		nodes[item.name] = node
+	}
+	return tree, nodes
+}
+
+// TestDefaultTree_DeleteNode 测试 defaultTree.DeleteNode 方法
+func TestDefaultTree_DeleteNode(t *testing.T) {
+	tree, nodes := newTestTree(t)
+	if err := tree.AddTag("v1", nodes["c"].ID()); err != nil {
+		t.Fatalf("add tag error: %v", err)
+	}
+
+	// 根节点和非叶子节点不能删除
+	for _, name := range []string{"root", "a", "b"} {
+		if tree.DeleteNode(nodes[name].ID()) {
+			t.Errorf("node %q deleted, expected not", name)
+		}
+	}
+
+	if !tree.DeleteNode(nodes["c"].ID()) {
+		t.Fatalf("node \"c\" not deleted")
+	}
+	if _, ok := tree.Get(nodes["c"].ID()); ok {
+		t.Errorf("node \"c\" still in tree")
+	}
+	if nodes["b"].HasChild(nodes["c"].ID()) {
+		t.Errorf("node \"c\" still a child of \"b\"")
+	}
+	if _, ok := tree.GetByTag("v1"); ok {
+		t.Errorf("tag \"v1\" still exists")
+	}
+	if tree.DeleteNode(nodes["c"].ID()) {
+		t.Errorf("node \"c\" deleted twice")
+	}
+}
+
+// TestDefaultTree_DeleteSubtree 测试 defaultTree.DeleteSubtree 方法
+func TestDefaultTree_DeleteSubtree(t *testing.T) {
+	tree, nodes := newTestTree(t)
+	if err := tree.AddBranch("main", nodes["d"].ID()); err != nil {
+		t.Fatalf("add branch error: %v", err)
+	}
+	if err := tree.AddBranch("exp", nodes["c"].ID()); err != nil {
+		t.Fatalf("add branch error: %v", err)
+	}
+
+	// 根节点不能删除
+	if _, err := tree.DeleteSubtree(nodes["root"].ID(), true); err == nil {
+		t.Errorf("expected an error when deleting root")
+	}
+	// 被分支引用时不能删除
+	if _, err := tree.DeleteSubtree(nodes["b"].ID(), false); err == nil {
+		t.Errorf("expected an error when deleting a referenced subtree")
+	}
+	if _, ok := tree.Get(nodes["c"].ID()); !ok {
+		t.Errorf("node \"c\" deleted by a failed deletion")
+	}
+
+	// 强制删除
+	deleted, err := tree.DeleteSubtree(nodes["b"].ID(), true)
+	if err != nil {
+		t.Fatalf("delete subtree error: %v", err)
+	}
+	if len(deleted) != 2 {
+		t.Errorf("expected 2 nodes deleted, got %d", len(deleted))
+	}
+	for _, name := range []string{"b", "c"} {
+		if _, ok := tree.Get(nodes[name].ID()); ok {
+			t.Errorf("node %q still in tree", name)
+		}
+	}
+	if _, ok := tree.GetByBranch("exp"); ok {
+		t.Errorf("branch \"exp\" still exists")
+	}
+	if _, ok := tree.GetByBranch("main"); !ok {
+		t.Errorf("branch \"main\" deleted")
+	}
+	if !nodes["a"].HasChild(nodes["d"].ID()) || nodes["a"].HasChild(nodes["b"].ID()) {
+		t.Errorf("unexpected children of node \"a\": %v", nodes["a"].Children())
+	}
+}
@@ -13,6 +13,7 @@ import (
 
 	"github.com/yhlooo/stackcrisp/pkg/commands/options"
 	"github.com/yhlooo/stackcrisp/pkg/manager"
+	"github.com/yhlooo/stackcrisp/pkg/reflogs"
 	logutil "github.com/yhlooo/stackcrisp/pkg/utils/log"
 	"github.com/yhlooo/stackcrisp/pkg/utils/sudo"
 )
@@ -83,7 +84,15 @@ func ChangeWorkingDirectory(cmd *cobra.Command, path string) error {
 	}()
 
 	if path == "" {
-		return nil
+		// 工作目录所在挂载可能已经被替换并延迟卸载（比如在工作空间中 commit 后），
+		// 此时无法获取工作目录，和 cd . 一样通过原路径重新进入
+		if _, err := os.Getwd(); err == nil {
+			return nil
+		}
+		path = os.Getenv("PWD")
+		if path == "" || !filepath.IsAbs(path) {
+			return nil
+		}
 	}
 
 	absPath, err := filepath.Abs(path)
@@ -112,19 +121,54 @@ func InjectManagerIfNecessary(cmd *cobra.Command, globalOptions options.GlobalOp
 	// 创建管理器
 	logger.V(1).Info(fmt.Sprintf("new manager, dataRoot: %q", globalOptions.GetDataRoot()))
 	mgr, err := manager.New(manager.Options{
-		DataRoot: globalOptions.GetDataRoot(),
-		ChownUID: globalOptions.GetUID(),
-		ChownGID: globalOptions.GetGID(),
+		DataRoot:         globalOptions.GetDataRoot(),
+		ChownUID:         globalOptions.GetUID(),
+		ChownGID:         globalOptions.GetGID(),
+		LockTimeout:      globalOptions.GetLockTimeout(),
+		FlattenThreshold: globalOptions.GetFlattenThreshold(),
+		// 不以 root 运行的命令不修改数据，也没有权限创建锁文件
+		SkipLocks: cmd.Annotations[AnnotationRunAsRoot] != AnnotationValueTrue,
 	})
 	if err != nil {
 		return fmt.Errorf("create manager error: %w", err)
 	}
+	// 记录引用移动和持有锁时需要知道当前执行的命令
+	cmd.SetContext(reflogs.NewContextWithCommand(cmd.Context(), cmd.Name()))
 	if err := mgr.Prepare(cmd.Context()); err != nil {
 		return fmt.Errorf("prepare manager error: %w", err)
 	}
+	// 处理上次被中断的操作，这可能需要挂载和卸载，所以仅以 root 运行时处理
+	if cmd.Annotations[AnnotationRunAsRoot] == AnnotationValueTrue {
+		if err := mgr.Recover(cmd.Context()); err != nil {
+			_ = mgr.Close(cmd.Context())
+			return fmt.Errorf("recover interrupted operations error: %w", err)
+		}
+		// 恢复时可能替换了工作目录所在的挂载，重新进入
+		if err := ChangeWorkingDirectory(cmd, ""); err != nil {
+			_ = mgr.Close(cmd.Context())
+			return err
+		}
+	}
 
 	// 注入到上下文
 	cmd.SetContext(NewContextWithManager(cmd.Context(), mgr))
 
 	return nil
 }
+
+// CloseManagerIfNecessary 如果注入了 manager.Manager 的话关闭它，释放其持有的锁
+//
+// 命令出错时不会执行 PersistentPostRunE ，需要在命令返回后对执行的命令调用
+func CloseManagerIfNecessary(cmd *cobra.Command) error {
+	if cmd.Annotations[AnnotationRequireManager] != AnnotationValueTrue {
+		return nil
+	}
+	mgr := ManagerFromContext(cmd.Context())
+	if mgr == nil {
+		return nil
+	}
+	if err := mgr.Close(cmd.Context()); err != nil {
+		return fmt.Errorf("close manager error: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package compress
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Format 压缩格式
+type Format string
+
+// Format 的合法值
+const (
+	None Format = "None"
+	Gzip Format = "Gzip"
+	// Zstd 标准库不支持 zstd ，通过 zstd 命令压缩和解压
+	Zstd Format = "Zstd"
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// FormatFromTarName 根据 tar 归档文件名后缀返回压缩格式
+//
+// 支持 .tar 、 .tar.gz 、 .tgz 、 .tar.zst 和 .tzst ，其它后缀返回错误
+func FormatFromTarName(path string) (Format, error) {
+	name := strings.ToLower(filepath.Base(path))
+	switch {
+	case strings.HasSuffix(name, ".tar.zst") || strings.HasSuffix(name, ".tzst"):
+		return Zstd, nil
+	case strings.HasSuffix(name, ".tar.gz") || strings.HasSuffix(name, ".tgz"):
+		return Gzip, nil
+	case strings.HasSuffix(name, ".tar"):
+		return None, nil
+	default:
+		return "", fmt.Errorf("unsupported archive format of %q, expected .tar, .tar.gz, .tgz, .tar.zst or .tzst", path)
+	}
+}
+
+// NewWriter 创建一个将内容以指定格式压缩后写入 w 的 io.WriteCloser
+//
+// 关闭时完成压缩，但不关闭 w
+func NewWriter(w io.Writer, format Format) (io.WriteCloser, error) {
+	switch format {
+	case None:
+		return nopWriteCloser{Writer: w}, nil
+	case Gzip:
+		return gzip.NewWriter(w), nil
+	case Zstd:
+		zstd := exec.Command("zstd", "-q", "-c")
+		zstd.Stdout = w
+		zstd.Stderr = os.Stderr
+		stdin, err := zstd.StdinPipe()
+		if err != nil {
+			return nil, fmt.Errorf("create stdin pipe of zstd error: %w", err)
+		}
+		if err := zstd.Start(); err != nil {
+			return nil, fmt.Errorf("start zstd error (is zstd installed?): %w", err)
+		}
+		return &cmdWriteCloser{WriteCloser: stdin, cmd: zstd}, nil
+	default:
+		return nil, fmt.Errorf("unsupported compression format %q", format)
+	}
+}
+
+// NewReader 创建一个读取 r 中内容的 io.ReadCloser ，根据内容头部识别并解压 gzip 或 zstd 压缩
+//
+// 关闭时不关闭 r
+func NewReader(r io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	magic, _ := br.Peek(len(zstdMagic))
+
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		gr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("read gzip header error: %w", err)
+		}
+		return gr, nil
+	case bytes.HasPrefix(magic, zstdMagic):
+		zstd := exec.Command("zstd", "-q", "-d", "-c")
+		zstd.Stdin = br
+		zstd.Stderr = os.Stderr
+		stdout, err := zstd.StdoutPipe()
+		if err != nil {
+			return nil, fmt.Errorf("create stdout pipe of zstd error: %w", err)
+		}
+		if err := zstd.Start(); err != nil {
+			return nil, fmt.Errorf("start zstd error (is zstd installed?): %w", err)
+		}
+		return &cmdReadCloser{ReadCloser: stdout, cmd: zstd}, nil
+	default:
+		return io.NopCloser(br), nil
+	}
+}
+
+// nopWriteCloser 关闭时什么也不做的 io.WriteCloser
+type nopWriteCloser struct {
+	io.Writer
+}
+
+// Close 什么也不做
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+// cmdWriteCloser 写入命令标准输入的 io.WriteCloser
+type cmdWriteCloser struct {
+	io.WriteCloser
+	cmd *exec.Cmd
+}
+
+// Close 关闭标准输入并等待命令退出
+func (w *cmdWriteCloser) Close() error {
+	if err := w.WriteCloser.Close(); err != nil {
+		_ = w.cmd.Wait()
+		return err
+	}
+	if err := w.cmd.Wait(); err != nil {
+		return fmt.Errorf("run %s error: %w", w.cmd.Path, err)
+	}
+	return nil
+}
+
+// cmdReadCloser 读取命令标准输出的 io.ReadCloser
+type cmdReadCloser struct {
+	io.ReadCloser
+	cmd *exec.Cmd
+}
+
+// Close 等待命令退出
+func (r *cmdReadCloser) Close() error {
+	// 提前关闭时命令可能阻塞在写入上，需要先关闭管道
+	_ = r.ReadCloser.Close()
+	_ = r.cmd.Wait()
+	return nil
+}
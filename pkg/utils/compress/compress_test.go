@@ -0,0 +1,56 @@
+package compress
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+// TestNewReader 测试 NewReader 方法
+func TestNewReader(t *testing.T) {
+	content := []byte("hello stackcrisp")
+	for _, format := range []Format{None, Gzip} {
+		buf := &bytes.Buffer{}
+		w, err := NewWriter(buf, format)
+		if err != nil {
+			t.Fatalf("new %s writer error: %v", format, err)
+		}
+		if _, err := w.Write(content); err != nil {
+			t.Fatalf("write %s error: %v", format, err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("close %s writer error: %v", format, err)
+		}
+
+		r, err := NewReader(buf)
+		if err != nil {
+			t.Fatalf("new reader of %s error: %v", format, err)
+		}
+		got, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatalf("read %s error: %v", format, err)
+		}
+		if !bytes.Equal(got, content) {
+			t.Errorf("unexpected content of %s: %q (expected %q)", format, got, content)
+		}
+	}
+}
+
+// TestFormatFromTarName 测试 FormatFromTarName 方法
+func TestFormatFromTarName(t *testing.T) {
+	cases := map[string]Format{
+		"a.tar":       None,
+		"a.tar.gz":    Gzip,
+		"/x/A.TGZ":    Gzip,
+		"a.tar.zst":   Zstd,
+		"rootfs.tzst": Zstd,
+	}
+	for name, expected := range cases {
+		if format, err := FormatFromTarName(name); err != nil || format != expected {
+			t.Errorf("format of %q: expected %s, got %s (%v)", name, expected, format, err)
+		}
+	}
+	if _, err := FormatFromTarName("a.zip"); err == nil {
+		t.Errorf("expected an error for a.zip")
+	}
+}
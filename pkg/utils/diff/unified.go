@@ -0,0 +1,285 @@
+package diff
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// opKind 编辑操作类型
+type opKind int
+
+const (
+	opEqual opKind = iota
+	opDelete
+	opInsert
+)
+
+// edit 编辑操作
+type edit struct {
+	kind opKind
+	// 操作前在 from 中的行序号
+	a int
+	// 操作前在 to 中的行序号
+	b int
+}
+
+// IsBinary 返回内容是否二进制
+//
+// 与 git 的判断方式一致，前 8000 个字节中包含 NUL 字节就认为是二进制
+func IsBinary(content []byte) bool {
+	if len(content) > 8000 {
+		content = content[:8000]
+	}
+	return bytes.IndexByte(content, 0) >= 0
+}
+
+// Unified 生成 unified 格式的文本差异
+//
+// contextLines 是每处差异前后保留的上下文行数。内容相同时返回空字符串。
+func Unified(fromName, toName string, from, to []byte, contextLines int) string {
+	if bytes.Equal(from, to) {
+		return ""
+	}
+	if contextLines < 0 {
+		contextLines = 0
+	}
+
+	a := splitLines(from)
+	b := splitLines(to)
+	edits := myers(a, b)
+
+	out := &strings.Builder{}
+	_, _ = fmt.Fprintf(out, "--- %s\n+++ %s\n", fromName, toName)
+	for _, h := range splitHunks(edits, contextLines) {
+		writeHunk(out, a, b, h)
+	}
+	return out.String()
+}
+
+// splitLines 将内容分割为行，每行包含结尾的换行符
+func splitLines(content []byte) []string {
+	if len(content) == 0 {
+		return nil
+	}
+	lines := strings.SplitAfter(string(content), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// maxEditDistance 计算最短编辑序列时最多尝试的编辑距离
+//
+// 回溯需要保存每一轮的状态，占用的内存与编辑距离的平方成正比，超过时退化为简单的全部删除再全部插入
+const maxEditDistance = 2000
+
+// myers 使用 Myers 差分算法计算从 a 到 b 的最短编辑序列
+//
+// 编辑距离超过 maxEditDistance 时返回的编辑序列不一定最短
+func myers(a, b []string) []edit {
+	n, m := len(a), len(b)
+	maxD := n + m
+	if maxD > maxEditDistance {
+		maxD = maxEditDistance
+	}
+	offset := maxD + 1
+	v := make([]int, 2*maxD+3)
+
+	// 记录每一轮开始前的状态，用于回溯。第 d 轮开始前只有 [-(d-1), d-1] 范围内的 k 是有效的，仅保存这部分
+	var trace [][]int
+	found := false
+	for d := 0; d <= maxD && !found; d++ {
+		if d == 0 {
+			trace = append(trace, nil)
+		} else {
+			trace = append(trace, append([]int(nil), v[offset-d+1:offset+d]...))
+		}
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				found = true
+				break
+			}
+		}
+	}
+
+	if !found {
+		return simpleEdits(a, b)
+	}
+
+	// 回溯
+	var edits []edit
+	x, y := n, m
+	for d := len(trace) - 1; d > 0; d-- {
+		// trace[d][i] 对应 k = i-(d-1)
+		prev := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && prev[k-1+d-1] < prev[k+1+d-1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := prev[prevK+d-1]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			edits = append(edits, edit{kind: opEqual, a: x, b: y})
+		}
+		if x == prevX {
+			y--
+			edits = append(edits, edit{kind: opInsert, a: x, b: y})
+		} else {
+			x--
+			edits = append(edits, edit{kind: opDelete, a: x, b: y})
+		}
+	}
+	for x > 0 && y > 0 {
+		x--
+		y--
+		edits = append(edits, edit{kind: opEqual, a: x, b: y})
+	}
+
+	// 翻转
+	for i, j := 0, len(edits)-1; i < j; i, j = i+1, j-1 {
+		edits[i], edits[j] = edits[j], edits[i]
+	}
+	return edits
+}
+
+// simpleEdits 返回保留共同的开头和结尾，删除 a 中其余的行再插入 b 中其余的行的编辑序列
+func simpleEdits(a, b []string) []edit {
+	n, m := len(a), len(b)
+	prefix := 0
+	for prefix < n && prefix < m && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < n-prefix && suffix < m-prefix && a[n-1-suffix] == b[m-1-suffix] {
+		suffix++
+	}
+
+	edits := make([]edit, 0, n+m-prefix-suffix)
+	for i := 0; i < prefix; i++ {
+		edits = append(edits, edit{kind: opEqual, a: i, b: i})
+	}
+	for i := prefix; i < n-suffix; i++ {
+		edits = append(edits, edit{kind: opDelete, a: i, b: prefix})
+	}
+	for j := prefix; j < m-suffix; j++ {
+		edits = append(edits, edit{kind: opInsert, a: n - suffix, b: j})
+	}
+	for i := 0; i < suffix; i++ {
+		edits = append(edits, edit{kind: opEqual, a: n - suffix + i, b: m - suffix + i})
+	}
+	return edits
+}
+
+// splitHunks 将编辑序列分割为带上下文的块
+func splitHunks(edits []edit, contextLines int) [][]edit {
+	var hunks [][]edit
+	i := 0
+	// 上一块的结束位置
+	prevEnd := 0
+	for i < len(edits) {
+		// 找到下一处变更
+		for i < len(edits) && edits[i].kind == opEqual {
+			i++
+		}
+		if i >= len(edits) {
+			break
+		}
+		start := i - contextLines
+		if start < prevEnd {
+			start = prevEnd
+		}
+
+		// 相距不超过两倍上下文的变更合并到同一块
+		end := i
+		for {
+			for end < len(edits) && edits[end].kind != opEqual {
+				end++
+			}
+			j := end
+			for j < len(edits) && edits[j].kind == opEqual {
+				j++
+			}
+			if j < len(edits) && j-end <= 2*contextLines {
+				end = j
+				continue
+			}
+			end += contextLines
+			if end > len(edits) {
+				end = len(edits)
+			}
+			break
+		}
+
+		hunks = append(hunks, edits[start:end])
+		i = end
+		prevEnd = end
+	}
+	return hunks
+}
+
+// writeHunk 输出一个块
+func writeHunk(out *strings.Builder, a, b []string, hunk []edit) {
+	fromCount, toCount := 0, 0
+	for _, e := range hunk {
+		switch e.kind {
+		case opEqual:
+			fromCount++
+			toCount++
+		case opDelete:
+			fromCount++
+		case opInsert:
+			toCount++
+		}
+	}
+	fromStart, toStart := hunk[0].a, hunk[0].b
+	if fromCount > 0 {
+		fromStart++
+	}
+	if toCount > 0 {
+		toStart++
+	}
+	_, _ = fmt.Fprintf(out, "@@ -%s +%s @@\n", hunkRange(fromStart, fromCount), hunkRange(toStart, toCount))
+
+	for _, e := range hunk {
+		var prefix, line string
+		switch e.kind {
+		case opEqual:
+			prefix, line = " ", a[e.a]
+		case opDelete:
+			prefix, line = "-", a[e.a]
+		case opInsert:
+			prefix, line = "+", b[e.b]
+		}
+		out.WriteString(prefix)
+		out.WriteString(line)
+		if !strings.HasSuffix(line, "\n") {
+			out.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
+
+// hunkRange 返回块头中的行范围
+func hunkRange(start, count int) string {
+	if count == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
@@ -0,0 +1,93 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// TestUnified 测试 Unified 方法
+func TestUnified(t *testing.T) {
+	cases := []struct {
+		from     string
+		to       string
+		expected string
+	}{
+		{"a\nb\nc\n", "a\nb\nc\n", ""},
+		{
+			"a\nb\nc\n", "a\nx\nc\n",
+			"--- a/f\n+++ b/f\n@@ -1,3 +1,3 @@\n a\n-b\n+x\n c\n",
+		},
+		{
+			"", "a\n",
+			"--- a/f\n+++ b/f\n@@ -0,0 +1 @@\n+a\n",
+		},
+		{
+			"a\n", "a",
+			"--- a/f\n+++ b/f\n@@ -1 +1 @@\n-a\n+a\n\\ No newline at end of file\n",
+		},
+		{
+			"1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n", "0\n1\n2\n3\n4\n5\n6\n7\n8\n9\n",
+			"--- a/f\n+++ b/f\n@@ -1,3 +1,4 @@\n+0\n 1\n 2\n 3\n@@ -7,4 +8,3 @@\n 7\n 8\n 9\n-10\n",
+		},
+	}
+
+	for i, c := range cases {
+		ret := Unified("a/f", "b/f", []byte(c.from), []byte(c.to), 3)
+		if ret != c.expected {
+			t.Errorf("unexpected result of the case %d: %q (expected: %q)", i, ret, c.expected)
+		}
+	}
+}
+
+// TestMyers 测试 myers 方法
+func TestMyers(t *testing.T) {
+	lines := func(prefix string, n int) []string {
+		ret := make([]string, n)
+		for i := range ret {
+			ret[i] = fmt.Sprintf("%s%d\n", prefix, i)
+		}
+		return ret
+	}
+	cases := []struct {
+		a, b     []string
+		expected int
+	}{
+		{[]string{"a", "b", "c"}, []string{"a", "x", "c"}, 2},
+		{nil, []string{"a"}, 1},
+		{[]string{"a", "b", "c", "a", "b", "b", "a"}, []string{"c", "b", "a", "b", "a", "c"}, 5},
+		// 超过 maxEditDistance 时退化为全部删除再全部插入
+		{
+			append(append([]string{"x"}, lines("a", maxEditDistance)...), "y"),
+			append(append([]string{"x"}, lines("b", maxEditDistance)...), "y"),
+			2 * maxEditDistance,
+		},
+	}
+
+	for i, c := range cases {
+		edits := myers(c.a, c.b)
+
+		// 按编辑序列还原 a 和 b
+		var a, b []string
+		changes := 0
+		for _, e := range edits {
+			switch e.kind {
+			case opEqual:
+				a = append(a, c.a[e.a])
+				b = append(b, c.b[e.b])
+			case opDelete:
+				a = append(a, c.a[e.a])
+				changes++
+			case opInsert:
+				b = append(b, c.b[e.b])
+				changes++
+			}
+		}
+		if strings.Join(a, "") != strings.Join(c.a, "") || strings.Join(b, "") != strings.Join(c.b, "") {
+			t.Errorf("unexpected edits of the case %d: %v", i, edits)
+		}
+		if changes != c.expected {
+			t.Errorf("unexpected number of changes of the case %d: %d (expected: %d)", i, changes, c.expected)
+		}
+	}
+}
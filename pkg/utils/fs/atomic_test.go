@@ -0,0 +1,47 @@
+package fs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestWriteFileAtomic 测试 WriteFileAtomic
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "file")
+
+	for _, content := range []string{"old", "new"} {
+		if err := WriteFileAtomic(path, []byte(content), 0600); err != nil {
+			t.Fatalf("write %q error: %v", content, err)
+		}
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("read file error: %v", err)
+		}
+		if string(raw) != content {
+			t.Errorf("expected content %q, got %q", content, string(raw))
+		}
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("stat file error: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("expected mode 0600, got %s", info.Mode().Perm())
+	}
+	// 不留下临时文件
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("read dir error: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("expected only the file in dir, got %d entries", len(entries))
+	}
+
+	// 目录不存在时出错
+	if err := WriteFileAtomic(filepath.Join(dir, "missing", "file"), []byte("x"), 0644); err == nil {
+		t.Errorf("expected an error when the dir does not exist")
+	}
+}
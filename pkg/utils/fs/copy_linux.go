@@ -0,0 +1,264 @@
+//go:build linux
+
+package fs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// CopyTree 将 src 目录中的内容复制到 dst 目录
+//
+// 保留文件类型（包括设备文件，因此 overlay whiteout 也会被保留）、权限、所有者、扩展属性、修改时间和硬链接关系。
+// dst 目录不存在时会被创建，已经存在的同名文件会被替换。
+func CopyTree(src, dst string) error {
+	c := NewCopier()
+	err := filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return fmt.Errorf("get info of %q error: %w", path, err)
+		}
+		return c.Copy(path, filepath.Join(dst, rel), info)
+	})
+	if err != nil {
+		return err
+	}
+	return c.Finish()
+}
+
+// Copier 逐个复制文件
+//
+// 与 CopyTree 一样保留文件类型、权限、所有者、扩展属性、修改时间和硬链接关系。
+type Copier struct {
+	// 不为 nil 时跳过使其返回 true 的扩展属性
+	SkipXattr func(name string) bool
+	// 以非特权用户复制，不保留所有者，并且忽略没有权限设置的扩展属性
+	Unprivileged bool
+
+	// 已复制的 inode 与目标路径，用于还原硬链接
+	inodes map[uint64]string
+	// 目录的时间需要在其中内容复制完后再设置
+	dirTimes []dirTime
+}
+
+// dirTime 待设置时间的目录
+type dirTime struct {
+	path string
+	stat *syscall.Stat_t
+}
+
+// NewCopier 创建一个 Copier
+func NewCopier() *Copier {
+	return &Copier{inodes: map[uint64]string{}}
+}
+
+// Copy 将文件 src 复制到 dst ， info 是 src 的文件信息（不穿透软链）
+//
+// 对于目录只创建目录本身，其中的内容需要逐个复制。 dst 的父目录需要已经存在，已经存在的同名文件会被替换。
+func (c *Copier) Copy(src, dst string, info os.FileInfo) error {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fmt.Errorf("unsupported file info of %q", src)
+	}
+
+	if info.IsDir() {
+		if err := os.Mkdir(dst, info.Mode().Perm()); err != nil && !errors.Is(err, os.ErrExist) {
+			return fmt.Errorf("mkdir %q error: %w", dst, err)
+		}
+		if err := c.copyMetadata(src, dst, info, stat); err != nil {
+			return err
+		}
+		c.dirTimes = append(c.dirTimes, dirTime{path: dst, stat: stat})
+		return nil
+	}
+
+	// 替换已经存在的文件
+	if err := os.Remove(dst); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("remove %q error: %w", dst, err)
+	}
+
+	// 硬链接
+	if stat.Nlink > 1 {
+		if linked, ok := c.inodes[stat.Ino]; ok {
+			if err := os.Link(linked, dst); err != nil {
+				return fmt.Errorf("link %q to %q error: %w", dst, linked, err)
+			}
+			return nil
+		}
+		c.inodes[stat.Ino] = dst
+	}
+
+	switch {
+	case info.Mode().IsRegular():
+		if err := copyFileContent(src, dst, info.Mode().Perm()); err != nil {
+			return err
+		}
+	case info.Mode()&os.ModeSymlink != 0:
+		link, err := os.Readlink(src)
+		if err != nil {
+			return fmt.Errorf("read link %q error: %w", src, err)
+		}
+		if err := os.Symlink(link, dst); err != nil {
+			return fmt.Errorf("create symlink %q error: %w", dst, err)
+		}
+	default:
+		// 设备、管道、套接字等
+		if err := syscall.Mknod(dst, stat.Mode, int(stat.Rdev)); err != nil {
+			return fmt.Errorf("mknod %q error: %w", dst, err)
+		}
+	}
+
+	if err := c.copyMetadata(src, dst, info, stat); err != nil {
+		return err
+	}
+	if info.Mode()&os.ModeSymlink == 0 {
+		if err := setTimes(dst, stat); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Finish 设置已复制目录的时间，需要在所有文件复制完后调用
+func (c *Copier) Finish() error {
+	// 从深到浅设置目录时间
+	for i := len(c.dirTimes) - 1; i >= 0; i-- {
+		if err := setTimes(c.dirTimes[i].path, c.dirTimes[i].stat); err != nil {
+			return err
+		}
+	}
+	c.dirTimes = nil
+	return nil
+}
+
+// copyFileContent 复制普通文件内容
+func copyFileContent(src, dst string, perm os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("open %q error: %w", src, err)
+	}
+	defer func() { _ = in.Close() }()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return fmt.Errorf("create %q error: %w", dst, err)
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return fmt.Errorf("copy %q to %q error: %w", src, dst, err)
+	}
+	return out.Close()
+}
+
+// copyMetadata 复制所有者、权限和扩展属性
+func (c *Copier) copyMetadata(src, dst string, info os.FileInfo, stat *syscall.Stat_t) error {
+	if !c.Unprivileged {
+		if err := os.Lchown(dst, int(stat.Uid), int(stat.Gid)); err != nil {
+			return fmt.Errorf("chown %q error: %w", dst, err)
+		}
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		// 软链的权限和扩展属性没有意义
+		return nil
+	}
+	// chown 会清除 setuid 等位，所以最后设置权限
+	if err := syscall.Chmod(dst, stat.Mode&07777); err != nil {
+		return fmt.Errorf("chmod %q error: %w", dst, err)
+	}
+	if err := copyXattrs(src, dst, c.SkipXattr); err != nil {
+		if c.Unprivileged && errors.Is(err, syscall.EPERM) {
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+// CopyXattrs 复制扩展属性（穿透软链）
+func CopyXattrs(src, dst string) error {
+	return copyXattrs(src, dst, nil)
+}
+
+// copyXattrs 复制扩展属性（穿透软链），跳过使 skip 返回 true 的扩展属性
+func copyXattrs(src, dst string, skip func(name string) bool) error {
+	names, err := ListXattrs(src)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if skip != nil && skip(name) {
+			continue
+		}
+		value, err := GetXattr(src, name)
+		if err != nil {
+			return err
+		}
+		if err := syscall.Setxattr(dst, name, value, 0); err != nil {
+			return fmt.Errorf("set xattr %q of %q error: %w", name, dst, err)
+		}
+	}
+	return nil
+}
+
+// ListXattrs 列出文件的扩展属性名（穿透软链）
+func ListXattrs(path string) ([]string, error) {
+	size, err := syscall.Listxattr(path, nil)
+	if err != nil {
+		if errors.Is(err, syscall.ENOTSUP) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("list xattrs of %q error: %w", path, err)
+	}
+	if size == 0 {
+		return nil, nil
+	}
+	buf := make([]byte, size)
+	size, err = syscall.Listxattr(path, buf)
+	if err != nil {
+		return nil, fmt.Errorf("list xattrs of %q error: %w", path, err)
+	}
+	var names []string
+	for _, name := range strings.Split(string(buf[:size]), "\x00") {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// GetXattr 获取文件的扩展属性值（穿透软链）
+func GetXattr(path, name string) ([]byte, error) {
+	size, err := syscall.Getxattr(path, name, nil)
+	if err != nil {
+		return nil, fmt.Errorf("get xattr %q of %q error: %w", name, path, err)
+	}
+	value := make([]byte, size)
+	if size == 0 {
+		return value, nil
+	}
+	size, err = syscall.Getxattr(path, name, value)
+	if err != nil {
+		return nil, fmt.Errorf("get xattr %q of %q error: %w", name, path, err)
+	}
+	return value[:size], nil
+}
+
+// setTimes 设置文件访问和修改时间（穿透软链）
+func setTimes(path string, stat *syscall.Stat_t) error {
+	if err := syscall.UtimesNano(path, []syscall.Timespec{stat.Atim, stat.Mtim}); err != nil {
+		return fmt.Errorf("set times of %q error: %w", path, err)
+	}
+	return nil
+}
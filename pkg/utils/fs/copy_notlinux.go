@@ -0,0 +1,52 @@
+//go:build !linux
+
+package fs
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+)
+
+// CopyTree 将 src 目录中的内容复制到 dst 目录
+func CopyTree(string, string) error {
+	return fmt.Errorf("copy tree is not supported on %s", runtime.GOOS)
+}
+
+// Copier 逐个复制文件
+type Copier struct {
+	// 不为 nil 时跳过使其返回 true 的扩展属性
+	SkipXattr func(name string) bool
+	// 以非特权用户复制，不保留所有者，并且忽略没有权限设置的扩展属性
+	Unprivileged bool
+}
+
+// NewCopier 创建一个 Copier
+func NewCopier() *Copier {
+	return &Copier{}
+}
+
+// Copy 将文件 src 复制到 dst ， info 是 src 的文件信息（不穿透软链）
+func (c *Copier) Copy(string, string, os.FileInfo) error {
+	return fmt.Errorf("copy file is not supported on %s", runtime.GOOS)
+}
+
+// Finish 设置已复制目录的时间，需要在所有文件复制完后调用
+func (c *Copier) Finish() error {
+	return nil
+}
+
+// CopyXattrs 复制扩展属性（穿透软链）
+func CopyXattrs(string, string) error {
+	return nil
+}
+
+// ListXattrs 列出文件的扩展属性名（穿透软链）
+func ListXattrs(string) ([]string, error) {
+	return nil, nil
+}
+
+// GetXattr 获取文件的扩展属性值（穿透软链）
+func GetXattr(_, name string) ([]byte, error) {
+	return nil, fmt.Errorf("get xattr %q is not supported on %s", name, runtime.GOOS)
+}
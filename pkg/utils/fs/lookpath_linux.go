@@ -0,0 +1,62 @@
+//go:build linux
+
+package fs
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// LookPathInRoot 在以 root 为根目录的文件系统中查找可执行文件 file ，返回其在该文件系统中的绝对路径
+//
+// file 不包含 / 时在 searchPath （以 : 分隔的目录列表）中查找，否则直接检查它。
+// 路径中的软链按 root 为根目录解析，不会指向 root 以外的文件
+func LookPathInRoot(root, file, searchPath string) (string, error) {
+	if strings.Contains(file, "/") {
+		p := path.Join("/", file)
+		if err := checkExecutableInRoot(root, p); err != nil {
+			return "", fmt.Errorf("%q: %w", file, err)
+		}
+		return p, nil
+	}
+	for _, dir := range strings.Split(searchPath, ":") {
+		if dir == "" {
+			continue
+		}
+		p := path.Join("/", dir, file)
+		if err := checkExecutableInRoot(root, p); err == nil {
+			return p, nil
+		}
+	}
+	return "", fmt.Errorf("%q: executable file not found in $PATH", file)
+}
+
+// checkExecutableInRoot 检查以 root 为根目录的文件系统中的 p 是否是可执行的普通文件
+func checkExecutableInRoot(root, p string) error {
+	rootFD, err := unix.Open(root, unix.O_PATH|unix.O_DIRECTORY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = unix.Close(rootFD) }()
+
+	fd, err := unix.Openat2(rootFD, p, &unix.OpenHow{
+		Flags:   unix.O_PATH | unix.O_CLOEXEC,
+		Resolve: unix.RESOLVE_IN_ROOT,
+	})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = unix.Close(fd) }()
+
+	var stat unix.Stat_t
+	if err := unix.Fstat(fd, &stat); err != nil {
+		return err
+	}
+	if stat.Mode&unix.S_IFMT != unix.S_IFREG || stat.Mode&0111 == 0 {
+		return fmt.Errorf("not an executable file")
+	}
+	return nil
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package fs
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// LookPathInRoot 在以 root 为根目录的文件系统中查找可执行文件 file ，返回其在该文件系统中的绝对路径
+func LookPathInRoot(string, string, string) (string, error) {
+	return "", fmt.Errorf("looking up path in root is not supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,58 @@
+package fs
+
+import (
+	"io/fs"
+	"path/filepath"
+)
+
+// Usage 目录占用的空间
+type Usage struct {
+	// 所有文件的总大小（字节）
+	Bytes int64 `json:"bytes"`
+	// 普通文件数
+	Files int64 `json:"files"`
+	// inode 数，包括目录、软链等所有类型的文件
+	Inodes int64 `json:"inodes"`
+}
+
+// DiskUsage 返回目录中所有文件的总大小（字节）
+//
+// 不穿透软链，同一文件的多个硬链接只计算一次（仅在支持的平台上）
+func DiskUsage(path string) (int64, error) {
+	usage, err := GetUsage(path)
+	return usage.Bytes, err
+}
+
+// GetUsage 统计目录占用的空间
+//
+// 不穿透软链，同一文件的多个硬链接只计算一次（仅在支持的平台上）
+func GetUsage(path string) (Usage, error) {
+	var usage Usage
+	seen := map[uint64]bool{}
+	err := filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			usage.Inodes++
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if ino, ok := hardlinkInode(info); ok {
+			if seen[ino] {
+				return nil
+			}
+			seen[ino] = true
+		}
+		usage.Inodes++
+		if info.Mode().IsRegular() {
+			usage.Files++
+		}
+		usage.Bytes += info.Size()
+		return nil
+	})
+	return usage, err
+}
@@ -0,0 +1,17 @@
+//go:build linux
+
+package fs
+
+import (
+	"os"
+	"syscall"
+)
+
+// hardlinkInode 如果文件有多个硬链接，返回其 inode 号
+func hardlinkInode(info os.FileInfo) (uint64, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok || stat.Nlink <= 1 {
+		return 0, false
+	}
+	return stat.Ino, true
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package fs
+
+import "os"
+
+// hardlinkInode 如果文件有多个硬链接，返回其 inode 号
+func hardlinkInode(os.FileInfo) (uint64, bool) {
+	return 0, false
+}
@@ -53,6 +53,42 @@ func ParseBranchLocalName(workspaceID uid.UID, localName string) []BranchInfo {
 	return ret
 }
 
+// FindBranch 不限定工作空间，通过分支名在树中查找分支
+//
+// name 可以是分支完整名、全局分支名（可以带 origin/ 前缀）或者任意工作空间的本地分支名，
+// 优先匹配全局分支。多个工作空间的同名本地分支指向不同节点时返回错误
+func FindBranch(tree trees.Tree, name string) (BranchInfo, trees.Node, error) {
+	if node, ok := tree.GetByBranch(name); ok {
+		if b, err := ParseBranchFullName(name); err == nil {
+			return b, node, nil
+		}
+	}
+	global := NewGlobalBranch(strings.TrimPrefix(name, globalBranchLocalPrefix))
+	if node, ok := tree.GetByBranch(global.FullName()); ok {
+		return global, node, nil
+	}
+
+	var found BranchInfo
+	var foundNode trees.Node
+	for fullName, node := range tree.Branches() {
+		b, err := ParseBranchFullName(fullName)
+		if err != nil || !b.IsLocal() || b.Name() != name {
+			continue
+		}
+		if foundNode != nil && foundNode.ID().Hex() != node.ID().Hex() {
+			return nil, nil, fmt.Errorf(
+				"branch %q is ambiguous, it points to %s in workspace %s and %s in workspace %s",
+				name, foundNode.ID().Hex(), found.WorkspaceID().Base32(), node.ID().Hex(), b.WorkspaceID().Base32(),
+			)
+		}
+		found, foundNode = b, node
+	}
+	if found == nil {
+		return nil, nil, fmt.Errorf("branch %q not found", name)
+	}
+	return found, foundNode, nil
+}
+
 // NewLocalBranch 创建一个本地分支
 func NewLocalBranch(workspaceID uid.UID, name string) BranchInfo {
 	return &defaultBranch{
@@ -0,0 +1,66 @@
+package workspaces
+
+import (
+	"testing"
+
+	"github.com/yhlooo/stackcrisp/pkg/spaces/trees"
+	"github.com/yhlooo/stackcrisp/pkg/utils/uid"
+)
+
+// TestFindBranch 测试 FindBranch 方法
+func TestFindBranch(t *testing.T) {
+	tree := trees.NewTree()
+	root := trees.NewNode(uid.NewUID128())
+	a := trees.NewNode(uid.NewUID128())
+	b := trees.NewNode(uid.NewUID128())
+	if err := tree.AddNode(nil, root); err != nil {
+		t.Fatalf("add root error: %v", err)
+	}
+	for _, n := range []trees.Node{a, b} {
+		if err := tree.AddNode(root.ID(), n); err != nil {
+			t.Fatalf("add node error: %v", err)
+		}
+	}
+	ws1, ws2 := uid.NewUID128(), uid.NewUID128()
+	for fullName, node := range map[string]trees.Node{
+		NewLocalBranch(ws1, "main").FullName():    a,
+		NewLocalBranch(ws2, "main").FullName():    a,
+		NewLocalBranch(ws1, "dev").FullName():     a,
+		NewLocalBranch(ws2, "dev").FullName():     b,
+		NewGlobalBranch("release").FullName():     b,
+		NewLocalBranch(ws1, "release").FullName(): a,
+	} {
+		if err := tree.AddBranch(fullName, node.ID()); err != nil {
+			t.Fatalf("add branch %q error: %v", fullName, err)
+		}
+	}
+
+	cases := []struct {
+		name     string
+		expected trees.Node
+		local    string
+	}{
+		{"main", a, "main"},
+		{"release", b, "release"},
+		{"origin/release", b, "release"},
+		{NewLocalBranch(ws2, "dev").FullName(), b, "dev"},
+	}
+	for _, c := range cases {
+		branch, node, err := FindBranch(tree, c.name)
+		if err != nil {
+			t.Errorf("find %q error: %v", c.name, err)
+			continue
+		}
+		if node.ID().Hex() != c.expected.ID().Hex() || branch.Name() != c.local {
+			t.Errorf("find %q: expected %s (%s), got %s (%s)",
+				c.name, c.expected.ID().Hex(), c.local, node.ID().Hex(), branch.Name())
+		}
+	}
+
+	// 同名本地分支指向不同节点，以及不存在的分支
+	for _, name := range []string{"dev", "feature"} {
+		if _, node, err := FindBranch(tree, name); err == nil {
+			t.Errorf("find %q: expected an error, got %s", name, node.ID().Hex())
+		}
+	}
+}
@@ -8,9 +8,13 @@ import (
 )
 
 func NewCommitInfo(msg string) CommitInfo {
-	now := time.Now()
+	return NewCommitInfoWithDate(msg, time.Now())
+}
+
+// NewCommitInfoWithDate 创建指定提交日期的提交信息（比如导入的提交保留原来的日期）
+func NewCommitInfoWithDate(msg string, date time.Time) CommitInfo {
 	return &defaultCommit{
-		date:    &now,
+		date:    &date,
 		message: msg,
 	}
 }
@@ -20,14 +24,7 @@ func GetCommitFromNode(ws Workspace, node trees.Node) Commit {
 	anno := node.Annotations()
 
 	// 获取提交日期
-	var date *time.Time
-	dateStr := anno[nodeAnnoCommitDate]
-	if dateStr != "" {
-		d, err := time.Parse(time.RFC3339, dateStr)
-		if err == nil {
-			date = &d
-		}
-	}
+	date := CommitDate(node)
 
 	nodeIDHex := node.ID().Hex()
 
@@ -56,6 +53,33 @@ func GetCommitFromNode(ws Workspace, node trees.Node) Commit {
 	}
 }
 
+// CommitMessage 返回节点的提交信息，未提交的节点返回空字符串
+func CommitMessage(node trees.Node) string {
+	return node.Annotations()[nodeAnnoCommitMessage]
+}
+
+// CommitDate 返回节点的提交日期，未提交或者没有记录日期的节点返回 nil
+func CommitDate(node trees.Node) *time.Time {
+	date, err := time.Parse(time.RFC3339, node.Annotations()[nodeAnnoCommitDate])
+	if err != nil {
+		return nil
+	}
+	return &date
+}
+
+// IsCommitted 返回节点是否已经提交
+//
+// 未提交的节点是工作空间的 upper 层
+func IsCommitted(node trees.Node) bool {
+	if node.IsRoot() {
+		return true
+	}
+	anno := node.Annotations()
+	_, hasDate := anno[nodeAnnoCommitDate]
+	_, hasMessage := anno[nodeAnnoCommitMessage]
+	return hasDate || hasMessage
+}
+
 const (
 	nodeAnnoCommitDate    = "commit-date"
 	nodeAnnoCommitMessage = "commit-message"
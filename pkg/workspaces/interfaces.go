@@ -4,7 +4,9 @@ import (
 	"context"
 	"time"
 
+	"github.com/yhlooo/stackcrisp/pkg/changes"
 	"github.com/yhlooo/stackcrisp/pkg/mounts"
+	"github.com/yhlooo/stackcrisp/pkg/reflogs"
 	"github.com/yhlooo/stackcrisp/pkg/spaces"
 	"github.com/yhlooo/stackcrisp/pkg/spaces/trees"
 	"github.com/yhlooo/stackcrisp/pkg/utils/uid"
@@ -29,6 +31,13 @@ type Workspace interface {
 	// Expand 展开工作空间
 	Expand(ctx context.Context) error
 
+	// Status 获取工作空间中尚未提交的变更
+	Status(ctx context.Context) ([]changes.Change, error)
+	// GetView 获取指定 revision 的文件视图
+	GetView(ctx context.Context, ref string) (changes.View, error)
+	// GetWorkingView 获取包含尚未提交变更的工作空间文件视图
+	GetWorkingView(ctx context.Context) (changes.View, error)
+
 	// GetHistory 获取提交历史
 	GetHistory(ref string) ([]Commit, error)
 
@@ -54,8 +63,23 @@ type Workspace interface {
 
 	// Search 通过 ref 搜索节点
 	//
-	// ref 可以是各种形式的节点 ID 、分支名、标签名
+	// ref 可以是各种形式的节点 ID 、分支名、标签名，以及 revision 表达式
 	Search(ref string) (trees.Node, trees.KeyType, bool)
+	// Resolve 解析 revision 表达式并返回其指向的节点
+	//
+	// 除了 Search 支持的形式，还支持提交 ID 前缀、 HEAD~n 、 HEAD^ 、 HEAD@{n} 、 @{-n} 等形式，
+	// 找不到或者有歧义时返回错误
+	Resolve(ref string) (trees.Node, trees.KeyType, error)
+
+	// Reflog 返回指定引用的 reflog
+	//
+	// name 可以是 HEAD 或分支名，为空时表示当前分支，分离头指针状态时表示 HEAD
+	Reflog(name string) (reflogs.Reflog, error)
+
+	// CheckoutHistory 返回之前检出过的分支本地名或提交 ID ，最近的在前
+	CheckoutHistory() []string
+	// SetCheckoutHistory 设置之前检出过的分支本地名或提交 ID ，最近的在前
+	SetCheckoutHistory(history []string)
 }
 
 // BranchInfo 分支信息
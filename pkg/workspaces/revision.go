@@ -0,0 +1,118 @@
+package workspaces
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	// 最短的提交 ID 前缀长度
+	minIDPrefixLength = 4
+	// 最多记录的之前检出位置数量
+	maxCheckoutHistory = 16
+)
+
+var (
+	// revisionSuffixRegexp 匹配 revision 末尾的祖先后缀，如 ~3^^
+	revisionSuffixRegexp = regexp.MustCompile(`(?:~[0-9]*|\^[0-9]*)+$`)
+	// revisionSuffixItemRegexp 匹配单个祖先后缀
+	revisionSuffixItemRegexp = regexp.MustCompile(`[~^][0-9]*`)
+	// revisionAtRegexp 匹配 <name>@{n} 和 @{-n}
+	revisionAtRegexp = regexp.MustCompile(`^(.*)@\{(-?[0-9]+)}$`)
+	// hexPrefixRegexp 匹配提交 ID 十六进制形式的前缀
+	hexPrefixRegexp = regexp.MustCompile(`^[0-9a-fA-F]+$`)
+)
+
+// revision 解析后的 revision 表达式
+//
+// 支持以下形式（可以组合）：
+//   - <name>: 提交 ID （或其不短于 4 个字符的十六进制前缀）、分支名、标签名、 HEAD 或 @ （ HEAD 的简写）
+//   - <name>@{n}: 指定引用的 reflog 中倒数第 n 个位置， name 为空时表示当前分支
+//   - @{-n}: 倒数第 n 次检出前所在的分支或提交
+//   - <rev>~n: 第 n 代祖先， n 缺省时为 1
+//   - <rev>^n: n 为 0 时是其本身，为 1 或缺省时是父节点
+type revision struct {
+	// 基准名称
+	name string
+	// 是否 reflog 形式
+	isReflog bool
+	// reflog 中的序号
+	reflogIndex int
+	// 之前检出的序号，没有则为 0
+	previous int
+	// 向上追溯的代数
+	generations int
+}
+
+// parseRevision 解析 revision 表达式
+func parseRevision(expr string) (*revision, error) {
+	rev := &revision{}
+
+	// 解析末尾的祖先后缀
+	base := expr
+	if loc := revisionSuffixRegexp.FindStringIndex(expr); loc != nil {
+		base = expr[:loc[0]]
+		for _, item := range revisionSuffixItemRegexp.FindAllString(expr[loc[0]:], -1) {
+			n := 1
+			if len(item) > 1 {
+				var err error
+				n, err = strconv.Atoi(item[1:])
+				if err != nil {
+					return nil, fmt.Errorf("invalid revision %q: %w", expr, err)
+				}
+			}
+			if item[0] == '^' && n > 1 {
+				return nil, fmt.Errorf("invalid revision %q: commit has only one parent", expr)
+			}
+			rev.generations += n
+		}
+	}
+
+	// 解析 @{n} 和 @{-n}
+	if match := revisionAtRegexp.FindStringSubmatch(base); match != nil {
+		n, err := strconv.Atoi(match[2])
+		if err != nil {
+			return nil, fmt.Errorf("invalid revision %q: %w", expr, err)
+		}
+		switch {
+		case n < 0:
+			if match[1] != "" {
+				return nil, fmt.Errorf("invalid revision %q: @{-n} can not follow a name", expr)
+			}
+			rev.previous = -n
+		default:
+			rev.isReflog = true
+			rev.reflogIndex = n
+			rev.name = match[1]
+		}
+		return rev, nil
+	}
+
+	if base == "" {
+		return nil, fmt.Errorf("invalid revision %q: empty name", expr)
+	}
+	rev.name = base
+	return rev, nil
+}
+
+// isIDPrefix 判断 name 是否可能是提交 ID 十六进制形式的前缀
+func isIDPrefix(name string) bool {
+	return len(name) >= minIDPrefixLength && hexPrefixRegexp.MatchString(name)
+}
+
+// ParsePreviousCheckout 判断 ref 是否恰好是 @{-n} 形式（或者 - ，即 @{-1} ），是则返回 n
+func ParsePreviousCheckout(ref string) (int, bool) {
+	if ref == "-" {
+		return 1, true
+	}
+	if !strings.HasPrefix(ref, "@{-") {
+		return 0, false
+	}
+	rev, err := parseRevision(ref)
+	if err != nil || rev.previous == 0 || rev.generations != 0 {
+		return 0, false
+	}
+	return rev.previous, true
+}
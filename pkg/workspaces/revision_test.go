@@ -0,0 +1,66 @@
+package workspaces
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestParseRevision 测试 parseRevision 方法
+func TestParseRevision(t *testing.T) {
+	cases := []struct {
+		expr     string
+		expected *revision
+	}{
+		{"HEAD", &revision{name: "HEAD"}},
+		{"main~3", &revision{name: "main", generations: 3}},
+		{"main~", &revision{name: "main", generations: 1}},
+		{"HEAD^^", &revision{name: "HEAD", generations: 2}},
+		{"HEAD~2^1^0", &revision{name: "HEAD", generations: 3}},
+		{"abcd~1", &revision{name: "abcd", generations: 1}},
+		{"@", &revision{name: "@"}},
+		{"HEAD@{2}", &revision{name: "HEAD", isReflog: true, reflogIndex: 2}},
+		{"@{0}~1", &revision{isReflog: true, generations: 1}},
+		{"@{-1}", &revision{previous: 1}},
+		{"@{-2}^", &revision{previous: 2, generations: 1}},
+		{"feature/a@b", &revision{name: "feature/a@b"}},
+	}
+	for _, c := range cases {
+		rev, err := parseRevision(c.expr)
+		if err != nil {
+			t.Errorf("parse %q error: %v", c.expr, err)
+			continue
+		}
+		if !reflect.DeepEqual(rev, c.expected) {
+			t.Errorf("parse %q: expected %#v, got %#v", c.expr, c.expected, rev)
+		}
+	}
+
+	// 非法表达式
+	for _, expr := range []string{"~1", "HEAD^2", "main@{-1}", "^"} {
+		if rev, err := parseRevision(expr); err == nil {
+			t.Errorf("parse %q: expected an error, got %#v", expr, rev)
+		}
+	}
+}
+
+// TestParsePreviousCheckout 测试 ParsePreviousCheckout 方法
+func TestParsePreviousCheckout(t *testing.T) {
+	cases := []struct {
+		ref string
+		n   int
+		ok  bool
+	}{
+		{"-", 1, true},
+		{"@{-1}", 1, true},
+		{"@{-3}", 3, true},
+		{"@{-1}~1", 0, false},
+		{"@{1}", 0, false},
+		{"main", 0, false},
+	}
+	for _, c := range cases {
+		n, ok := ParsePreviousCheckout(c.ref)
+		if n != c.n || ok != c.ok {
+			t.Errorf("parse %q: expected (%d, %t), got (%d, %t)", c.ref, c.n, c.ok, n, ok)
+		}
+	}
+}
@@ -3,23 +3,26 @@ package workspaces
 import (
 	"context"
 	"fmt"
-	"os"
 	"sort"
+	"strings"
 
 	"github.com/go-logr/logr"
 
+	"github.com/yhlooo/stackcrisp/pkg/changes"
+	"github.com/yhlooo/stackcrisp/pkg/layers"
 	"github.com/yhlooo/stackcrisp/pkg/mounts"
+	"github.com/yhlooo/stackcrisp/pkg/reflogs"
 	"github.com/yhlooo/stackcrisp/pkg/spaces"
 	"github.com/yhlooo/stackcrisp/pkg/spaces/trees"
-	fsutil "github.com/yhlooo/stackcrisp/pkg/utils/fs"
 	"github.com/yhlooo/stackcrisp/pkg/utils/uid"
 )
 
 const (
 	loggerName = "workspaces"
 
-	rootTag = "ROOT"
-	headTag = "HEAD"
+	rootTag      = "ROOT"
+	headTag      = "HEAD"
+	headShortTag = "@"
 )
 
 // New 创建一个工作空间
@@ -34,6 +37,14 @@ func New(id uid.UID, path string, space spaces.Space, mount mounts.Mount, head t
 	}
 }
 
+// ResolveInSpace 不基于任何工作空间，在空间中解析 revision 表达式并返回其指向的节点
+//
+// 不支持 HEAD 、 reflog 和之前检出的位置，分支名通过 FindBranch 查找
+func ResolveInSpace(space spaces.Space, ref string) (trees.Node, trees.KeyType, error) {
+	ws := &defaultWorkspace{space: space}
+	return ws.Resolve(ref)
+}
+
 // defaultWorkspace 是 Workspace 的一个默认实现
 type defaultWorkspace struct {
 	id     uid.UID
@@ -42,6 +53,8 @@ type defaultWorkspace struct {
 	mount  mounts.Mount
 	head   trees.Node
 	branch string
+
+	checkoutHistory []string
 }
 
 var _ Workspace = &defaultWorkspace{}
@@ -72,8 +85,18 @@ func (ws *defaultWorkspace) Head() trees.Node {
 }
 
 // Branch 返回当前分支
+//
+// 跟踪全局分支时分支本地名带有 origin/ 前缀，存在同名本地分支时优先使用本地分支
 func (ws *defaultWorkspace) Branch() BranchInfo {
-	return NewLocalBranch(ws.id, ws.branch)
+	candidates := ParseBranchLocalName(ws.id, ws.branch)
+	if ws.space != nil {
+		for _, b := range candidates {
+			if _, ok := ws.space.Tree().GetByBranch(b.FullName()); ok {
+				return b
+			}
+		}
+	}
+	return candidates[0]
 }
 
 // Expand 展开工作空间
@@ -86,43 +109,62 @@ func (ws *defaultWorkspace) Expand(ctx context.Context) error {
 		return fmt.Errorf("mount error: %w", err)
 	}
 
-	// 先移动到根目录
-	pwd, err := os.Getwd()
-	if err != nil {
-		return fmt.Errorf("get pwd error: %w", err)
-	}
-	if err := os.Chdir("/"); err != nil {
-		return fmt.Errorf("change working directory to \"/\" error: %w", err)
+	// 绑定到工作空间路径，替换其上原有的挂载
+	logger.Info(fmt.Sprintf("bind mount point to %q", ws.Path()))
+	if err := ws.Mount().BindTo(ctx, ws.Path()); err != nil {
+		return fmt.Errorf("bind mount point to %q error: %w", ws.Path(), err)
 	}
 
-	// 确保目标路径上什么也没有
-	if fsutil.IsExists(ws.Path()) {
-		logger.V(1).Info(fmt.Sprintf("target path %q exists, remove it", ws.Path()))
-		if err := os.Remove(ws.Path()); err != nil {
-			return fmt.Errorf("clear path %q error: %w", ws.Path(), err)
-		}
+	return nil
+}
+
+// Status 获取工作空间中尚未提交的变更
+func (ws *defaultWorkspace) Status(ctx context.Context) ([]changes.Change, error) {
+	// 头指针是尚未提交的 upper 层
+	layerSet, err := ws.layers(ctx, ws.Head())
+	if err != nil {
+		return nil, err
 	}
+	return changes.Diff(changes.NewLayersView(layerSet[:len(layerSet)-1]), layerSet[len(layerSet)-1].DiffDir())
+}
 
-	// 创建软链
-	logger.Info(fmt.Sprintf("link mount point to %q", ws.Path()))
-	if err := ws.Mount().CreateSymlink(ctx, ws.Path()); err != nil {
-		return fmt.Errorf("create syslink %q to mount path error: %w", ws.Path(), err)
+// GetView 获取指定 revision 的文件视图
+func (ws *defaultWorkspace) GetView(ctx context.Context, ref string) (changes.View, error) {
+	node, _, err := ws.Resolve(ref)
+	if err != nil {
+		return nil, err
 	}
+	layerSet, err := ws.layers(ctx, node)
+	if err != nil {
+		return nil, err
+	}
+	return changes.NewLayersView(layerSet), nil
+}
 
-	// 移动回去
-	if err := os.Chdir(pwd); err != nil {
-		return fmt.Errorf("change working directory to %q error: %w", pwd, err)
+// GetWorkingView 获取包含尚未提交变更的工作空间文件视图
+func (ws *defaultWorkspace) GetWorkingView(ctx context.Context) (changes.View, error) {
+	layerSet, err := ws.layers(ctx, ws.Head())
+	if err != nil {
+		return nil, err
 	}
+	return changes.NewLayersView(layerSet), nil
+}
 
-	return nil
+// layers 获取从根节点到指定节点的所有层
+func (ws *defaultWorkspace) layers(ctx context.Context, node trees.Node) ([]layers.Layer, error) {
+	layerSet, err := ws.Space().GetLayers(ctx, node.ID())
+	if err != nil {
+		return nil, fmt.Errorf("get layers of %q error: %w", node.ID().Hex(), err)
+	}
+	return layerSet, nil
 }
 
 // GetHistory 获取提交历史
 func (ws *defaultWorkspace) GetHistory(ref string) ([]Commit, error) {
 	// 获取指定节点
-	node, _, _ := ws.Search(ref)
-	if node == nil {
-		return nil, fmt.Errorf("revision %q not found", ref)
+	node, _, err := ws.Resolve(ref)
+	if err != nil {
+		return nil, err
 	}
 
 	// 追溯提交历史
@@ -229,17 +271,25 @@ func (ws *defaultWorkspace) SetBranch(localName string) error {
 
 // AddBranch 添加分支
 func (ws *defaultWorkspace) AddBranch(ctx context.Context, branchLocalName string, ref string, force bool) error {
-	node, _, ok := ws.Search(ref)
-	if !ok {
-		return fmt.Errorf("failed to resolve %q as valid ref", ref)
+	node, _, err := ws.Resolve(ref)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %q as valid ref: %w", ref, err)
 	}
 
 	branch := NewLocalBranch(ws.id, branchLocalName)
 
 	// 检查是否已经存在该分支
-	if existsNode, ok := ws.Space().Tree().GetByBranch(branch.FullName()); ok && !force {
+	existsNode, exists := ws.Space().Tree().GetByBranch(branch.FullName())
+	if exists && !force {
 		return fmt.Errorf("branch %q already exists at %q", branch.LocalName(), existsNode.ID().Hex())
 	}
+	if reflogs.MessageFromContext(ctx) == "" {
+		if exists {
+			ctx = reflogs.NewContextWithMessage(ctx, fmt.Sprintf("reset to %s", ref))
+		} else {
+			ctx = reflogs.NewContextWithMessage(ctx, fmt.Sprintf("created from %s", ref))
+		}
+	}
 
 	// 添加分支
 	if err := ws.Space().Tree().AddBranch(branch.FullName(), node.ID()); err != nil {
@@ -269,6 +319,7 @@ func (ws *defaultWorkspace) DeleteBranch(ctx context.Context, name string, globa
 	}
 
 	// 删除
+	ctx = reflogs.NewContextWithMessage(ctx, "deleted")
 	if ok := ws.Space().Tree().DeleteBranch(branch.FullName()); !ok {
 		return fmt.Errorf("branch %q not found", branch.LocalName())
 	}
@@ -316,9 +367,9 @@ func (ws *defaultWorkspace) DeleteTag(ctx context.Context, tagName string) error
 
 // AddTag 添加标签
 func (ws *defaultWorkspace) AddTag(ctx context.Context, tagName string, ref string, force bool) error {
-	node, _, ok := ws.Search(ref)
-	if !ok {
-		return fmt.Errorf("failed to resolve %q as valid ref", ref)
+	node, _, err := ws.Resolve(ref)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %q as valid ref: %w", ref, err)
 	}
 
 	// 检查是否已经存在该标签
@@ -341,19 +392,173 @@ func (ws *defaultWorkspace) AddTag(ctx context.Context, tagName string, ref stri
 
 // Search 通过 ref 搜索节点
 //
-// ref 可以是各种形式的节点 ID 、分支名、标签名
+// ref 可以是各种形式的节点 ID 、分支名、标签名，以及 revision 表达式
 func (ws *defaultWorkspace) Search(ref string) (trees.Node, trees.KeyType, bool) {
+	node, keyType, err := ws.Resolve(ref)
+	return node, keyType, err == nil
+}
+
+// Resolve 解析 revision 表达式并返回其指向的节点
+//
+// 除了 Search 支持的形式，还支持提交 ID 前缀、 HEAD~n 、 HEAD^ 、 HEAD@{n} 、 @{-n} 等形式，
+// 找不到或者有歧义时返回错误
+func (ws *defaultWorkspace) Resolve(ref string) (trees.Node, trees.KeyType, error) {
+	// 首先作为完整的名字搜索，名字中可能包含特殊字符
+	if node, keyType, ok := ws.searchName(ref); ok {
+		return node, keyType, nil
+	}
+
+	rev, err := parseRevision(ref)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// 找到基准节点
+	var node trees.Node
+	var keyType trees.KeyType
+	switch {
+	case rev.previous > 0:
+		if rev.previous > len(ws.checkoutHistory) {
+			return nil, "", fmt.Errorf(
+				"revision %q not found: only %d previous checkouts recorded", ref, len(ws.checkoutHistory),
+			)
+		}
+		node, keyType, err = ws.resolveName(ws.checkoutHistory[rev.previous-1])
+	case rev.isReflog:
+		node, err = ws.resolveReflog(rev.name, rev.reflogIndex)
+		keyType = trees.Commit
+	default:
+		node, keyType, err = ws.resolveName(rev.name)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	// 向上追溯祖先
+	for i := 0; i < rev.generations; i++ {
+		if node.IsRoot() {
+			return nil, "", fmt.Errorf("revision %q not found: no ancestor that far", ref)
+		}
+		node = node.Parent()
+		keyType = trees.Commit
+	}
+	return node, keyType, nil
+}
+
+// resolveName 通过名字或者提交 ID 前缀搜索节点
+func (ws *defaultWorkspace) resolveName(name string) (trees.Node, trees.KeyType, error) {
+	if node, keyType, ok := ws.searchName(name); ok {
+		return node, keyType, nil
+	}
+
+	// 尝试作为提交 ID 前缀
+	if isIDPrefix(name) {
+		var candidates []trees.Node
+		for _, node := range ws.space.Tree().FindByIDPrefix(name) {
+			// 忽略尚未提交的 upper 层
+			if IsCommitted(node) {
+				candidates = append(candidates, node)
+			}
+		}
+		switch len(candidates) {
+		case 0:
+		case 1:
+			return candidates[0], trees.Commit, nil
+		default:
+			ids := make([]string, len(candidates))
+			for i, node := range candidates {
+				ids[i] = node.ID().Hex()
+			}
+			return nil, "", fmt.Errorf("short commit id %q is ambiguous, candidates: %s", name, strings.Join(ids, ", "))
+		}
+	}
+
+	return nil, "", fmt.Errorf("revision %q not found", name)
+}
+
+// resolveReflog 获取指定引用 reflog 中倒数第 n 个位置
+func (ws *defaultWorkspace) resolveReflog(name string, n int) (trees.Node, error) {
+	reflog, err := ws.Reflog(name)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := reflog.Entries()
+	if err != nil {
+		return nil, err
+	}
+	if n >= len(entries) {
+		return nil, fmt.Errorf("reflog of %q has only %d entries", name, len(entries))
+	}
+	if entries[n].New == "" {
+		return nil, fmt.Errorf("%s@{%d} is a deletion", name, n)
+	}
+	id, err := uid.DecodeUID128FromHex(entries[n].New)
+	if err != nil {
+		return nil, fmt.Errorf("parse id %q in reflog error: %w", entries[n].New, err)
+	}
+	node, ok := ws.space.Tree().Get(id)
+	if !ok {
+		return nil, fmt.Errorf("commit %s of %s@{%d} no longer exists", entries[n].New, name, n)
+	}
+	return node, nil
+}
+
+// Reflog 返回指定引用的 reflog
+//
+// name 可以是 HEAD 或分支名，为空时表示当前分支，分离头指针状态时表示 HEAD
+func (ws *defaultWorkspace) Reflog(name string) (reflogs.Reflog, error) {
+	if name == "" {
+		name = ws.branch
+		if name == "" {
+			name = headTag
+		}
+	}
+	if ws.id == nil {
+		return nil, fmt.Errorf("no reflog for %q outside a workspace", name)
+	}
+	if name == headTag || name == headShortTag {
+		return ws.space.HeadReflog(ws.id), nil
+	}
+	// 分支，包括已经被删除的分支
+	for _, b := range ParseBranchLocalName(ws.id, name) {
+		if _, ok := ws.space.Tree().GetByBranch(b.FullName()); ok {
+			return ws.space.BranchReflog(b.FullName()), nil
+		}
+	}
+	for _, b := range ParseBranchLocalName(ws.id, name) {
+		reflog := ws.space.BranchReflog(b.FullName())
+		if entries, err := reflog.Entries(); err == nil && len(entries) > 0 {
+			return reflog, nil
+		}
+	}
+	return nil, fmt.Errorf("no reflog for %q", name)
+}
+
+// searchName 通过名字搜索节点
+//
+// name 可以是 HEAD 、完整的节点 ID 、分支名、标签名
+func (ws *defaultWorkspace) searchName(name string) (trees.Node, trees.KeyType, bool) {
 	// 首先是 HEAD
-	if ref == headTag {
+	if name == headTag || name == headShortTag {
+		if ws.head == nil {
+			return nil, "", false
+		}
 		return ws.Head().Parent(), trees.Commit, true
 	}
 	// 首先直接搜
 	// 包括 commit 、 tag 、 分支完整名
-	if node, keyType, ok := ws.space.Tree().Search(ref); ok {
+	if node, keyType, ok := ws.space.Tree().Search(name); ok {
 		return node, keyType, true
 	}
+	// 不基于工作空间时搜索所有工作空间的分支
+	if ws.id == nil {
+		if _, node, err := FindBranch(ws.space.Tree(), name); err == nil {
+			return node, trees.Branch, true
+		}
+		return nil, "", false
+	}
 	// 然后搜索分支本地名
-	for _, b := range ParseBranchLocalName(ws.id, ref) {
+	for _, b := range ParseBranchLocalName(ws.id, name) {
 		if node, ok := ws.space.Tree().GetByBranch(b.FullName()); ok {
 			return node, trees.Branch, true
 		}
@@ -361,3 +566,16 @@ func (ws *defaultWorkspace) Search(ref string) (trees.Node, trees.KeyType, bool)
 	// 实在没有了
 	return nil, "", false
 }
+
+// CheckoutHistory 返回之前检出过的分支本地名或提交 ID ，最近的在前
+func (ws *defaultWorkspace) CheckoutHistory() []string {
+	return ws.checkoutHistory
+}
+
+// SetCheckoutHistory 设置之前检出过的分支本地名或提交 ID ，最近的在前
+func (ws *defaultWorkspace) SetCheckoutHistory(history []string) {
+	if len(history) > maxCheckoutHistory {
+		history = history[:maxCheckoutHistory]
+	}
+	ws.checkoutHistory = history
+}